@@ -113,6 +113,9 @@ type PHReg struct {
 	// Optimization method
 	optmethod optimize.Method
 
+	// The method for handling tied event times
+	ties TiesMethod
+
 	log *log.Logger
 
 	nslices [][]float64
@@ -133,11 +136,47 @@ func (ph *PHReg) Dataset() [][]statmodel.Dtype {
 	return ph.data
 }
 
+// VarNames returns the names of the data columns that are used to fit
+// the model.
+func (ph *PHReg) VarNames() []string {
+	return ph.varnames
+}
+
 // Xpos return the positions of the covariates in the model's dstream.
 func (ph *PHReg) Xpos() []int {
 	return ph.xpos
 }
 
+// TiesMethod specifies how tied event times are handled in the
+// partial likelihood.
+type TiesMethod int
+
+const (
+	// BreslowTies uses the Breslow approximation, in which all cases
+	// with an event at a given time are treated as if they remain in
+	// the risk set until all of these events have occurred.
+	BreslowTies TiesMethod = iota
+
+	// EfronTies uses the Efron approximation, in which the
+	// contribution of the tied cases to the risk set is
+	// progressively reduced as the tied events occur.  It is the
+	// default in R's survival package and is usually more accurate
+	// than the Breslow approximation when there are many ties.
+	EfronTies
+)
+
+// String returns the name of the method for handling ties.
+func (tm TiesMethod) String() string {
+	switch tm {
+	case BreslowTies:
+		return "Breslow"
+	case EfronTies:
+		return "Efron"
+	default:
+		return fmt.Sprintf("TiesMethod(%d)", int(tm))
+	}
+}
+
 // PHRegConfig defines configuration parameters for a proportional hazards regression..
 type PHRegConfig struct {
 
@@ -163,6 +202,10 @@ type PHRegConfig struct {
 	L1Penalty map[string]float64
 	L2Penalty map[string]float64
 
+	// Ties is the method used to handle tied event times, the
+	// default is BreslowTies.
+	Ties TiesMethod
+
 	// OptMethod is the Gonum optimization used to fit the model.
 	OptMethod optimize.Method
 
@@ -241,6 +284,11 @@ func NewPHReg(data statmodel.Dataset, time, status string, predictors []string,
 	offsetpos := getpos(config.OffsetVar)
 	entrypos := getpos(config.EntryVar)
 
+	if config.Ties != BreslowTies && config.Ties != EfronTies {
+		msg := fmt.Sprintf("Unknown ties method %v\n", config.Ties)
+		return nil, fmt.Errorf(msg)
+	}
+
 	varnames := data.Names()
 
 	penToSlice := func(m map[string]float64) []float64 {
@@ -272,6 +320,7 @@ func NewPHReg(data statmodel.Dataset, time, status string, predictors []string,
 		log:         config.Log,
 		optsettings: config.OptSettings,
 		optmethod:   config.OptMethod,
+		ties:        config.Ties,
 	}
 
 	ph.init()
@@ -534,7 +583,13 @@ func (ph *PHReg) LogLike(param statmodel.Parameter, exact bool) float64 {
 
 	coeff := param.GetCoeff()
 
-	ll := ph.breslowLogLike(coeff)
+	var ll float64
+	switch ph.ties {
+	case EfronTies:
+		ll = ph.efron(coeff, nil, nil)
+	default:
+		ll = ph.breslowLogLike(coeff)
+	}
 
 	// Account for L2 weights if present.
 	if len(ph.l2wgt) > 0 {
@@ -686,7 +741,12 @@ func zero(x []float64) {
 func (ph *PHReg) Score(params statmodel.Parameter, score []float64) {
 
 	coeff := params.GetCoeff()
-	ph.breslowScore(coeff, score)
+	switch ph.ties {
+	case EfronTies:
+		ph.efron(coeff, score, nil)
+	default:
+		ph.breslowScore(coeff, score)
+	}
 
 	// Account for L2 weights if present.
 	if len(ph.l2wgt) > 0 {
@@ -791,7 +851,12 @@ func (ph *PHReg) breslowScore(params, score []float64) {
 func (ph *PHReg) Hessian(params statmodel.Parameter, ht statmodel.HessType, hess []float64) {
 
 	coeff := params.GetCoeff()
-	ph.breslowHess(coeff, hess)
+	switch ph.ties {
+	case EfronTies:
+		ph.efron(coeff, nil, hess)
+	default:
+		ph.breslowHess(coeff, hess)
+	}
 
 	// Account for L2 weights if present.
 	p := len(coeff)
@@ -922,6 +987,166 @@ func (ph *PHReg) breslowHess(params []float64, hess []float64) {
 	}
 }
 
+// efron returns the log-likelihood value for the proportional hazards
+// regression model at the given parameter values, using the Efron
+// method to resolve ties.  If score or hess are not nil, the score
+// vector and Hessian matrix are also calculated and placed into these
+// slices.  With case weights, the contribution of each set of tied
+// events is scaled by the mean weight of the cases in the set, as in
+// R's survival package.
+func (ph *PHReg) efron(params, score, hess []float64) float64 {
+
+	var wgt []statmodel.Dtype
+	if ph.weightpos != -1 {
+		wgt = ph.data[ph.weightpos]
+	}
+
+	var off []statmodel.Dtype
+	if ph.offsetpos != -1 {
+		off = ph.data[ph.offsetpos]
+	}
+
+	p := len(ph.xpos)
+	if score != nil {
+		zero(score)
+	}
+	if hess != nil {
+		zero(hess)
+	}
+
+	lp := ph.getNslice()
+	elp := ph.getNslice()
+
+	// Get the linear predictors
+	for j, k := range ph.xpos {
+		x := ph.data[k]
+		for i := range x {
+			lp[i] += float64(x[i]) * params[j]
+		}
+	}
+
+	// Add the offset, if present
+	if off != nil {
+		for i := range off {
+			lp[i] += float64(off[i])
+		}
+	}
+
+	// Risk set sums (s0, s1, s2) and sums over the cases with an
+	// event at the current time (e0, e1, e2), of the weighted
+	// exponentiated linear predictor, and its products with the
+	// covariates.
+	s1 := make([]float64, p)
+	e1 := make([]float64, p)
+	a := make([]float64, p)
+	var s2, e2 []float64
+	if hess != nil {
+		s2 = make([]float64, p*p)
+		e2 = make([]float64, p*p)
+	}
+
+	// Add f*elp[i]*x to t1, and f*elp[i]*x*x' to t2.
+	update := func(i int, f float64, t1, t2 []float64) {
+		for j1, k1 := range ph.xpos {
+			x1 := float64(ph.data[k1][i])
+			t1[j1] += f * elp[i] * x1
+			if t2 != nil {
+				for j2, k2 := range ph.xpos {
+					t2[j1*p+j2] += f * elp[i] * x1 * float64(ph.data[k2][i])
+				}
+			}
+		}
+	}
+
+	ql := float64(0)
+	for s, ix := range ph.stratumix {
+
+		if score != nil && ph.sumx[s] != nil {
+			floats.Add(score, ph.sumx[s])
+		}
+
+		// We can add any constant here due to invariance in
+		// the partial likelihood.
+		mx := floats.Max(lp[ix[0]:ix[1]])
+		for i := ix[0]; i < ix[1]; i++ {
+			lp[i] -= mx
+			elp[i] = math.Exp(lp[i])
+		}
+		if wgt != nil {
+			for i := ix[0]; i < ix[1]; i++ {
+				elp[i] *= float64(wgt[i])
+			}
+		}
+
+		var s0 float64
+		zero(s1)
+		if s2 != nil {
+			zero(s2)
+		}
+
+		for k := range ph.etimes[s] {
+
+			// Update for new entries
+			for _, i := range ph.enter[s][k] {
+				s0 += elp[i]
+				update(i, 1, s1, s2)
+			}
+
+			ev := ph.event[s][k]
+			m := float64(len(ev))
+
+			var e0, dw float64
+			zero(e1)
+			if e2 != nil {
+				zero(e2)
+			}
+			for _, i := range ev {
+				w := 1.0
+				if wgt != nil {
+					w = float64(wgt[i])
+				}
+				ql += w * lp[i]
+				dw += w
+				e0 += elp[i]
+				update(i, 1, e1, e2)
+			}
+			mw := dw / m
+
+			for j := 0; j < len(ev); j++ {
+				c := float64(j) / m
+				den := s0 - c*e0
+				ql -= mw * math.Log(den)
+
+				for l := range a {
+					a[l] = (s1[l] - c*e1[l]) / den
+				}
+				if score != nil {
+					floats.AddScaled(score, -mw, a)
+				}
+				if hess != nil {
+					for j1 := 0; j1 < p; j1++ {
+						for j2 := 0; j2 < p; j2++ {
+							q := j1*p + j2
+							hess[q] -= mw * ((s2[q]-c*e2[q])/den - a[j1]*a[j2])
+						}
+					}
+				}
+			}
+
+			// Update for new exits
+			for _, i := range ph.exit[s][k] {
+				s0 -= elp[i]
+				update(i, -1, s1, s2)
+			}
+		}
+	}
+
+	ph.putNslice(lp)
+	ph.putNslice(elp)
+
+	return ql
+}
+
 func negative(x []float64) {
 	for i := 0; i < len(x); i++ {
 		x[i] *= -1
@@ -1278,7 +1503,7 @@ func (phs *PHSummary) String() string {
 	sum.Top = append(sum.Top, fmt.Sprintf("  Sample size: %10d", n))
 	sum.Top = append(sum.Top, fmt.Sprintf("  Strata:      %10d", ns))
 	sum.Top = append(sum.Top, fmt.Sprintf("  Events:      %10d", e))
-	sum.Top = append(sum.Top, "  Ties:           Breslow")
+	sum.Top = append(sum.Top, fmt.Sprintf("  Ties:%18s", ph.ties))
 
 	l1 := ph.l1wgt != nil
 
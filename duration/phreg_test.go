@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"strings"
 	"testing"
 
+	"gonum.org/v1/gonum/diff/fd"
 	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
 	"gonum.org/v1/gonum/optimize"
 
 	"github.com/kshedden/statmodel/statmodel"
@@ -69,6 +72,22 @@ func data4() statmodel.Dataset {
 	return statmodel.NewDataset(da, names)
 }
 
+// The "book1" data from the tests in R's survival package.  There is a tie
+// between an event and a censoring time at time 1, and a tie between two
+// events at time 6.  The row with a missing status is omitted.
+func data5() statmodel.Dataset {
+
+	da := [][]statmodel.Dtype{
+		{9, 1, 1, 6, 6, 8},
+		{1, 1, 0, 1, 1, 0},
+		{0, 1, 1, 1, 0, 0},
+	}
+
+	names := []string{"time", "status", "x"}
+
+	return statmodel.NewDataset(da, names)
+}
+
 // Basic check, no strata, weights, or entry times.
 func TestSimple(t *testing.T) {
 
@@ -610,3 +629,117 @@ func TestBaselineHaz(t *testing.T) {
 		}
 	}
 }
+
+// Check the Breslow and Efron methods for handling ties against the closed
+// form expressions for the "book1" data from R's survival package, and
+// against the parameter estimates obtained using coxph in R.
+func TestTies(t *testing.T) {
+
+	// Closed form log-likelihood, score, and Hessian
+	byhand := func(beta float64, ties TiesMethod) (float64, float64, float64) {
+		r := math.Exp(beta)
+		if ties == EfronTies {
+			ll := 2*beta - math.Log(3*r+3) - math.Log((r+5)/2) - math.Log(r+3)
+			score := 2 - r/(r+1) - r/(r+5) - r/(r+3)
+			hess := -(r/((r+1)*(r+1)) + 5*r/((r+5)*(r+5)) + 3*r/((r+3)*(r+3)))
+			return ll, score, hess
+		}
+		ll := 2*beta - math.Log(3*r+3) - 2*math.Log(r+3)
+		score := 2 - r/(r+1) - 2*r/(r+3)
+		hess := -(r/((r+1)*(r+1)) + 6*r/((r+3)*(r+3)))
+		return ll, score, hess
+	}
+
+	// Estimates from R's survival package
+	rparams := map[TiesMethod]float64{
+		BreslowTies: 1.475285,
+		EfronTies:   1.676857,
+	}
+
+	for _, ties := range []TiesMethod{BreslowTies, EfronTies} {
+
+		config := DefaultPHRegConfig()
+		config.Ties = ties
+		ph, err := NewPHReg(data5(), "time", "status", []string{"x"}, config)
+		if err != nil {
+			panic(err)
+		}
+
+		score := make([]float64, 1)
+		hess := make([]float64, 1)
+		for _, beta := range []float64{-1, 0, 0.5, 2} {
+			par := &PHParameter{[]float64{beta}}
+			ll0, score0, hess0 := byhand(beta, ties)
+			ph.Score(par, score)
+			ph.Hessian(par, statmodel.ObsHess, hess)
+			if math.Abs(ph.LogLike(par, true)-ll0) > 1e-8 {
+				t.Fail()
+			}
+			if math.Abs(score[0]-score0) > 1e-8 || math.Abs(hess[0]-hess0) > 1e-8 {
+				t.Fail()
+			}
+		}
+
+		rslt, err := ph.Fit()
+		if err != nil {
+			panic(err)
+		}
+		if math.Abs(rslt.Params()[0]-rparams[ties]) > 1e-5 {
+			t.Logf("%v: %v != %v\n", ties, rslt.Params()[0], rparams[ties])
+			t.Fail()
+		}
+		_, _, hess0 := byhand(rslt.Params()[0], ties)
+		if math.Abs(rslt.StdErr()[0]-math.Sqrt(-1/hess0)) > 1e-5 {
+			t.Fail()
+		}
+
+		if !strings.Contains(rslt.Summary().String(), ties.String()) {
+			t.Fail()
+		}
+	}
+}
+
+// TestTiesDiff checks the Efron score and Hessian against numerical
+// derivatives of the log-likelihood and score, for the problems used in
+// TestGrad.
+func TestTiesDiff(t *testing.T) {
+
+	for _, dt := range diffTests {
+
+		config := DefaultPHRegConfig()
+		config.Ties = EfronTies
+		model, err := NewPHReg(dt.data, "time", "status", dt.xnames, config)
+		if err != nil {
+			panic(err)
+		}
+
+		p := len(dt.params[0])
+		ngrad := make([]float64, p)
+		score := make([]float64, p)
+		hess := make([]float64, p*p)
+		nhess := mat.NewDense(p, p, nil)
+
+		loglike := func(x []float64) float64 {
+			return model.LogLike(&PHParameter{x}, true)
+		}
+		scoref := func(y, x []float64) {
+			model.Score(&PHParameter{x}, y)
+		}
+
+		for _, params := range dt.params {
+			fd.Gradient(ngrad, loglike, params, &fd.Settings{Formula: fd.Forward, Step: 1e-6})
+			model.Score(&PHParameter{params}, score)
+			if !floats.EqualApprox(score, ngrad, 1e-5) {
+				t.Logf("%v: numerical %v, analytical %v\n", params, ngrad, score)
+				t.Fail()
+			}
+
+			fd.Jacobian(nhess, scoref, params, &fd.JacobianSettings{Formula: fd.Central, Step: 1e-5})
+			model.Hessian(&PHParameter{params}, statmodel.ObsHess, hess)
+			if !floats.EqualApprox(hess, nhess.RawMatrix().Data, 1e-5) {
+				t.Logf("%v: numerical %v, analytical %v\n", params, nhess.RawMatrix().Data, hess)
+				t.Fail()
+			}
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package glm
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/mathext"
+
+	"github.com/kshedden/statmodel/statmodel"
+)
+
+// The beta regression model has log-likelihood
+//
+//   lgamma(phi) - lgamma(m*phi) - lgamma((1-m)*phi) + (m*phi - 1)*log(y) + ((1-m)*phi - 1)*log(1-y)
+//
+// for each observation, where m is the mean and phi is the precision.
+// With y* = log(y/(1-y)) and m* = digamma(m*phi) - digamma((1-m)*phi),
+// the derivative with respect to the mean is phi*(y* - m*), which has
+// expectation zero and variance phi^2*(trigamma(m*phi) +
+// trigamma((1-m)*phi)).  See Ferrari and Cribari-Neto (2004), Beta
+// regression for modelling rates and proportions, Journal of Applied
+// Statistics 31(7).
+//
+// The GLM scale parameter is 1/(1+phi), so that the variance of the
+// response is the scale times m(1-m).  As for the other families, the
+// score is the derivative of the log-likelihood at the given scale, and
+// the Hessian is the second derivative of the log-likelihood multiplied
+// by the scale.
+
+// trigamma returns the derivative of the digamma function, which is
+// infinite at zero.
+func trigamma(x float64) float64 {
+	if x == 0 {
+		return math.Inf(1)
+	}
+	return mathext.Zeta(2, x)
+}
+
+// betaScoreFactor sets sfac to the derivatives of the beta
+// log-likelihood with respect to the linear predictor, multiplied by
+// the scale, for means mn and link derivatives deriv.
+func betaScoreFactor(yda []statmodel.Dtype, mn, deriv []float64, scale float64, sfac []float64) {
+
+	phi := 1/scale - 1
+	for i, y := range yda {
+		yv := float64(y)
+		ys := math.Log(yv / (1 - yv))
+		ms := mathext.Digamma(mn[i]*phi) - mathext.Digamma((1-mn[i])*phi)
+		sfac[i] = scale * phi * (ys - ms) / deriv[i]
+	}
+}
+
+// betaHessFactor sets fac to the negative second derivatives of the
+// beta log-likelihood with respect to the linear predictor, multiplied
+// by the scale.  The expected values are used unless ht is ObsHess, in
+// which case deriv2 must contain the second derivatives of the link.
+func betaHessFactor(yda []statmodel.Dtype, mn, deriv, deriv2 []float64, scale float64, ht statmodel.HessType, fac []float64) {
+
+	phi := 1/scale - 1
+	for i, y := range yda {
+		a := mn[i] * phi
+		b := (1 - mn[i]) * phi
+		d := deriv[i]
+		fac[i] = scale * phi * phi * (trigamma(a) + trigamma(b)) / (d * d)
+		if ht == statmodel.ObsHess {
+			yv := float64(y)
+			ys := math.Log(yv / (1 - yv))
+			ms := mathext.Digamma(a) - mathext.Digamma(b)
+			fac[i] += scale * phi * (ys - ms) * deriv2[i] / (d * d * d)
+		}
+	}
+}
+
+// betaPrecisionDeriv returns the first and second derivatives of the
+// beta log-likelihood with respect to the precision phi, and the mixed
+// second derivatives with respect to the coefficients and phi.  The
+// mixed derivatives are replaced by their expected values unless ht is
+// ObsHess.
+func (model *GLM) betaPrecisionDeriv(coeff []float64, phi float64, ht statmodel.HessType) (float64, []float64, float64) {
+
+	yda := model.data[model.ypos]
+	mn := model.Mean(&GLMParams{coeff, 1 / (1 + phi)}, model.getNslice())
+	deriv := model.getNslice()
+	fac := model.getNslice()
+	model.link.Deriv(mn, deriv)
+
+	var wgts []statmodel.Dtype
+	if model.weightpos != -1 {
+		wgts = model.data[model.weightpos]
+	}
+
+	var d1, d2 float64
+	var w float64 = 1
+	dg := mathext.Digamma(phi)
+	tg := trigamma(phi)
+	for i, y := range yda {
+		if wgts != nil {
+			w = float64(wgts[i])
+		}
+		yv := float64(y)
+		ys := math.Log(yv / (1 - yv))
+		a := mn[i] * phi
+		b := (1 - mn[i]) * phi
+		ms := mathext.Digamma(a) - mathext.Digamma(b)
+		ta := trigamma(a)
+		tb := trigamma(b)
+		d1 += w * (mn[i]*(ys-ms) + math.Log(1-yv) - mathext.Digamma(b) + dg)
+		d2 += w * (tg - mn[i]*mn[i]*ta - (1-mn[i])*(1-mn[i])*tb)
+		fac[i] = -phi * (mn[i]*ta - (1-mn[i])*tb)
+		if ht == statmodel.ObsHess {
+			fac[i] += ys - ms
+		}
+		fac[i] *= w / deriv[i]
+	}
+
+	cross := make([]float64, len(model.xpos))
+	for j, k := range model.xpos {
+		for i, x := range model.data[k] {
+			cross[j] += fac[i] * float64(x)
+		}
+	}
+
+	model.putNslice(mn)
+	model.putNslice(deriv)
+	model.putNslice(fac)
+
+	return d1, cross, d2
+}
+
+// betaDeriv returns the score vector and the information matrix (the
+// negative Hessian) of the beta log-likelihood, with respect to the
+// coefficients followed by the precision.
+func (model *GLM) betaDeriv(coeff []float64, phi float64, ht statmodel.HessType) ([]float64, *mat.SymDense) {
+
+	p := model.NumParams()
+	scale := 1 / (1 + phi)
+	par := &GLMParams{coeff, scale}
+
+	score := make([]float64, p+1)
+	model.Score(par, score[0:p])
+	hess := make([]float64, p*p)
+	model.Hessian(par, ht, hess)
+
+	d1, cross, d2 := model.betaPrecisionDeriv(coeff, phi, ht)
+	score[p] = d1
+
+	info := mat.NewSymDense(p+1, nil)
+	for j1 := 0; j1 < p; j1++ {
+		for j2 := 0; j2 <= j1; j2++ {
+			info.SetSym(j1, j2, -hess[j1*p+j2]/scale)
+		}
+		info.SetSym(j1, p, -cross[j1])
+	}
+	info.SetSym(p, p, -d2)
+
+	return score, info
+}
+
+// betaVcov returns the covariance matrix of the coefficients of a beta
+// regression, and the variance of the precision.  These are obtained
+// by inverting the joint information matrix, since the coefficients and
+// the precision are not orthogonal.
+func (model *GLM) betaVcov(coeff []float64, phi float64) ([]float64, float64, error) {
+
+	p := model.NumParams()
+	_, info := model.betaDeriv(coeff, phi, model.Information())
+
+	var chol mat.Cholesky
+	if !chol.Factorize(info) {
+		return nil, 0, fmt.Errorf("The information matrix of the beta regression is not positive definite")
+	}
+	var vc mat.SymDense
+	if err := chol.InverseTo(&vc); err != nil {
+		return nil, 0, err
+	}
+
+	vcov := make([]float64, p*p)
+	for j1 := 0; j1 < p; j1++ {
+		for j2 := 0; j2 < p; j2++ {
+			vcov[j1*p+j2] = vc.At(j1, j2)
+		}
+	}
+
+	return vcov, vc.At(p, p), nil
+}
+
+// fitBeta obtains the maximum likelihood estimates of the coefficients
+// and the scale parameter of a beta regression, using Fisher scoring for
+// the coefficients and the precision jointly.  The starting values are
+// the quasi-likelihood estimates of the coefficients, obtained using
+// IRLS with the beta variance function, and the corresponding moment
+// estimate of the precision.  Fisher scoring stops when the largest
+// change in a coefficient, or the relative change in the precision, is
+// less than the convergence tolerance.
+func (model *GLM) fitBeta(start []float64, maxiter int) ([]float64, float64, FitInfo, error) {
+
+	tol := model.convtol
+	if tol == 0 {
+		tol = DefaultConvergenceTol
+	}
+
+	p := model.NumParams()
+	params := make([]float64, p)
+	copy(params, start)
+	if p > 0 {
+		var err error
+		params, _, err = model.fitIRLS(start, maxiter)
+		if err != nil {
+			return nil, 0, FitInfo{}, err
+		}
+	}
+
+	chi2, _ := model.pearsonChi2(params)
+	phi := model.residDF()/chi2 - 1
+	if !(phi > 0) || math.IsInf(phi, 0) {
+		phi = 1
+	}
+
+	var info FitInfo
+	ll := model.LogLike(&GLMParams{params, 1 / (1 + phi)}, true)
+	newparams := make([]float64, p)
+	step := mat.NewVecDense(p+1, nil)
+	for iter := 0; iter < maxiter; iter++ {
+
+		score, einfo := model.betaDeriv(params, phi, statmodel.ExpHess)
+
+		var chol mat.Cholesky
+		if !chol.Factorize(einfo) {
+			return nil, 0, info, fmt.Errorf("The information matrix of the beta regression is not positive definite")
+		}
+		if err := chol.SolveVecTo(step, mat.NewVecDense(p+1, score)); err != nil {
+			return nil, 0, info, err
+		}
+
+		// Halve the step until the precision is positive and the
+		// log-likelihood does not decrease, unless the step is below
+		// the tolerance, in which case the change in the
+		// log-likelihood may be due to rounding.
+		var newphi, newll, d float64
+		f := 1.0
+		for k := 0; ; k++ {
+			newphi = phi + f*step.AtVec(p)
+			d = math.Abs(newphi-phi) / phi
+			for j := range newparams {
+				newparams[j] = params[j] + f*step.AtVec(j)
+				d = math.Max(d, math.Abs(newparams[j]-params[j]))
+			}
+			if newphi > 0 {
+				newll = model.LogLike(&GLMParams{newparams, 1 / (1 + newphi)}, true)
+				if newll >= ll || d < tol {
+					break
+				}
+			}
+			if k == 50 {
+				msg := fmt.Sprintf("The beta log-likelihood could not be increased in iteration %d\n", iter+1)
+				return nil, 0, info, fmt.Errorf(msg)
+			}
+			f /= 2
+		}
+
+		copy(params, newparams)
+		phi = newphi
+		ll = newll
+		info.Iterations++
+
+		if model.log != nil {
+			model.log.Printf("Iteration %d: log-likelihood=%.10f precision=%.6f\n", iter+1, ll, phi)
+		}
+
+		if d < tol {
+			info.Converged = true
+			info.Criterion = ConvergeParams
+			break
+		}
+	}
+
+	return params, 1 / (1 + phi), info, nil
+}
@@ -0,0 +1,111 @@
+package glm
+
+import (
+	"fmt"
+
+	"github.com/kshedden/statmodel/statmodel"
+)
+
+// BootstrapVCov returns a bootstrap estimate of the covariance matrix of
+// the coefficients, stored by row.  The model is refit nrep times, and the
+// result is the empirical covariance matrix of the coefficients over the
+// replicates.  If bayesian is false, the replicates use the usual
+// bootstrap, which resamples the observations with replacement.  If
+// bayesian is true, the Bayesian bootstrap is used, in which each
+// replicate reweights the observations with weights from the
+// Dirichlet(1, ..., 1) distribution, scaled to sum to the number of
+// observations.  In both cases the replicates are fit by multiplying the
+// case weights of the model by the bootstrap weights (for the usual
+// bootstrap these are the number of times that each observation is
+// drawn), so the data are not copied.  The Bayesian bootstrap has no tied
+// observations, which gives smoother results in small samples.  The
+// results are reproducible for a given seed.
+func (rslt *GLMResults) BootstrapVCov(nrep int, seed int64, bayesian bool) ([]float64, error) {
+
+	model := rslt.Model().(*GLM)
+
+	if nrep < 2 {
+		msg := fmt.Sprintf("BootstrapVCov: at least two replicates are required, got %d\n", nrep)
+		return nil, fmt.Errorf(msg)
+	}
+
+	n := model.NumObs()
+	p := model.NumParams()
+
+	// The model for the replicates has additional columns for the
+	// reweighted case weights, and the weights used in fitting if these
+	// differ because of per-observation dispersions.
+	bmodel := *model
+	bmodel.nslices = new(nslicePool)
+	bmodel.data = append([][]statmodel.Dtype(nil), model.data...)
+	bmodel.varnames = append([]string(nil), model.varnames...)
+
+	addCol := func(name string) []statmodel.Dtype {
+		x := make([]statmodel.Dtype, n)
+		bmodel.data = append(bmodel.data, x)
+		bmodel.varnames = append(bmodel.varnames, name)
+		return x
+	}
+	bw := addCol("__bootweight")
+	bmodel.weightpos = len(bmodel.data) - 1
+	bmodel.casewpos = bmodel.weightpos
+	var bcw []statmodel.Dtype
+	if model.casewpos != model.weightpos {
+		bcw = addCol("__bootcaseweight")
+		bmodel.casewpos = len(bmodel.data) - 1
+	}
+
+	rs := statmodel.NewResampler(seed)
+	wt := make([]float64, n)
+	params := make([][]float64, nrep)
+	mean := make([]float64, p)
+	for k := range params {
+
+		if bayesian {
+			wt = rs.DirichletWeights(n)
+		} else {
+			zero(wt)
+			for _, i := range rs.Bootstrap(n) {
+				wt[i]++
+			}
+		}
+
+		for i := range bw {
+			bw[i] = statmodel.Dtype(wt[i])
+			if model.weightpos != -1 {
+				bw[i] *= model.data[model.weightpos][i]
+			}
+			if bcw != nil {
+				bcw[i] = statmodel.Dtype(wt[i])
+				if model.casewpos != -1 {
+					bcw[i] *= model.data[model.casewpos][i]
+				}
+			}
+		}
+
+		// Each replicate starts from the estimates.  The starting
+		// values are copied, since L1 regularized fits update them in
+		// place.
+		bmodel.start = append([]float64(nil), rslt.Params()...)
+		br, err := bmodel.FitChecked()
+		if err != nil {
+			msg := fmt.Sprintf("BootstrapVCov: the fit failed for replicate %d: %v\n", k, err)
+			return nil, fmt.Errorf(msg)
+		}
+		params[k] = br.Params()
+		for j, v := range params[k] {
+			mean[j] += v / float64(nrep)
+		}
+	}
+
+	vcov := make([]float64, p*p)
+	for _, pa := range params {
+		for j1 := 0; j1 < p; j1++ {
+			for j2 := 0; j2 < p; j2++ {
+				vcov[j1*p+j2] += (pa[j1] - mean[j1]) * (pa[j2] - mean[j2]) / float64(nrep-1)
+			}
+		}
+	}
+
+	return vcov, nil
+}
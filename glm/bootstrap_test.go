@@ -0,0 +1,113 @@
+package glm
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/kshedden/statmodel/statmodel"
+)
+
+func TestBootstrapVCov(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(5))
+	n := 400
+	y := make([]statmodel.Dtype, n)
+	icept := make([]statmodel.Dtype, n)
+	x := make([]statmodel.Dtype, n)
+	for i := range y {
+		icept[i] = 1
+		x[i] = statmodel.Dtype(rng.NormFloat64())
+		if rng.Float64() < 1/(1+math.Exp(-float64(x[i]))) {
+			y[i] = 1
+		} else {
+			y[i] = 0
+		}
+	}
+	data := statmodel.NewDataset([][]statmodel.Dtype{y, icept, x}, []string{"y", "icept", "x"})
+
+	config := DefaultConfig().WithFamily(NewFamily(BinomialFamily))
+	model, err := NewGLM(data, "y", []string{"icept", "x"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+	se := result.StdErr()
+
+	for _, bayes := range []bool{false, true} {
+		vc, err := result.BootstrapVCov(200, 3, bayes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(vc) != 4 || vc[1] != vc[2] {
+			t.Fail()
+		}
+
+		// The bootstrap standard errors agree roughly with the
+		// model-based standard errors
+		for j := range se {
+			if bse := math.Sqrt(vc[j*2+j]); math.Abs(bse/se[j]-1) > 0.2 {
+				t.Errorf("bayesian=%v: bootstrap SE %v, model SE %v", bayes, bse, se[j])
+			}
+		}
+
+		// Reproducible for a given seed
+		vc2, _ := result.BootstrapVCov(200, 3, bayes)
+		for j := range vc {
+			if vc[j] != vc2[j] {
+				t.Fail()
+			}
+		}
+	}
+
+	// The model is not modified
+	if model.weightpos != -1 || len(model.data) != 3 {
+		t.Fail()
+	}
+
+	if _, err := result.BootstrapVCov(1, 3, true); err == nil {
+		t.Fail()
+	}
+}
+
+// TestBootstrapVCovL1 checks that the bootstrap does not modify the
+// estimates of an L1 regularized fit, and that the replicates differ.
+func TestBootstrapVCovL1(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(7))
+	n := 200
+	y := make([]statmodel.Dtype, n)
+	icept := make([]statmodel.Dtype, n)
+	x := make([]statmodel.Dtype, n)
+	for i := range y {
+		icept[i] = 1
+		x[i] = statmodel.Dtype(rng.NormFloat64())
+		if rng.Float64() < 1/(1+math.Exp(-float64(x[i]))) {
+			y[i] = 1
+		}
+	}
+	data := statmodel.NewDataset([][]statmodel.Dtype{y, icept, x}, []string{"y", "icept", "x"})
+
+	config := DefaultConfig().WithFamily(NewFamily(BinomialFamily)).WithL1Penalty(map[string]float64{"x": 0.01})
+	model, err := NewGLM(data, "y", []string{"icept", "x"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+	params := append([]float64(nil), result.Params()...)
+
+	vc, err := result.BootstrapVCov(50, 3, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for j, v := range result.Params() {
+		if v != params[j] {
+			t.Errorf("the estimates changed from %v to %v", params, result.Params())
+			break
+		}
+	}
+	if !(vc[0] > 0 && vc[3] > 0) {
+		t.Errorf("the bootstrap variances are not positive: %v", vc)
+	}
+}
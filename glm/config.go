@@ -0,0 +1,314 @@
+package glm
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// The methods below allow a Config value to be constructed by chaining,
+// e.g. DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithOffset("off").
+// Errors in the arguments are recorded in the Config value, and are
+// returned by Validate, which is called by NewGLM.
+
+// setErr records the first error encountered while building the Config.
+func (config *Config) setErr(msg string) {
+	if config.err == nil {
+		config.err = fmt.Errorf(msg)
+	}
+}
+
+// WithFamily sets the GLM family.
+func (config *Config) WithFamily(fam *Family) *Config {
+	if fam == nil {
+		config.setErr("WithFamily: the family must not be nil")
+	}
+	config.Family = fam
+	return config
+}
+
+// WithLink sets the GLM link function.
+func (config *Config) WithLink(link *Link) *Config {
+	if link == nil {
+		config.setErr("WithLink: the link must not be nil")
+	}
+	config.Link = link
+	return config
+}
+
+// WithVarFunc sets the GLM variance function.
+func (config *Config) WithVarFunc(vari *Variance) *Config {
+	if vari == nil {
+		config.setErr("WithVarFunc: the variance function must not be nil")
+	}
+	config.VarFunc = vari
+	return config
+}
+
+// WithWeight sets the name of the variable containing case weights.
+func (config *Config) WithWeight(name string) *Config {
+	if strings.TrimSpace(name) == "" {
+		config.setErr("WithWeight: the weight variable name must not be empty")
+	}
+	config.WeightVar = name
+	return config
+}
+
+// WithWeightVec sets the case weights directly, rather than by the name
+// of a variable in the dataset.
+func (config *Config) WithWeightVec(wgt []float64) *Config {
+	config.WeightVec = wgt
+	return config
+}
+
+// WithNormalizedWeights sets whether the case weights are rescaled to
+// sum to the number of cases with positive weight.
+func (config *Config) WithNormalizedWeights(normalize bool) *Config {
+	config.NormalizeWeights = normalize
+	return config
+}
+
+// WithBinomialTrials sets whether the responses of a binomial GLM are
+// proportions, with the case weights giving the numbers of trials.
+func (config *Config) WithBinomialTrials(trials bool) *Config {
+	config.BinomialTrials = trials
+	return config
+}
+
+// WithDispersion sets the name of the variable containing known
+// per-observation dispersions.
+func (config *Config) WithDispersion(name string) *Config {
+	if strings.TrimSpace(name) == "" {
+		config.setErr("WithDispersion: the dispersion variable name must not be empty")
+	}
+	config.DispersionVar = name
+	return config
+}
+
+// WithDispersionVec sets the per-observation dispersions directly,
+// rather than by the name of a variable in the dataset.
+func (config *Config) WithDispersionVec(disp []float64) *Config {
+	config.DispersionVec = disp
+	return config
+}
+
+// WithOffset sets the name of the variable containing an offset.
+func (config *Config) WithOffset(name string) *Config {
+	if strings.TrimSpace(name) == "" {
+		config.setErr("WithOffset: the offset variable name must not be empty")
+	}
+	config.OffsetVar = name
+	return config
+}
+
+// WithOffsetVec sets the offset directly, rather than by the name of a
+// variable in the dataset.
+func (config *Config) WithOffsetVec(off []float64) *Config {
+	config.OffsetVec = off
+	return config
+}
+
+// WithID sets the name of the variable containing observation identifiers.
+func (config *Config) WithID(name string) *Config {
+	if strings.TrimSpace(name) == "" {
+		config.setErr("WithID: the ID variable name must not be empty")
+	}
+	config.IDVar = name
+	return config
+}
+
+// WithFitMethod sets the fitting method, which must be one of IRLS,
+// gradient, or coordinate.
+func (config *Config) WithFitMethod(method string) *Config {
+	config.FitMethod = method
+	return config
+}
+
+// WithStart sets the starting values for the regression parameters.
+func (config *Config) WithStart(start []float64) *Config {
+	config.Start = start
+	return config
+}
+
+// WithL1Penalty sets the L1 (lasso) penalty weights, by variable name.
+func (config *Config) WithL1Penalty(pen map[string]float64) *Config {
+	config.L1Penalty = pen
+	return config
+}
+
+// WithL2Penalty sets the L2 (ridge) penalty weights, by variable name.
+func (config *Config) WithL2Penalty(pen map[string]float64) *Config {
+	config.L2Penalty = pen
+	return config
+}
+
+// WithPenaltyExempt sets the names of variables that are excluded from
+// the L1 and L2 penalties.
+func (config *Config) WithPenaltyExempt(names ...string) *Config {
+	config.PenaltyExempt = names
+	return config
+}
+
+// WithRidge sets the ridge penalty weight.
+func (config *Config) WithRidge(lambda float64) *Config {
+	config.Ridge = lambda
+	return config
+}
+
+// WithDispersionForm sets the approach for handling the dispersion parameter.
+func (config *Config) WithDispersionForm(df DispersionForm) *Config {
+	config.DispersionForm = df
+	return config
+}
+
+// WithScaleEstimator sets the statistic used to estimate the scale parameter.
+func (config *Config) WithScaleEstimator(se ScaleEstimator) *Config {
+	config.ScaleEstimator = se
+	return config
+}
+
+// WithObservedInfo sets whether the standard errors are based on the
+// observed rather than the expected information.
+func (config *Config) WithObservedInfo(obs bool) *Config {
+	config.ObservedInfo = obs
+	return config
+}
+
+// WithPackedHessian sets whether the covariance matrix of the parameters
+// is obtained from the Hessian in packed form.
+func (config *Config) WithPackedHessian(packed bool) *Config {
+	config.PackedHessian = packed
+	return config
+}
+
+// WithNumericalHessian sets whether the Hessian is obtained by
+// numerically differentiating the score.
+func (config *Config) WithNumericalHessian(num bool) *Config {
+	config.NumericalHessian = num
+	return config
+}
+
+// WithNewtonRaphson sets whether the IRLS updates use the observed
+// information (Newton-Raphson) rather than the expected information
+// (Fisher scoring).
+func (config *Config) WithNewtonRaphson(newton bool) *Config {
+	config.NewtonRaphson = newton
+	return config
+}
+
+// WithConvergence sets the IRLS convergence criteria and tolerance.
+// If tol is zero, DefaultConvergenceTol is used.
+func (config *Config) WithConvergence(cc ConvergenceCriterion, tol float64) *Config {
+	if tol < 0 {
+		config.setErr("WithConvergence: the tolerance must not be negative")
+	}
+	config.Convergence = cc
+	config.ConvergenceTol = tol
+	return config
+}
+
+// WithNonConvergence sets how an IRLS fit that does not converge is handled.
+func (config *Config) WithNonConvergence(nc NonConvergence) *Config {
+	config.NonConvergence = nc
+	return config
+}
+
+// WithLinPredBound sets a bound on the absolute value of the linear
+// predictor, which is clamped to [-bound, bound] before the inverse link
+// function is applied.  A bound of zero means that the linear predictor
+// is not clamped.
+func (config *Config) WithLinPredBound(bound float64) *Config {
+	config.LinPredBound = bound
+	return config
+}
+
+// WithLinkCheck sets the strictness of the family/link compatibility check.
+func (config *Config) WithLinkCheck(lc LinkCheck) *Config {
+	config.LinkCheck = lc
+	return config
+}
+
+// WithConstrainMean sets whether the means are constrained to the range
+// of the family during IRLS fitting.
+func (config *Config) WithConstrainMean(constrain bool) *Config {
+	config.ConstrainMean = constrain
+	return config
+}
+
+// WithLog sets a logger to which logging information is written.
+func (config *Config) WithLog(lg *log.Logger) *Config {
+	config.Log = lg
+	return config
+}
+
+// Validate returns an error if the configuration is invalid, including
+// any errors recorded while building the configuration by chaining.
+func (config *Config) Validate() error {
+
+	if config.err != nil {
+		return config.err
+	}
+
+	if config.Family == nil {
+		return fmt.Errorf("A GLM family must be specified")
+	}
+
+	switch strings.ToLower(config.FitMethod) {
+	case "", "irls", "gradient", "coordinate":
+	default:
+		msg := fmt.Sprintf("Unknown fit method '%s'\n", config.FitMethod)
+		return fmt.Errorf(msg)
+	}
+
+	if config.WeightVar != "" && config.WeightVar == config.OffsetVar {
+		msg := fmt.Sprintf("Variable '%s' can not be used as both the weight and the offset\n", config.WeightVar)
+		return fmt.Errorf(msg)
+	}
+
+	if config.BinomialTrials {
+		if config.Family.TypeCode != BinomialFamily {
+			msg := fmt.Sprintf("BinomialTrials requires the binomial family, not %s\n", config.Family.Name)
+			return fmt.Errorf(msg)
+		}
+		if config.WeightVar == "" && config.WeightVec == nil {
+			return fmt.Errorf("BinomialTrials requires the numbers of trials to be given as case weights")
+		}
+		if config.NormalizeWeights {
+			return fmt.Errorf("BinomialTrials can not be used with normalized weights")
+		}
+	}
+
+	if config.Convergence >= ConvergeRelDeviance<<1 {
+		msg := fmt.Sprintf("Unknown convergence criterion %d\n", config.Convergence)
+		return fmt.Errorf(msg)
+	}
+
+	if config.NonConvergence > NonConvergencePanic {
+		msg := fmt.Sprintf("Unknown non-convergence policy %d\n", config.NonConvergence)
+		return fmt.Errorf(msg)
+	}
+
+	if !(config.LinPredBound >= 0) {
+		msg := fmt.Sprintf("The linear predictor bound %f is negative\n", config.LinPredBound)
+		return fmt.Errorf(msg)
+	}
+
+	if config.Ridge < 0 {
+		msg := fmt.Sprintf("The ridge penalty weight %f is negative\n", config.Ridge)
+		return fmt.Errorf(msg)
+	}
+	if config.Ridge > 0 && (len(config.L1Penalty) > 0 || len(config.L2Penalty) > 0) {
+		return fmt.Errorf("The ridge penalty can not be combined with L1 or L2 penalties")
+	}
+
+	for _, pen := range []map[string]float64{config.L1Penalty, config.L2Penalty} {
+		for k, v := range pen {
+			if v < 0 {
+				msg := fmt.Sprintf("The penalty weight for '%s' is negative\n", k)
+				return fmt.Errorf(msg)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,587 @@
+package glm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+
+	"github.com/kshedden/statmodel/statmodel"
+)
+
+// BreuschPagan performs the studentized (Koenker) version of the
+// Breusch-Pagan test for heteroscedasticity.  The squared Pearson
+// residuals are regressed on the variables named in zvars (using an
+// auxiliary Gaussian GLM that includes an intercept), and the test
+// statistic is n*R^2, where n is the sum of the case weights and R^2 is
+// the coefficient of determination for the auxiliary regression.  If
+// zvars is nil, the covariates of the model are used.  Variables that
+// are constant are not included.  The test statistic, its degrees of
+// freedom, and the p-value based on the chi-square distribution are
+// returned.  The test is mainly intended for Gaussian models, where it
+// tests for non-constant variance, but for other families it tests
+// whether the variance function is correctly specified.
+func (rslt *GLMResults) BreuschPagan(zvars []string) (float64, float64, float64, error) {
+
+	model := rslt.Model().(*GLM)
+
+	pos := make(map[string]int)
+	for i, v := range model.varnames {
+		pos[v] = i
+	}
+
+	var zpos []int
+	if zvars == nil {
+		zpos = model.xpos
+	} else {
+		for _, na := range zvars {
+			k, ok := pos[na]
+			if !ok {
+				msg := fmt.Sprintf("Variable '%s' not found in dataset\n", na)
+				return 0, 0, 0, fmt.Errorf(msg)
+			}
+			zpos = append(zpos, k)
+		}
+	}
+
+	nobs := model.NumObs()
+
+	// The squared Pearson residuals
+	resid := rslt.PearsonResid(nil)
+	u := make([]statmodel.Dtype, nobs)
+	for i, r := range resid {
+		u[i] = statmodel.Dtype(r * r)
+	}
+
+	icept := make([]statmodel.Dtype, nobs)
+	for i := range icept {
+		icept[i] = 1
+	}
+
+	data := [][]statmodel.Dtype{u, icept}
+	names := []string{"u", "icept"}
+	for _, k := range zpos {
+		z := model.data[k]
+		if isConstant(z) {
+			continue
+		}
+		data = append(data, z)
+		names = append(names, model.varnames[k])
+	}
+
+	df := float64(len(data) - 2)
+	if df == 0 {
+		return 0, 0, 0, fmt.Errorf("BreuschPagan requires at least one non-constant variable")
+	}
+
+	xnames := names[1:]
+
+	config := DefaultConfig()
+	var wgt []statmodel.Dtype
+	if model.weightpos != -1 {
+		wgt = model.data[model.weightpos]
+		data = append(data, wgt)
+		names = append(names, "__weight")
+		config.WeightVar = "__weight"
+	}
+
+	aux, err := NewGLM(statmodel.NewDataset(data, names), "u", xnames, config)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	arslt, err := aux.FitChecked()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	ssr := arslt.Deviance()
+
+	// The total sum of squares
+	var ws, um float64
+	for i := range u {
+		w := 1.0
+		if wgt != nil {
+			w = float64(wgt[i])
+		}
+		ws += w
+		um += w * float64(u[i])
+	}
+	um /= ws
+	var sst float64
+	for i := range u {
+		w := 1.0
+		if wgt != nil {
+			w = float64(wgt[i])
+		}
+		d := float64(u[i]) - um
+		sst += w * d * d
+	}
+
+	lm := ws * (1 - ssr/sst)
+	pv := distuv.ChiSquared{K: df}.Survival(lm)
+
+	return lm, df, pv, nil
+}
+
+// OverdispersionTest performs the score (Lagrange multiplier) test of
+// Cameron and Trivedi for overdispersion in a fitted Poisson model.  The
+// alternative is the negative binomial (NB2) model, with variance
+// mu + alpha*mu^2, and the null hypothesis is alpha = 0.  The test
+// statistic is sum((y - mu)^2 - y) / sqrt(2 * sum(mu^2)), using the
+// case weights if present, which has a standard normal distribution
+// under the null hypothesis.  The statistic and the one-sided p-value
+// (large values of the statistic indicate overdispersion) are returned.
+func (rslt *GLMResults) OverdispersionTest() (float64, float64, error) {
+
+	model := rslt.Model().(*GLM)
+	if model.fam.TypeCode != PoissonFamily {
+		msg := fmt.Sprintf("OverdispersionTest requires a Poisson model, not %s\n", model.fam.Name)
+		return 0, 0, fmt.Errorf(msg)
+	}
+
+	mn := rslt.Mean()
+	yda := model.data[model.ypos]
+
+	var wgt []statmodel.Dtype
+	if model.weightpos != -1 {
+		wgt = model.data[model.weightpos]
+	}
+
+	var num, den float64
+	for i, y := range yda {
+		w := 1.0
+		if wgt != nil {
+			w = float64(wgt[i])
+		}
+		r := float64(y) - mn[i]
+		num += w * (r*r - float64(y))
+		den += w * mn[i] * mn[i]
+	}
+
+	z := num / math.Sqrt(2*den)
+	pv := distuv.UnitNormal.Survival(z)
+
+	return z, pv, nil
+}
+
+// FitMetrics contains simple measures of predictive accuracy, comparing
+// the fitted means to the observed responses on the response scale.
+type FitMetrics struct {
+
+	// The correlation between the fitted means and the observed
+	// responses.
+	Corr float64
+
+	// The mean squared error.
+	MSE float64
+
+	// The mean absolute error.
+	MAE float64
+}
+
+// FitMetrics returns the correlation between the fitted means and the
+// observed responses, along with the mean squared error and the mean
+// absolute error.  If the model has case weights, all three metrics are
+// weighted, using the same weights that were used in fitting.
+func (rslt *GLMResults) FitMetrics() *FitMetrics {
+
+	model := rslt.Model().(*GLM)
+	mn := rslt.Mean()
+
+	yda := model.data[model.ypos]
+	y := make([]float64, len(yda))
+	for i := range yda {
+		y[i] = float64(yda[i])
+	}
+
+	var w []float64
+	if model.weightpos != -1 {
+		w = make([]float64, len(y))
+		for i, v := range model.data[model.weightpos] {
+			w[i] = float64(v)
+		}
+	}
+
+	var mse, mae, ws float64
+	for i := range y {
+		wt := 1.0
+		if w != nil {
+			wt = w[i]
+		}
+		d := y[i] - mn[i]
+		mse += wt * d * d
+		mae += wt * math.Abs(d)
+		ws += wt
+	}
+
+	return &FitMetrics{
+		Corr: stat.Correlation(mn, y, w),
+		MSE:  mse / ws,
+		MAE:  mae / ws,
+	}
+}
+
+// ResidSummary contains the moments of a set of residuals, along with the
+// Jarque-Bera test of normality, as a diagnostic that does not require
+// graphics.
+type ResidSummary struct {
+
+	// The sum of the weights, or the number of residuals if the
+	// residuals are not weighted.
+	N float64
+
+	// The mean of the residuals
+	Mean float64
+
+	// The standard deviation of the residuals
+	SD float64
+
+	// The skewness of the residuals
+	Skew float64
+
+	// The excess kurtosis of the residuals, which is zero for normally
+	// distributed residuals.
+	ExKurtosis float64
+
+	// The Jarque-Bera statistic, N*(Skew^2 + ExKurtosis^2/4)/6
+	JarqueBera float64
+
+	// The p-value for the Jarque-Bera statistic, based on its limiting
+	// chi-square distribution with 2 degrees of freedom.
+	JarqueBeraPValue float64
+}
+
+// SummarizeResid returns the moments and the Jarque-Bera normality test
+// for the given residuals, which can be of any type, e.g. from Resid,
+// PearsonResid, or FittedResid.  If wgt is not nil, it contains
+// frequency weights for the residuals, which must have the same length
+// as resid.  The skewness and kurtosis are based on the moments about
+// the mean with divisor N, as in the Jarque-Bera statistic, while the
+// standard deviation uses divisor N-1.
+func SummarizeResid(resid, wgt []float64) *ResidSummary {
+
+	if wgt != nil && len(wgt) != len(resid) {
+		msg := fmt.Sprintf("SummarizeResid: there are %d residuals but %d weights\n", len(resid), len(wgt))
+		panic(msg)
+	}
+
+	mean := stat.Mean(resid, wgt)
+
+	var n, m2, m3, m4 float64
+	for i, r := range resid {
+		w := 1.0
+		if wgt != nil {
+			w = wgt[i]
+		}
+		d := r - mean
+		d2 := d * d
+		n += w
+		m2 += w * d2
+		m3 += w * d2 * d
+		m4 += w * d2 * d2
+	}
+	m2 /= n
+	m3 /= n
+	m4 /= n
+
+	skew := m3 / math.Pow(m2, 1.5)
+	kurt := m4/(m2*m2) - 3
+	jb := n * (skew*skew + kurt*kurt/4) / 6
+
+	return &ResidSummary{
+		N:                n,
+		Mean:             mean,
+		SD:               math.Sqrt(m2 * n / (n - 1)),
+		Skew:             skew,
+		ExKurtosis:       kurt,
+		JarqueBera:       jb,
+		JarqueBeraPValue: math.Exp(-jb / 2),
+	}
+}
+
+// String returns a text summary of the residual distribution.
+func (rs *ResidSummary) String() string {
+	return fmt.Sprintf("N = %.4g, mean = %.4g, SD = %.4g, skewness = %.4g, excess kurtosis = %.4g\nJarque-Bera = %.4g, p-value = %.4g",
+		rs.N, rs.Mean, rs.SD, rs.Skew, rs.ExKurtosis, rs.JarqueBera, rs.JarqueBeraPValue)
+}
+
+// ResidType is a type of residual.
+type ResidType int
+
+const (
+	// ResidResponse residuals are the observed minus the fitted values.
+	ResidResponse ResidType = iota
+
+	// ResidPearson residuals are the response residuals divided by the
+	// model-based standard deviation.
+	ResidPearson
+
+	// ResidDeviance residuals are the signed square roots of the
+	// contributions of the observations to the (unscaled) deviance.
+	ResidDeviance
+
+	// ResidStdPearson residuals are the Pearson residuals adjusted for
+	// the case weights and leverage, see StdPearsonResid.
+	ResidStdPearson
+
+	// ResidStdDeviance residuals are the deviance residuals adjusted for
+	// the scale and leverage, see StudentizedResid.
+	ResidStdDeviance
+)
+
+// FittedResid contains paired fitted means and residuals, as used in a
+// plot of the residuals against the fitted values.
+type FittedResid struct {
+
+	// The fitted means
+	Fitted []float64
+
+	// The residuals
+	Resid []float64
+
+	// Index[i] is the position in the data of the observation with
+	// fitted value Fitted[i] and residual Resid[i].
+	Index []int
+}
+
+// FittedResid returns the fitted means and the residuals of the given
+// type.  If sorted is true, the observations are sorted by increasing
+// fitted value, otherwise they are in the order of the data.  The
+// standardized residual types require the leverages, so FittedResid
+// panics if these are requested for an L1 regularized fit.
+func (rslt *GLMResults) FittedResid(rt ResidType, sorted bool) *FittedResid {
+
+	mn := rslt.Mean()
+
+	var resid []float64
+	var err error
+	switch rt {
+	case ResidResponse:
+		resid = rslt.Resid(nil)
+	case ResidPearson:
+		resid = rslt.PearsonResid(nil)
+	case ResidDeviance:
+		resid = rslt.devianceResid(mn)
+	case ResidStdPearson:
+		if resid, err = rslt.StdPearsonResid(); err != nil {
+			panic(err)
+		}
+	case ResidStdDeviance:
+		if resid, err = rslt.StudentizedResid(); err != nil {
+			panic(err)
+		}
+	default:
+		msg := fmt.Sprintf("FittedResid: unknown residual type %d\n", rt)
+		panic(msg)
+	}
+
+	ix := make([]int, len(mn))
+	for i := range ix {
+		ix[i] = i
+	}
+
+	if !sorted {
+		return &FittedResid{Fitted: mn, Resid: resid, Index: ix}
+	}
+
+	sort.SliceStable(ix, func(i, j int) bool { return mn[ix[i]] < mn[ix[j]] })
+	fr := &FittedResid{
+		Fitted: make([]float64, len(mn)),
+		Resid:  make([]float64, len(mn)),
+		Index:  ix,
+	}
+	for i, k := range ix {
+		fr.Fitted[i] = mn[k]
+		fr.Resid[i] = resid[k]
+	}
+	return fr
+}
+
+// devianceResid returns the deviance residuals, given the fitted means.
+func (rslt *GLMResults) devianceResid(mn []float64) []float64 {
+
+	model := rslt.Model().(*GLM)
+	yda := model.data[model.ypos]
+
+	var wgt []statmodel.Dtype
+	if model.weightpos != -1 {
+		wgt = model.data[model.weightpos]
+	}
+
+	resid := make([]float64, len(mn))
+	for i := range yda {
+		var w []statmodel.Dtype
+		if wgt != nil {
+			w = wgt[i : i+1]
+		}
+		d := model.fam.Deviance(yda[i:i+1], mn[i:i+1], w, 1)
+		resid[i] = math.Sqrt(math.Max(d, 0))
+		if float64(yda[i]) < mn[i] {
+			resid[i] = -resid[i]
+		}
+	}
+
+	return resid
+}
+
+// Leverage returns the leverages (hat values) of the observations, the
+// diagonal elements of the hat matrix W^(1/2) X (X'WX)^(-1) X' W^(1/2)
+// of the final IRLS iteration, where W contains the IRLS weights at the
+// fitted parameters.  For ridge fits, the penalty is included in X'WX.
+// An error is returned for L1 regularized fits, or if X'WX is singular.
+func (rslt *GLMResults) Leverage() ([]float64, error) {
+
+	model := rslt.Model().(*GLM)
+	if model.l1wgt != nil {
+		return nil, fmt.Errorf("Leverage can not be used with L1 regularized fits")
+	}
+
+	mn := rslt.Mean()
+	model.clampMean(mn)
+	lderiv := make([]float64, len(mn))
+	va := make([]float64, len(mn))
+	model.link.Deriv(mn, lderiv)
+	model.vari.Var(mn, va)
+
+	hat := make([]float64, len(mn))
+	p := model.NumParams()
+	if p == 0 {
+		return hat, nil
+	}
+
+	xtx, _ := rslt.IRLSMoments()
+	if model.l2wgt != nil {
+		nobs := float64(len(mn))
+		for j, v := range model.l2wgt {
+			xtx[j*p+j] += nobs * v
+		}
+	}
+
+	var xtxi mat.Dense
+	if err := xtxi.Inverse(mat.NewDense(p, p, xtx)); err != nil {
+		return nil, err
+	}
+
+	var wgt []statmodel.Dtype
+	if model.weightpos != -1 {
+		wgt = model.data[model.weightpos]
+	}
+
+	xr := make([]float64, p)
+	for i := range hat {
+		for j, k := range model.xpos {
+			xr[j] = float64(model.data[k][i])
+		}
+		var q float64
+		for j1 := range xr {
+			for j2 := range xr {
+				q += xr[j1] * xtxi.At(j1, j2) * xr[j2]
+			}
+		}
+		w := 1 / (lderiv[i] * lderiv[i] * va[i])
+		if wgt != nil {
+			w *= float64(wgt[i])
+		}
+		hat[i] = w * q
+	}
+
+	return hat, nil
+}
+
+// StudentizedResid returns the studentized (standardized) deviance
+// residuals r_i / sqrt(scale * (1 - h_i)), where r_i is the deviance
+// residual and h_i is the leverage of observation i.  These residuals
+// have approximately unit variance, and are preferred to the deviance
+// residuals for identifying outliers and for normal probability plots.
+// The residuals are not finite for observations with leverage 1.  An
+// error is returned if the leverages are not available.
+func (rslt *GLMResults) StudentizedResid() ([]float64, error) {
+
+	hat, err := rslt.Leverage()
+	if err != nil {
+		return nil, err
+	}
+
+	resid := rslt.devianceResid(rslt.Mean())
+	for i, h := range hat {
+		resid[i] /= math.Sqrt(rslt.scale * (1 - h))
+	}
+
+	return resid, nil
+}
+
+// PartialResid returns the partial (component plus residual) residuals
+// for the covariate named vname, which are the working residuals
+// (y - mu) * g'(mu) plus b * x, where g is the link function, and b is
+// the coefficient of the covariate x.  Plotting the partial residuals
+// against the covariate shows the functional form of its relationship
+// with the linear predictor, and curvature in the plot suggests that the
+// covariate should be transformed.  An error is returned if vname is not
+// a covariate in the model.
+func (rslt *GLMResults) PartialResid(vname string) ([]float64, error) {
+
+	model := rslt.Model().(*GLM)
+	j, err := statmodel.ParamIndex(model, vname)
+	if err != nil {
+		return nil, err
+	}
+
+	yda := model.data[model.ypos]
+	x := model.data[model.xpos[j]]
+	b := rslt.Params()[j]
+
+	mn := rslt.Mean()
+	lderiv := make([]float64, len(mn))
+	model.link.Deriv(mn, lderiv)
+
+	resid := make([]float64, len(mn))
+	for i := range resid {
+		resid[i] = (float64(yda[i])-mn[i])*lderiv[i] + b*float64(x[i])
+	}
+
+	return resid, nil
+}
+
+// StdPearsonResid returns the standardized Pearson residuals
+// sqrt(w_i) * (y_i - mu_i) / sqrt(scale * V(mu_i) * (1 - h_i)), where w_i
+// is the case weight, V is the variance function, and h_i is the
+// leverage of observation i.  These residuals have approximately unit
+// variance, unlike the Pearson residuals, whose variance is reduced for
+// observations with high leverage.  The residuals are not finite for
+// observations with leverage 1.  An error is returned if the leverages
+// are not available.
+func (rslt *GLMResults) StdPearsonResid() ([]float64, error) {
+
+	hat, err := rslt.Leverage()
+	if err != nil {
+		return nil, err
+	}
+
+	model := rslt.Model().(*GLM)
+	var wgt []statmodel.Dtype
+	if model.weightpos != -1 {
+		wgt = model.data[model.weightpos]
+	}
+
+	resid := rslt.PearsonResid(nil)
+	for i, h := range hat {
+		if wgt != nil {
+			resid[i] *= math.Sqrt(float64(wgt[i]))
+		}
+		resid[i] /= math.Sqrt(1 - h)
+	}
+
+	return resid, nil
+}
+
+// isConstant returns true if all elements of x are equal.
+func isConstant(x []statmodel.Dtype) bool {
+	for i := range x {
+		if x[i] != x[0] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,427 @@
+package glm
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/kshedden/statmodel/statmodel"
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// Simulate data with a linear mean, and a variance that increases with
+// x2 if het is true.
+func dataHet(n int, het bool) statmodel.Dataset {
+
+	var y, x1, x2 []statmodel.Dtype
+	for i := 0; i < n; i++ {
+		x := float64(i) / float64(n)
+		e := math.Sin(float64(7*i)) + math.Cos(float64(13*i))
+		if het {
+			e *= 4 * x
+		}
+		x1 = append(x1, 1)
+		x2 = append(x2, statmodel.Dtype(x))
+		y = append(y, statmodel.Dtype(1+x+e))
+	}
+
+	return statmodel.NewDataset([][]statmodel.Dtype{y, x1, x2}, []string{"y", "x1", "x2"})
+}
+
+func TestBreuschPagan(t *testing.T) {
+
+	for _, het := range []bool{false, true} {
+		model, err := NewGLM(dataHet(200, het), "y", []string{"x1", "x2"}, nil)
+		if err != nil {
+			panic(err)
+		}
+		result := model.Fit()
+
+		st, df, pv, err := result.BreuschPagan(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if df != 1 {
+			t.Fail()
+		}
+
+		// With one variable, R^2 is the squared correlation.
+		resid := result.PearsonResid(nil)
+		u := make([]float64, len(resid))
+		x := make([]float64, len(resid))
+		for i, r := range resid {
+			u[i] = r * r
+			x[i] = float64(model.data[2][i])
+		}
+		r := stat.Correlation(u, x, nil)
+		if !scalarClose(st, 200*r*r, 1e-6) {
+			t.Logf("%v != %v\n", st, 200*r*r)
+			t.Fail()
+		}
+
+		if het && pv > 1e-4 {
+			t.Fail()
+		}
+		if !het && pv < 0.01 {
+			t.Fail()
+		}
+	}
+}
+
+func TestFitMetrics(t *testing.T) {
+
+	// Duplicating a case is equivalent to giving it weight 2.
+	y := []statmodel.Dtype{1, 3, 2, 6, 4, 7}
+	x := []statmodel.Dtype{0, 1, 1, 2, 3, 3}
+	icept := []statmodel.Dtype{1, 1, 1, 1, 1, 1}
+	w := []statmodel.Dtype{1, 2, 1, 1, 1, 2}
+
+	var yd, xd, id []statmodel.Dtype
+	for i := range y {
+		for j := 0; j < int(w[i]); j++ {
+			yd = append(yd, y[i])
+			xd = append(xd, x[i])
+			id = append(id, 1)
+		}
+	}
+
+	var metrics []*FitMetrics
+	for k := 0; k < 2; k++ {
+		var ds statmodel.Dataset
+		config := DefaultConfig()
+		config.Family = NewFamily(PoissonFamily)
+		if k == 0 {
+			ds = statmodel.NewDataset([][]statmodel.Dtype{y, icept, x, w}, []string{"y", "icept", "x", "w"})
+			config.WeightVar = "w"
+		} else {
+			ds = statmodel.NewDataset([][]statmodel.Dtype{yd, id, xd}, []string{"y", "icept", "x"})
+		}
+		model, err := NewGLM(ds, "y", []string{"icept", "x"}, config)
+		if err != nil {
+			panic(err)
+		}
+		result := model.Fit()
+		metrics = append(metrics, result.FitMetrics())
+
+		// Check against a direct calculation
+		if k == 1 {
+			mn := result.Mean()
+			yf := make([]float64, len(yd))
+			var mse float64
+			for i := range yd {
+				yf[i] = float64(yd[i])
+				mse += (yf[i] - mn[i]) * (yf[i] - mn[i])
+			}
+			mse /= float64(len(yd))
+			if !scalarClose(metrics[1].MSE, mse, 1e-10) {
+				t.Fail()
+			}
+			if !scalarClose(metrics[1].Corr, stat.Correlation(mn, yf, nil), 1e-10) {
+				t.Fail()
+			}
+		}
+	}
+
+	m0, m1 := metrics[0], metrics[1]
+	if !scalarClose(m0.Corr, m1.Corr, 1e-8) || !scalarClose(m0.MSE, m1.MSE, 1e-8) || !scalarClose(m0.MAE, m1.MAE, 1e-8) {
+		t.Logf("%+v %+v\n", m0, m1)
+		t.Fail()
+	}
+}
+
+func TestFittedResid(t *testing.T) {
+
+	y := []statmodel.Dtype{1, 3, 2, 6, 4, 7}
+	x := []statmodel.Dtype{0, 3, 1, 2, 1, 3}
+	icept := []statmodel.Dtype{1, 1, 1, 1, 1, 1}
+	w := []statmodel.Dtype{1, 2, 1, 1, 1, 2}
+	ds := statmodel.NewDataset([][]statmodel.Dtype{y, icept, x, w}, []string{"y", "icept", "x", "w"})
+
+	config := DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithWeight("w")
+	model, err := NewGLM(ds, "y", []string{"icept", "x"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+	mn := result.Mean()
+
+	// The weighted squared deviance residuals sum to the deviance
+	fr := result.FittedResid(ResidDeviance, false)
+	var d float64
+	for _, r := range fr.Resid {
+		d += r * r
+	}
+	if !scalarClose(d, result.Deviance(), 1e-8) {
+		t.Fail()
+	}
+
+	for _, rt := range []ResidType{ResidResponse, ResidPearson, ResidDeviance, ResidStdPearson, ResidStdDeviance} {
+
+		fu := result.FittedResid(rt, false)
+		fs := result.FittedResid(rt, true)
+
+		for i, k := range fs.Index {
+			if fs.Fitted[i] != mn[k] || fs.Resid[i] != fu.Resid[k] {
+				t.Fail()
+			}
+			if i > 0 && fs.Fitted[i] < fs.Fitted[i-1] {
+				t.Fail()
+			}
+			if fu.Index[i] != i || fu.Fitted[i] != mn[i] {
+				t.Fail()
+			}
+		}
+	}
+
+	// The deviance residuals have the same signs as the response residuals
+	resid := result.Resid(nil)
+	rr := result.FittedResid(ResidResponse, false).Resid
+	for i := range resid {
+		if rr[i] != resid[i] || math.Signbit(rr[i]) != math.Signbit(fr.Resid[i]) {
+			t.Fail()
+		}
+	}
+}
+
+func TestOverdispersion(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(156))
+	n := 500
+
+	// If od is true, the Poisson means have a gamma distributed
+	// multiplier, giving negative binomial responses.
+	sim := func(od bool) statmodel.Dataset {
+		var y, x1, x2 []statmodel.Dtype
+		for i := 0; i < n; i++ {
+			x := rng.NormFloat64()
+			mu := math.Exp(1 + 0.5*x)
+			if od {
+				// Gamma with mean 1 and variance 1/2
+				mu *= (rng.ExpFloat64() + rng.ExpFloat64()) / 2
+			}
+
+			// Simulate Poisson by counting exponential arrivals
+			var k int
+			for u := rng.ExpFloat64(); u < mu; u += rng.ExpFloat64() {
+				k++
+			}
+			y = append(y, statmodel.Dtype(k))
+			x1 = append(x1, 1)
+			x2 = append(x2, statmodel.Dtype(x))
+		}
+		return statmodel.NewDataset([][]statmodel.Dtype{y, x1, x2}, []string{"y", "x1", "x2"})
+	}
+
+	for _, od := range []bool{false, true} {
+		config := DefaultConfig().WithFamily(NewFamily(PoissonFamily))
+		model, err := NewGLM(sim(od), "y", []string{"x1", "x2"}, config)
+		if err != nil {
+			panic(err)
+		}
+		stat, pv, err := model.Fit().OverdispersionTest()
+		if err != nil {
+			panic(err)
+		}
+		if od && (stat < 5 || pv > 1e-4) {
+			t.Logf("%v %v\n", stat, pv)
+			t.Fail()
+		}
+		if !od && (math.Abs(stat) > 3 || !scalarClose(pv, distuv.UnitNormal.Survival(stat), 1e-12)) {
+			t.Logf("%v %v\n", stat, pv)
+			t.Fail()
+		}
+	}
+
+	// Only Poisson models can be tested
+	model, err := NewGLM(dataHet(50, false), "y", []string{"x1", "x2"}, DefaultConfig())
+	if err != nil {
+		panic(err)
+	}
+	if _, _, err := model.Fit().OverdispersionTest(); err == nil {
+		t.Fail()
+	}
+}
+
+func TestStudentizedResid(t *testing.T) {
+
+	for _, fam := range []FamilyType{GaussianFamily, PoissonFamily, BinomialFamily} {
+		config := DefaultConfig()
+		config.Family = NewFamily(fam)
+		config.WeightVar = "w"
+		model, err := NewGLM(data2(), "y", []string{"x1", "x2", "x3"}, config)
+		if err != nil {
+			panic(err)
+		}
+		result := model.Fit()
+
+		hat, err := result.Leverage()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The leverages sum to the number of parameters
+		var sh float64
+		for _, h := range hat {
+			if h < 0 || h > 1 {
+				t.Fail()
+			}
+			sh += h
+		}
+		if !scalarClose(sh, 3, 1e-8) {
+			t.Logf("%v sum of leverages %f\n", fam, sh)
+			t.Fail()
+		}
+
+		sr, err := result.StudentizedResid()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dr := result.FittedResid(ResidDeviance, false).Resid
+		for i := range sr {
+			if !scalarClose(sr[i], dr[i]/math.Sqrt(result.Scale()*(1-hat[i])), 1e-10) {
+				t.Fail()
+			}
+		}
+
+		spr, err := result.StdPearsonResid()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pr := result.PearsonResid(nil)
+		w := data2().Data()[4]
+		for i := range spr {
+			if !scalarClose(spr[i], pr[i]*math.Sqrt(float64(w[i])/(1-hat[i])), 1e-10) {
+				t.Fail()
+			}
+		}
+
+		// For the Gaussian family these are the internally studentized
+		// residuals of weighted least squares, and the standardized
+		// Pearson and deviance residuals agree.
+		if fam == GaussianFamily {
+			if !floats.EqualApprox(spr, sr, 1e-10) {
+				t.Fail()
+			}
+			resid := result.Resid(nil)
+			for i := range sr {
+				r := resid[i] * math.Sqrt(float64(w[i])) / math.Sqrt(result.Scale()*(1-hat[i]))
+				if !scalarClose(sr[i], r, 1e-8) {
+					t.Fail()
+				}
+			}
+		}
+	}
+}
+
+func TestPartialResid(t *testing.T) {
+
+	xnames := []string{"x1", "x2", "x3"}
+	config := DefaultConfig().WithWeight("w")
+	model, err := NewGLM(data2(), "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	pr, err := result.PartialResid("x2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// For a linear model, regressing the partial residuals on the
+	// covariates gives the coefficient of x2, and zero for the other
+	// covariates.
+	da := data2().Data()
+	prd := make([]statmodel.Dtype, len(pr))
+	for i, v := range pr {
+		prd[i] = statmodel.Dtype(v)
+	}
+	pdata := statmodel.NewDataset([][]statmodel.Dtype{prd, da[1], da[2], da[3], da[4]},
+		[]string{"y", "x1", "x2", "x3", "w"})
+	pmodel, err := NewGLM(pdata, "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	pa := pmodel.Fit().Params()
+	want := []float64{0, result.Params()[1], 0}
+	for j := range want {
+		if !scalarClose(pa[j], want[j], 1e-8) {
+			t.Errorf("coefficient %d: got %v, expected %v", j, pa[j], want[j])
+		}
+	}
+
+	// For the Poisson family with the log link, the working residuals
+	// are (y - mu) / mu.
+	config = DefaultConfig().WithFamily(NewFamily(PoissonFamily))
+	model, err = NewGLM(data4(), "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	result = model.Fit()
+	pr, err = result.PartialResid("x3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mn := result.Mean()
+	da = data4().Data()
+	b := result.Params()[2]
+	for i := range pr {
+		r := (float64(da[0][i])-mn[i])/mn[i] + b*float64(da[3][i])
+		if !scalarClose(pr[i], r, 1e-10) {
+			t.Fail()
+		}
+	}
+
+	if _, err := result.PartialResid("w"); err == nil {
+		t.Fail()
+	}
+}
+
+func TestSummarizeResid(t *testing.T) {
+
+	// Moments of a small sample, computed by hand
+	rs := SummarizeResid([]float64{-2, -1, 0, 0, 3}, nil)
+	if !scalarClose(rs.N, 5, 1e-12) || !scalarClose(rs.Mean, 0, 1e-12) || !scalarClose(rs.SD, math.Sqrt(14.0/4), 1e-12) {
+		t.Errorf("unexpected moments: %v", rs)
+	}
+	m2 := 14.0 / 5
+	skew := (18.0 / 5) / math.Pow(m2, 1.5)
+	kurt := (98.0/5)/(m2*m2) - 3
+	if !scalarClose(rs.Skew, skew, 1e-12) || !scalarClose(rs.ExKurtosis, kurt, 1e-12) {
+		t.Errorf("unexpected skewness or kurtosis: %v", rs)
+	}
+	jb := 5 * (skew*skew + kurt*kurt/4) / 6
+	if !scalarClose(rs.JarqueBera, jb, 1e-12) || !scalarClose(rs.JarqueBeraPValue, distuv.ChiSquared{K: 2}.Survival(jb), 1e-12) {
+		t.Fail()
+	}
+
+	// Integer weights are equivalent to repeating the residuals
+	rw := SummarizeResid([]float64{-2, -1, 0, 3}, []float64{1, 1, 2, 1})
+	if !scalarClose(rw.SD, rs.SD, 1e-12) || !scalarClose(rw.Skew, rs.Skew, 1e-12) || !scalarClose(rw.JarqueBera, rs.JarqueBera, 1e-12) {
+		t.Fail()
+	}
+
+	// Normal residuals are not rejected, exponential residuals are
+	rng := rand.New(rand.NewSource(4))
+	x := make([]float64, 1000)
+	z := make([]float64, 1000)
+	for i := range x {
+		x[i] = rng.NormFloat64()
+		z[i] = rng.ExpFloat64()
+	}
+	if SummarizeResid(x, nil).JarqueBeraPValue < 0.01 || SummarizeResid(z, nil).JarqueBeraPValue > 1e-6 {
+		t.Fail()
+	}
+
+	// Residuals from a fitted model
+	model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, nil)
+	if err != nil {
+		panic(err)
+	}
+	rm := SummarizeResid(model.Fit().Resid(nil), nil)
+	if !scalarClose(rm.Mean, 0, 1e-8) {
+		t.Fail()
+	}
+}
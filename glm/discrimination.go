@@ -0,0 +1,406 @@
+package glm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/kshedden/statmodel/statmodel"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// AUCResult contains the area under the ROC curve (AUC), with its
+// standard error and confidence interval.
+type AUCResult struct {
+
+	// The area under the ROC curve
+	AUC float64
+
+	// The standard error of the AUC, using the method of DeLong et al.
+	StdErr float64
+
+	// The lower and upper limits of the normal-approximation
+	// confidence interval, truncated to [0, 1].
+	LCB float64
+	UCB float64
+
+	// The coverage probability of the confidence interval
+	Level float64
+}
+
+// AUC returns the area under the ROC curve for the predicted values
+// pred of the binary (0/1) outcomes y, which is the probability that a
+// randomly selected case with y = 1 has a greater predicted value than a
+// randomly selected case with y = 0, counting ties as 1/2.  The standard
+// error is obtained using the structural components method of DeLong,
+// DeLong, and Clarke-Pearson (1988), and the confidence interval with
+// coverage probability level (e.g. 0.95) uses the normal approximation.
+// At least two cases with each outcome value are required for the
+// standard error, otherwise it is NaN.
+func AUC(y []statmodel.Dtype, pred []float64, level float64) (*AUCResult, error) {
+
+	v10, v01, err := aucComponents(y, pred)
+	if err != nil {
+		return nil, err
+	}
+
+	auc := stat.Mean(v10, nil)
+
+	m, n := float64(len(v10)), float64(len(v01))
+	se := math.NaN()
+	if m > 1 && n > 1 {
+		se = math.Sqrt(stat.Variance(v10, nil)/m + stat.Variance(v01, nil)/n)
+	}
+
+	q := distuv.UnitNormal.Quantile((1 + level) / 2)
+
+	return &AUCResult{
+		AUC:    auc,
+		StdErr: se,
+		LCB:    math.Max(0, auc-q*se),
+		UCB:    math.Min(1, auc+q*se),
+		Level:  level,
+	}, nil
+}
+
+// aucComponents returns the DeLong structural components of the AUC.
+// The value v10[i] is the proportion of cases with y = 0 whose predicted
+// values are less than that of the i^th case with y = 1, and v01[j] is
+// the proportion of cases with y = 1 whose predicted values are greater
+// than that of the j^th case with y = 0, counting ties as 1/2.
+func aucComponents(y []statmodel.Dtype, pred []float64) ([]float64, []float64, error) {
+
+	if len(y) != len(pred) {
+		msg := fmt.Sprintf("The outcomes have length %d, but the predictions have length %d\n", len(y), len(pred))
+		return nil, nil, fmt.Errorf(msg)
+	}
+
+	var pos, neg []float64
+	for i, v := range y {
+		switch v {
+		case 1:
+			pos = append(pos, pred[i])
+		case 0:
+			neg = append(neg, pred[i])
+		default:
+			msg := fmt.Sprintf("The outcomes must be 0 or 1, observation %d has value %v\n", i, v)
+			return nil, nil, fmt.Errorf(msg)
+		}
+	}
+
+	if len(pos) == 0 || len(neg) == 0 {
+		return nil, nil, fmt.Errorf("The outcomes must include both 0 and 1")
+	}
+
+	// The midranks in the pooled sample give the comparisons between
+	// groups, after removing the comparisons within each group.
+	all := append(append([]float64(nil), pos...), neg...)
+	rz := midranks(all)
+	rx := midranks(pos)
+	ry := midranks(neg)
+
+	m, n := float64(len(pos)), float64(len(neg))
+	v10 := make([]float64, len(pos))
+	for i := range pos {
+		v10[i] = (rz[i] - rx[i]) / n
+	}
+	v01 := make([]float64, len(neg))
+	for j := range neg {
+		v01[j] = 1 - (rz[len(pos)+j]-ry[j])/m
+	}
+
+	return v10, v01, nil
+}
+
+// midranks returns the ranks of the values in x, starting at 1, with
+// tied values receiving the average of their ranks.
+func midranks(x []float64) []float64 {
+
+	ix := make([]int, len(x))
+	for i := range ix {
+		ix[i] = i
+	}
+	sort.Slice(ix, func(i, j int) bool { return x[ix[i]] < x[ix[j]] })
+
+	r := make([]float64, len(x))
+	for i := 0; i < len(ix); {
+		j := i
+		for j < len(ix) && x[ix[j]] == x[ix[i]] {
+			j++
+		}
+		// Positions i..j-1 are tied, with ranks i+1..j
+		mr := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			r[ix[k]] = mr
+		}
+		i = j
+	}
+
+	return r
+}
+
+// AUC returns the area under the ROC curve for the fitted means of a
+// binomial GLM with binary (0/1) outcomes, see the AUC function.  Case
+// weights are not supported.
+func (rslt *GLMResults) AUC(level float64) (*AUCResult, error) {
+
+	model := rslt.Model().(*GLM)
+	if model.fam.TypeCode != BinomialFamily {
+		msg := fmt.Sprintf("AUC requires a binomial model, not %s\n", model.fam.Name)
+		return nil, fmt.Errorf(msg)
+	}
+	if model.weightpos != -1 {
+		return nil, fmt.Errorf("AUC can not be used with case weights")
+	}
+
+	return AUC(model.data[model.ypos], rslt.Mean(), level)
+}
+
+// ImprovementResult contains a measure of the improvement in the
+// predictions of a binary outcome from an old model to a new model.
+type ImprovementResult struct {
+
+	// The improvement among the cases with y = 1 (events)
+	Event float64
+
+	// The improvement among the cases with y = 0 (non-events)
+	NonEvent float64
+
+	// The overall improvement, Event + NonEvent
+	Estimate float64
+
+	// The standard error of the overall improvement
+	StdErr float64
+
+	// The Z-score and two-sided p-value for testing the null hypothesis
+	// of no improvement
+	ZScore float64
+	PValue float64
+}
+
+// splitPaired splits the paired predictions from two models by the
+// binary outcome value, returning the differences (new minus old) for
+// the events and the non-events.
+func splitPaired(y []statmodel.Dtype, pold, pnew []float64) ([]float64, []float64, error) {
+
+	if len(pold) != len(y) || len(pnew) != len(y) {
+		msg := fmt.Sprintf("The outcomes have length %d, but the predictions have lengths %d and %d\n",
+			len(y), len(pold), len(pnew))
+		return nil, nil, fmt.Errorf(msg)
+	}
+
+	var de, dn []float64
+	for i, v := range y {
+		switch v {
+		case 1:
+			de = append(de, pnew[i]-pold[i])
+		case 0:
+			dn = append(dn, pnew[i]-pold[i])
+		default:
+			msg := fmt.Sprintf("The outcomes must be 0 or 1, observation %d has value %v\n", i, v)
+			return nil, nil, fmt.Errorf(msg)
+		}
+	}
+
+	if len(de) == 0 || len(dn) == 0 {
+		return nil, nil, fmt.Errorf("The outcomes must include both 0 and 1")
+	}
+
+	return de, dn, nil
+}
+
+// newImprovement returns an ImprovementResult with the given components
+// and the variances of the components.
+func newImprovement(ev, nev, vev, vnev float64) *ImprovementResult {
+
+	est := ev + nev
+	se := math.Sqrt(vev + vnev)
+	z := est / se
+
+	return &ImprovementResult{
+		Event:    ev,
+		NonEvent: nev,
+		Estimate: est,
+		StdErr:   se,
+		ZScore:   z,
+		PValue:   2 * distuv.UnitNormal.CDF(-math.Abs(z)),
+	}
+}
+
+// NRI returns the continuous (category-free) net reclassification
+// improvement of the predicted probabilities pnew over the predicted
+// probabilities pold, for the binary (0/1) outcomes y.  The predictions
+// are paired, i.e. pold[i] and pnew[i] are predictions for the same
+// case.  The event component is the proportion of events whose
+// predicted probability increases minus the proportion whose predicted
+// probability decreases, and the non-event component is the proportion
+// of non-events whose predicted probability decreases minus the
+// proportion whose predicted probability increases.  Cases whose
+// predictions are unchanged contribute to neither proportion.  The
+// standard error treats the events and non-events as independent
+// multinomial samples (Pencina et al., 2011).
+func NRI(y []statmodel.Dtype, pold, pnew []float64) (*ImprovementResult, error) {
+
+	de, dn, err := splitPaired(y, pold, pnew)
+	if err != nil {
+		return nil, err
+	}
+
+	// The proportions moving up and down, and the variance of their
+	// difference.
+	f := func(d []float64) (float64, float64, float64) {
+		var up, down float64
+		for _, v := range d {
+			if v > 0 {
+				up++
+			} else if v < 0 {
+				down++
+			}
+		}
+		n := float64(len(d))
+		up /= n
+		down /= n
+		return up, down, (up + down - (up-down)*(up-down)) / n
+	}
+
+	upe, downe, ve := f(de)
+	upn, downn, vn := f(dn)
+
+	return newImprovement(upe-downe, downn-upn, ve, vn), nil
+}
+
+// IDI returns the integrated discrimination improvement of the
+// predicted probabilities pnew over the predicted probabilities pold,
+// for the binary (0/1) outcomes y.  The predictions are paired, i.e.
+// pold[i] and pnew[i] are predictions for the same case.  The event
+// component is the mean increase in the predicted probabilities for the
+// events, and the non-event component is the mean decrease in the
+// predicted probabilities for the non-events, so that the IDI is the
+// change in the discrimination slope.  The standard error is based on
+// the paired differences of the predictions (Pencina et al., 2008).
+func IDI(y []statmodel.Dtype, pold, pnew []float64) (*ImprovementResult, error) {
+
+	de, dn, err := splitPaired(y, pold, pnew)
+	if err != nil {
+		return nil, err
+	}
+
+	// The variance of a mean
+	vm := func(d []float64) float64 {
+		if len(d) < 2 {
+			return math.NaN()
+		}
+		return stat.Variance(d, nil) / float64(len(d))
+	}
+
+	return newImprovement(stat.Mean(de, nil), -stat.Mean(dn, nil), vm(de), vm(dn)), nil
+}
+
+// BrierResult contains the Brier score and its decomposition.
+type BrierResult struct {
+
+	// The Brier score, the mean squared difference between the
+	// predicted probabilities and the binary outcomes
+	Score float64
+
+	// The reliability (calibration) component, smaller values are
+	// better
+	Reliability float64
+
+	// The resolution component, larger values are better
+	Resolution float64
+
+	// The uncertainty component, which depends only on the outcomes
+	Uncertainty float64
+}
+
+// Brier returns the Brier score of the predicted probabilities pred for
+// the binary (0/1) outcomes y, with the decomposition of Murphy (1973)
+// into reliability, resolution, and uncertainty components.  The
+// decomposition groups the predictions into nbins bins of equal width
+// on [0, 1], and the Brier score is equal to Reliability - Resolution +
+// Uncertainty when the predictions are constant within each bin.
+// Otherwise the decomposition is approximate, with the difference
+// arising from the variation of the predictions within the bins.
+func Brier(y []statmodel.Dtype, pred []float64, nbins int) (*BrierResult, error) {
+
+	if len(y) != len(pred) {
+		msg := fmt.Sprintf("The outcomes have length %d, but the predictions have length %d\n", len(y), len(pred))
+		return nil, fmt.Errorf(msg)
+	}
+	if len(y) == 0 {
+		return nil, fmt.Errorf("Brier: no observations")
+	}
+	if nbins <= 0 {
+		msg := fmt.Sprintf("Brier: the number of bins must be positive, got %d\n", nbins)
+		return nil, fmt.Errorf(msg)
+	}
+
+	cnt := make([]float64, nbins)
+	psum := make([]float64, nbins)
+	ysum := make([]float64, nbins)
+
+	var bs, ybar float64
+	for i, v := range y {
+		if v != 0 && v != 1 {
+			msg := fmt.Sprintf("The outcomes must be 0 or 1, observation %d has value %v\n", i, v)
+			return nil, fmt.Errorf(msg)
+		}
+		p := pred[i]
+		if !(p >= 0 && p <= 1) {
+			msg := fmt.Sprintf("The predicted probabilities must lie in [0, 1], observation %d has value %v\n", i, p)
+			return nil, fmt.Errorf(msg)
+		}
+		d := p - float64(v)
+		bs += d * d
+		ybar += float64(v)
+
+		k := int(p * float64(nbins))
+		if k == nbins {
+			k--
+		}
+		cnt[k]++
+		psum[k] += p
+		ysum[k] += float64(v)
+	}
+
+	n := float64(len(y))
+	bs /= n
+	ybar /= n
+
+	var rel, res float64
+	for k := range cnt {
+		if cnt[k] == 0 {
+			continue
+		}
+		pk := psum[k] / cnt[k]
+		ok := ysum[k] / cnt[k]
+		rel += cnt[k] * (pk - ok) * (pk - ok)
+		res += cnt[k] * (ok - ybar) * (ok - ybar)
+	}
+
+	return &BrierResult{
+		Score:       bs,
+		Reliability: rel / n,
+		Resolution:  res / n,
+		Uncertainty: ybar * (1 - ybar),
+	}, nil
+}
+
+// Brier returns the Brier score and its decomposition for the fitted
+// means of a binomial GLM with binary (0/1) outcomes, see the Brier
+// function.  Case weights are not supported.
+func (rslt *GLMResults) Brier(nbins int) (*BrierResult, error) {
+
+	model := rslt.Model().(*GLM)
+	if model.fam.TypeCode != BinomialFamily {
+		msg := fmt.Sprintf("Brier requires a binomial model, not %s\n", model.fam.Name)
+		return nil, fmt.Errorf(msg)
+	}
+	if model.weightpos != -1 {
+		return nil, fmt.Errorf("Brier can not be used with case weights")
+	}
+
+	return Brier(model.data[model.ypos], rslt.Mean(), nbins)
+}
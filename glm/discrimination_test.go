@@ -0,0 +1,186 @@
+package glm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kshedden/statmodel/statmodel"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// aucDirect calculates the AUC and its DeLong standard error by
+// comparing all pairs of cases.
+func aucDirect(y []statmodel.Dtype, pred []float64) (float64, float64) {
+
+	psi := func(x, y float64) float64 {
+		if x > y {
+			return 1
+		} else if x == y {
+			return 0.5
+		}
+		return 0
+	}
+
+	var pos, neg []float64
+	for i := range y {
+		if y[i] == 1 {
+			pos = append(pos, pred[i])
+		} else {
+			neg = append(neg, pred[i])
+		}
+	}
+	m, n := float64(len(pos)), float64(len(neg))
+
+	var auc float64
+	v10 := make([]float64, len(pos))
+	v01 := make([]float64, len(neg))
+	for i, x := range pos {
+		for j, z := range neg {
+			p := psi(x, z)
+			auc += p
+			v10[i] += p / n
+			v01[j] += p / m
+		}
+	}
+	auc /= m * n
+
+	var s10, s01 float64
+	for _, v := range v10 {
+		s10 += (v - auc) * (v - auc)
+	}
+	for _, v := range v01 {
+		s01 += (v - auc) * (v - auc)
+	}
+	s10 /= m - 1
+	s01 /= n - 1
+
+	return auc, math.Sqrt(s10/m + s01/n)
+}
+
+func TestAUC(t *testing.T) {
+
+	y := []statmodel.Dtype{0, 1, 1, 0, 1, 0, 0, 1, 1, 0}
+	pred := []float64{0.1, 0.8, 0.4, 0.4, 0.7, 0.2, 0.5, 0.4, 0.9, 0.3}
+
+	r, err := AUC(y, pred, 0.95)
+	if err != nil {
+		panic(err)
+	}
+	auc, se := aucDirect(y, pred)
+	if !scalarClose(r.AUC, auc, 1e-12) || !scalarClose(r.StdErr, se, 1e-12) {
+		t.Logf("%v %v %v %v\n", r.AUC, auc, r.StdErr, se)
+		t.Fail()
+	}
+	q := distuv.UnitNormal.Quantile(0.975)
+	if !scalarClose(r.LCB, auc-q*se, 1e-12) || !scalarClose(r.UCB, math.Min(1, auc+q*se), 1e-12) {
+		t.Fail()
+	}
+
+	// Invalid outcomes
+	for _, yb := range [][]statmodel.Dtype{{0, 0, 0}, {0, 1, 2}} {
+		if _, err := AUC(yb, []float64{1, 2, 3}, 0.95); err == nil {
+			t.Fail()
+		}
+	}
+
+	// The AUC of a fitted logistic regression
+	x1 := []statmodel.Dtype{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+	x2 := make([]statmodel.Dtype, len(pred))
+	copy(x2, pred)
+	data := statmodel.NewDataset([][]statmodel.Dtype{y, x1, x2}, []string{"y", "x1", "x2"})
+	model, err := NewGLM(data, "y", []string{"x1", "x2"}, DefaultConfig().WithFamily(NewFamily(BinomialFamily)))
+	if err != nil {
+		panic(err)
+	}
+	r2, err := model.Fit().AUC(0.95)
+	if err != nil {
+		panic(err)
+	}
+
+	// The fitted means are monotone in x2, so the AUC is unchanged
+	if !scalarClose(r2.AUC, r.AUC, 1e-12) || !scalarClose(r2.StdErr, r.StdErr, 1e-12) {
+		t.Fail()
+	}
+}
+
+func TestNRIIDI(t *testing.T) {
+
+	y := []statmodel.Dtype{0, 1, 1, 0, 1, 0, 0, 1}
+	pold := []float64{0.2, 0.5, 0.4, 0.3, 0.6, 0.5, 0.3, 0.4}
+	pnew := []float64{0.1, 0.7, 0.3, 0.3, 0.8, 0.4, 0.4, 0.6}
+
+	// Events: 3 up and 1 down.  Non-events: 2 down, 1 up, 1 unchanged.
+	nri, err := NRI(y, pold, pnew)
+	if err != nil {
+		panic(err)
+	}
+	ve := (1.0 - 0.25) / 4
+	vn := (0.75 - 0.0625) / 4
+	if !scalarClose(nri.Event, 0.5, 1e-12) || !scalarClose(nri.NonEvent, 0.25, 1e-12) ||
+		!scalarClose(nri.Estimate, 0.75, 1e-12) || !scalarClose(nri.StdErr, math.Sqrt(ve+vn), 1e-12) {
+		t.Logf("%+v\n", nri)
+		t.Fail()
+	}
+
+	// Events: differences 0.2, -0.1, 0.2, 0.2.  Non-events: -0.1, 0, -0.1, 0.1.
+	idi, err := IDI(y, pold, pnew)
+	if err != nil {
+		panic(err)
+	}
+	de := []float64{0.2, -0.1, 0.2, 0.2}
+	dn := []float64{-0.1, 0, -0.1, 0.1}
+	se := math.Sqrt(stat.Variance(de, nil)/4 + stat.Variance(dn, nil)/4)
+	if !scalarClose(idi.Event, 0.125, 1e-10) || !scalarClose(idi.NonEvent, 0.025, 1e-10) ||
+		!scalarClose(idi.StdErr, se, 1e-10) ||
+		!scalarClose(idi.PValue, 2*distuv.UnitNormal.CDF(-0.15/se), 1e-10) {
+		t.Logf("%+v\n", idi)
+		t.Fail()
+	}
+
+	// The predictions must be paired with the outcomes
+	if _, err := IDI(y, pold, pnew[1:]); err == nil {
+		t.Fail()
+	}
+}
+
+func TestBrier(t *testing.T) {
+
+	// The predictions are constant within the bins of width 0.1, so the
+	// decomposition is exact.
+	y := []statmodel.Dtype{0, 1, 1, 0, 1, 0, 0, 1, 1, 0}
+	pred := []float64{0.15, 0.85, 0.45, 0.45, 0.85, 0.15, 0.45, 0.45, 0.85, 0.15}
+
+	r, err := Brier(y, pred, 10)
+	if err != nil {
+		panic(err)
+	}
+
+	var bs float64
+	for i := range y {
+		d := pred[i] - float64(y[i])
+		bs += d * d
+	}
+	bs /= float64(len(y))
+
+	if !scalarClose(r.Score, bs, 1e-12) || !scalarClose(r.Uncertainty, 0.25, 1e-12) {
+		t.Fail()
+	}
+	if !scalarClose(r.Score, r.Reliability-r.Resolution+r.Uncertainty, 1e-12) {
+		t.Logf("%+v\n", r)
+		t.Fail()
+	}
+
+	// In the bins at 0.15, 0.45, and 0.85 the observed proportions are
+	// 0, 1/2, and 1.
+	rel := (3*0.15*0.15 + 4*0.05*0.05 + 3*0.15*0.15) / 10
+	res := (3*0.25 + 0 + 3*0.25) / 10
+	if !scalarClose(r.Reliability, rel, 1e-12) || !scalarClose(r.Resolution, res, 1e-12) {
+		t.Fail()
+	}
+
+	// Predictions must be probabilities
+	if _, err := Brier(y[0:2], []float64{0.5, 1.5}, 10); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,147 @@
+package glm
+
+import (
+	"fmt"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// The functions below support distributed (federated) fitting of a GLM,
+// in which the data are held in several shards that can not be pooled,
+// e.g. at different sites.  A coordinator broadcasts the current
+// parameters to the shards, each shard returns its ShardStats evaluated
+// at these parameters, and the coordinator combines the statistics with
+// CombineShardStats and obtains the updated parameters with
+// NewtonUpdate.  Only the summary statistics leave the shards.  Each
+// shard must use the same covariates, in the same order, and the same
+// family, link, and variance function.  The updates are the IRLS
+// updates, so iterating to convergence gives the same estimates as
+// fitting the model to the pooled data.
+
+// ShardStats contains the partial statistics for a shard of data,
+// evaluated at given parameters.  The statistics are sums over the
+// observations in the shard, so the statistics for several shards are
+// combined by adding them.
+type ShardStats struct {
+
+	// The number of parameters
+	NumParams int
+
+	// The weighted moment matrix X'WX, stored by row
+	XtWX []float64
+
+	// The weighted moment vector X'Wz
+	XtWz []float64
+
+	// The log-likelihood, with the scale parameter equal to 1
+	LogLike float64
+
+	// The (unscaled) deviance
+	Deviance float64
+
+	// The Pearson chi-square statistic
+	PearsonChi2 float64
+
+	// The sum of the case weights
+	SumWeights float64
+}
+
+// ShardStats returns the partial statistics for the model's data at the
+// given parameters.  Any penalties in the model are not included.
+func (model *GLM) ShardStats(params []float64) *ShardStats {
+
+	xtx, xty := model.IRLSMoments(params)
+	chi2, _ := model.pearsonChi2(params)
+
+	return &ShardStats{
+		NumParams:   model.NumParams(),
+		XtWX:        xtx,
+		XtWz:        xty,
+		LogLike:     model.unpenalized().LogLike(&GLMParams{params, 1}, true),
+		Deviance:    model.deviance(params),
+		PearsonChi2: chi2,
+		SumWeights:  model.sumWeights(),
+	}
+}
+
+// CombineShardStats returns the sum of the partial statistics for
+// several shards, which must all be evaluated at the same parameters.
+func CombineShardStats(stats []*ShardStats) (*ShardStats, error) {
+
+	if len(stats) == 0 {
+		return nil, fmt.Errorf("CombineShardStats: no statistics to combine")
+	}
+
+	p := stats[0].NumParams
+	total := &ShardStats{
+		NumParams: p,
+		XtWX:      make([]float64, p*p),
+		XtWz:      make([]float64, p),
+	}
+
+	for k, st := range stats {
+		if st.NumParams != p || len(st.XtWX) != p*p || len(st.XtWz) != p {
+			msg := fmt.Sprintf("CombineShardStats: shard %d has %d parameters, expected %d\n", k, st.NumParams, p)
+			return nil, fmt.Errorf(msg)
+		}
+		for j, v := range st.XtWX {
+			total.XtWX[j] += v
+		}
+		for j, v := range st.XtWz {
+			total.XtWz[j] += v
+		}
+		total.LogLike += st.LogLike
+		total.Deviance += st.Deviance
+		total.PearsonChi2 += st.PearsonChi2
+		total.SumWeights += st.SumWeights
+	}
+
+	return total, nil
+}
+
+// NewtonUpdate returns the updated parameters, which solve
+// (X'WX) b = X'Wz.
+func (st *ShardStats) NewtonUpdate() ([]float64, error) {
+
+	p := st.NumParams
+	if p == 0 {
+		return []float64{}, nil
+	}
+
+	var b mat.VecDense
+	if err := b.SolveVec(mat.NewDense(p, p, st.XtWX), mat.NewVecDense(p, st.XtWz)); err != nil {
+		return nil, err
+	}
+
+	params := make([]float64, p)
+	copy(params, b.RawVector().Data)
+
+	return params, nil
+}
+
+// Scale returns the Pearson estimate of the scale parameter, which is
+// appropriate for families with a free dispersion parameter.
+func (st *ShardStats) Scale() float64 {
+	return st.PearsonChi2 / (st.SumWeights - float64(st.NumParams))
+}
+
+// Vcov returns the estimated covariance matrix of the parameters, which
+// is the scale times the inverse of X'WX.  This is based on the expected
+// information, so it agrees with the covariance matrix of a pooled fit
+// when the link is canonical, or when the expected information is used.
+// For families with a fixed dispersion, the scale should be 1.
+func (st *ShardStats) Vcov(scale float64) ([]float64, error) {
+
+	p := st.NumParams
+	if p == 0 {
+		return []float64{}, nil
+	}
+
+	var vc mat.Dense
+	if err := vc.Inverse(mat.NewDense(p, p, st.XtWX)); err != nil {
+		return nil, err
+	}
+	vc.Scale(scale, &vc)
+
+	return vc.RawMatrix().Data, nil
+}
@@ -0,0 +1,78 @@
+package glm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kshedden/statmodel/statmodel"
+	"gonum.org/v1/gonum/floats"
+)
+
+func TestShardStats(t *testing.T) {
+
+	xnames := []string{"x1", "x2"}
+	config := DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithOffset("off").WithWeight("w")
+
+	pmodel, err := NewGLM(data5(), "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	rslt := pmodel.Fit()
+
+	var models []*GLM
+	for _, ix := range [][]int{{0, 1}, {2, 3, 4}, {5, 6}} {
+		model, err := NewGLM(statmodel.Subset(data5(), ix), "y", xnames, config)
+		if err != nil {
+			panic(err)
+		}
+		models = append(models, model)
+	}
+
+	params := []float64{0, 0}
+	var total *ShardStats
+	for iter := 0; iter < 50; iter++ {
+		var stats []*ShardStats
+		for _, model := range models {
+			stats = append(stats, model.ShardStats(params))
+		}
+		total, err = CombineShardStats(stats)
+		if err != nil {
+			panic(err)
+		}
+		if iter == 49 {
+			break
+		}
+		params, err = total.NewtonUpdate()
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if !floats.EqualApprox(params, rslt.Params(), 1e-8) {
+		t.Logf("%v %v\n", params, rslt.Params())
+		t.Fail()
+	}
+	if !scalarClose(total.LogLike, rslt.LogLike(), 1e-8) ||
+		!scalarClose(total.Deviance, rslt.Deviance(), 1e-8) {
+		t.Fail()
+	}
+
+	// The log link is canonical for the Poisson family, so the observed
+	// and expected information agree.
+	vcov, err := total.Vcov(1)
+	if err != nil {
+		panic(err)
+	}
+	for j := range params {
+		if !scalarClose(math.Sqrt(vcov[j*2+j]), rslt.StdErr()[j], 1e-6) {
+			t.Fail()
+		}
+	}
+
+	// The shards must have the same number of parameters
+	st := models[0].ShardStats(params)
+	st3 := &ShardStats{NumParams: 3, XtWX: make([]float64, 9), XtWz: make([]float64, 3)}
+	if _, err := CombineShardStats([]*ShardStats{st, st3}); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,135 @@
+/*
+This example illustrates distributed (federated) fitting of a logistic
+regression model, in which the data are held at several sites that can
+not share individual-level data.
+
+Each site computes partial statistics (the weighted moment matrices, the
+log-likelihood, and other sums) at the current parameter values, and
+only these statistics are sent to a coordinator.  The coordinator sums
+the statistics, obtains updated parameters, and broadcasts them back to
+the sites.  The iterations are IRLS iterations, so at convergence the
+estimates and standard errors agree with a fit to the pooled data, which
+is also shown here for comparison.
+
+The data in this example are simulated.
+*/
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/kshedden/statmodel/glm"
+	"github.com/kshedden/statmodel/statmodel"
+)
+
+var xnames = []string{"icept", "age", "sex"}
+
+// simulate generates data for one site.
+func simulate(rng *rand.Rand, n int, agemean float64) statmodel.Dataset {
+
+	var y, icept, age, sex []statmodel.Dtype
+	for i := 0; i < n; i++ {
+		a := agemean + 10*rng.NormFloat64()
+		s := float64(rng.Intn(2))
+		lp := -3 + 0.05*a - 0.5*s
+		pr := 1 / (1 + math.Exp(-lp))
+		var yi float64
+		if rng.Float64() < pr {
+			yi = 1
+		}
+		y = append(y, statmodel.Dtype(yi))
+		icept = append(icept, 1)
+		age = append(age, statmodel.Dtype(a))
+		sex = append(sex, statmodel.Dtype(s))
+	}
+
+	return statmodel.NewDataset([][]statmodel.Dtype{y, icept, age, sex}, []string{"y", "icept", "age", "sex"})
+}
+
+func main() {
+
+	rng := rand.New(rand.NewSource(1))
+
+	// The data held at each site
+	var sites []statmodel.Dataset
+	for k, n := range []int{300, 500, 200} {
+		sites = append(sites, simulate(rng, n, 40+5*float64(k)))
+	}
+
+	// Each site constructs its own model
+	var models []*glm.GLM
+	for _, ds := range sites {
+		config := glm.DefaultConfig().WithFamily(glm.NewFamily(glm.BinomialFamily))
+		model, err := glm.NewGLM(ds, "y", xnames, config)
+		if err != nil {
+			panic(err)
+		}
+		models = append(models, model)
+	}
+
+	// The coordinator iterates until the deviance stabilizes
+	params := make([]float64, len(xnames))
+	var total *glm.ShardStats
+	olddev := math.Inf(1)
+	for iter := 0; iter < 50; iter++ {
+
+		// In practice the sites would compute these statistics locally
+		var stats []*glm.ShardStats
+		for _, model := range models {
+			stats = append(stats, model.ShardStats(params))
+		}
+
+		var err error
+		total, err = glm.CombineShardStats(stats)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Iteration %d: deviance=%.8f\n", iter, total.Deviance)
+
+		if math.Abs(total.Deviance-olddev) < 1e-10*(math.Abs(total.Deviance)+0.1) {
+			break
+		}
+		olddev = total.Deviance
+
+		params, err = total.NewtonUpdate()
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// The binomial family has a fixed dispersion
+	vcov, err := total.Vcov(1)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("\nFederated estimates:\n")
+	p := len(xnames)
+	for j, na := range xnames {
+		fmt.Printf("%-8s %10.4f %10.4f\n", na, params[j], math.Sqrt(vcov[j*p+j]))
+	}
+
+	// For comparison, fit the model to the pooled data
+	var pooled [][]statmodel.Dtype
+	for j := range sites[0].Data() {
+		var col []statmodel.Dtype
+		for _, ds := range sites {
+			col = append(col, ds.Data()[j]...)
+		}
+		pooled = append(pooled, col)
+	}
+	config := glm.DefaultConfig().WithFamily(glm.NewFamily(glm.BinomialFamily))
+	model, err := glm.NewGLM(statmodel.NewDataset(pooled, sites[0].Names()), "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	rslt := model.Fit()
+
+	fmt.Printf("\nPooled estimates:\n")
+	for j, na := range xnames {
+		fmt.Printf("%-8s %10.4f %10.4f\n", na, rslt.Params()[j], rslt.StdErr()[j])
+	}
+}
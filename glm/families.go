@@ -20,6 +20,8 @@ const (
 	InvGaussianFamily
 	NegBinomFamily
 	TweedieFamily
+	CustomFamily
+	BetaFamily
 )
 
 // LogLikeFunc evaluates and returns the log-likelihood for a GLM.  The arguments
@@ -66,10 +68,13 @@ type Family struct {
 	// Auxiliary parameter: negative binomial parameter or Tweedie variance
 	// power parameter
 	alpha float64
+
+	// The default variance function, only specified for custom families
+	vari *Variance
 }
 
 // NewFamily returns a family object corresponding to the given name.
-// Supported names are binomial, gamma, gaussian, invgaussian,
+// Supported names are beta, binomial, gamma, gaussian, invgaussian,
 // poisson, quasipoisson.
 func NewFamily(fam FamilyType) *Family {
 
@@ -86,6 +91,8 @@ func NewFamily(fam FamilyType) *Family {
 		return &gamma
 	case InvGaussianFamily:
 		return &invGaussian
+	case BetaFamily:
+		return &beta
 	default:
 		msg := fmt.Sprintf("Unknown family: %v\n", fam)
 		panic(msg)
@@ -118,7 +125,7 @@ var binomial = Family{
 	TypeCode:                BinomialFamily,
 	LogLike:                 binomialLogLike,
 	Deviance:                binomialDeviance,
-	validLinks:              []LinkType{LogitLink, LogLink, IdentityLink},
+	validLinks:              []LinkType{LogitLink, LogLink, IdentityLink, CloglogLink},
 	dispersionDefaultMethod: DispersionFixed,
 	dispersionDefaultValue:  1,
 }
@@ -138,7 +145,7 @@ var gamma = Family{
 	TypeCode:                GammaFamily,
 	LogLike:                 gammaLogLike,
 	Deviance:                gammaDeviance,
-	validLinks:              []LinkType{RecipLink, LogLink, IdentityLink},
+	validLinks:              []LinkType{RecipLink, LogLink, IdentityLink, PowerLink},
 	dispersionDefaultMethod: DispersionFree,
 }
 
@@ -147,7 +154,22 @@ var invGaussian = Family{
 	TypeCode:                InvGaussianFamily,
 	LogLike:                 invGaussLogLike,
 	Deviance:                invGaussianDeviance,
-	validLinks:              []LinkType{RecipSquaredLink, RecipLink, LogLink, IdentityLink},
+	validLinks:              []LinkType{RecipSquaredLink, RecipLink, LogLink, IdentityLink, PowerLink},
+	dispersionDefaultMethod: DispersionFree,
+}
+
+// The beta family is for continuous responses that lie strictly between
+// 0 and 1.  The beta distribution with mean m and precision phi has
+// variance m(1-m)/(1+phi), so the GLM scale parameter is 1/(1+phi).  The
+// coefficients and the precision are estimated jointly by maximum
+// likelihood.  The deviance is the binomial deviance, which does not
+// depend on the precision.
+var beta = Family{
+	Name:                    "Beta",
+	TypeCode:                BetaFamily,
+	LogLike:                 betaLogLike,
+	Deviance:                binomialDeviance,
+	validLinks:              []LinkType{LogitLink, CloglogLink},
 	dispersionDefaultMethod: DispersionFree,
 }
 
@@ -164,6 +186,129 @@ func (fam *Family) IsValidLink(link *Link) bool {
 	return false
 }
 
+// DefaultLink returns the link that is used when a GLM is created for
+// the family without specifying a link.  This is the canonical link
+// for the exponential families: identity for Gaussian, log for Poisson,
+// logit for binomial, reciprocal for Gamma, and reciprocal squared for
+// inverse Gaussian.  For the negative binomial and Tweedie families it
+// is the link that was provided when the family was created, and for a
+// custom family it is the first of the family's links.  An error is
+// returned if the family does not define a default link.
+func (fam *Family) DefaultLink() (*Link, error) {
+
+	if fam.link != nil {
+		return fam.link, nil
+	}
+
+	if len(fam.validLinks) == 0 {
+		msg := fmt.Sprintf("The %s family does not have a default link, a link must be specified\n", fam.Name)
+		return nil, fmt.Errorf(msg)
+	}
+
+	return NewLink(fam.validLinks[0]), nil
+}
+
+// SaturatedLogLike returns the log-likelihood of the saturated model,
+// in which the mean of each observation is equal to its observed value,
+// including the same constant terms as the family's exact
+// log-likelihood.  The weights may be nil, in which case all weights
+// are taken to be 1.  For discrete families (Poisson, binomial,
+// negative binomial), the saturated log-likelihood does not depend on
+// the scale, and the deviance is 2*(SaturatedLogLike - LogLike).  The
+// binomial log-likelihood omits the log binomial coefficient, which
+// does not depend on the mean, so it is also omitted here and cancels
+// from the deviance.  The negative binomial deviance is computed in
+// this way.  For continuous families the saturated log-likelihood
+// depends on the scale parameter, and is finite only because the
+// density is evaluated at a fixed scale; in these cases the unscaled
+// deviance is 2*scale*(SaturatedLogLike - LogLike).  For the Tweedie
+// family this holds for the compound Poisson range 1 < p < 2.  For
+// custom families, the family's log-likelihood function is evaluated at
+// the observed values, which must be valid mean values.
+func (fam *Family) SaturatedLogLike(y, wt []statmodel.Dtype, scale float64) float64 {
+
+	var ll float64
+	var w float64 = 1
+
+	switch fam.TypeCode {
+	case PoissonFamily, QuasiPoissonFamily:
+		for i := range y {
+			if wt != nil {
+				w = float64(wt[i])
+			}
+			yi := float64(y[i])
+			if yi > 0 {
+				ll += w * (yi*math.Log(yi) - yi - lgamma(yi+1))
+			}
+		}
+	case BinomialFamily:
+		for i := range y {
+			if wt != nil {
+				w = float64(wt[i])
+			}
+			yi := float64(y[i])
+			if yi > 0 {
+				ll += w * yi * math.Log(yi)
+			}
+			if yi < 1 {
+				ll += w * (1 - yi) * math.Log(1-yi)
+			}
+		}
+	case NegBinomFamily:
+		alpha := fam.alpha
+		for i := range y {
+			if wt != nil {
+				w = float64(wt[i])
+			}
+			yi := float64(y[i])
+			if yi > 0 {
+				c := lgamma(yi+1/alpha) - lgamma(yi+1) - lgamma(1/alpha)
+				v := yi*math.Log(alpha*yi/(1+alpha*yi)) - math.Log(1+alpha*yi)/alpha
+				ll += w * (v + c)
+			}
+		}
+	case TweedieFamily:
+		// Observations equal to zero contribute zero to the
+		// saturated log-likelihood.
+		var yp []statmodel.Dtype
+		var mp []float64
+		var wp []statmodel.Dtype
+		for i := range y {
+			if y[i] > 0 {
+				yp = append(yp, y[i])
+				mp = append(mp, float64(y[i]))
+				if wt != nil {
+					wp = append(wp, wt[i])
+				}
+			}
+		}
+		ll = fam.LogLike(yp, mp, wp, scale, true)
+	default:
+		mn := make([]float64, len(y))
+		for i := range y {
+			mn[i] = float64(y[i])
+		}
+		ll = fam.LogLike(y, mn, wt, scale, true)
+	}
+
+	return ll
+}
+
+// meanBounds returns the lower and upper limits for the mean of a
+// response from the family.  The mean must lie strictly between the
+// limits.
+func (fam *Family) meanBounds() (float64, float64) {
+
+	switch fam.TypeCode {
+	case BinomialFamily, BetaFamily:
+		return 0, 1
+	case GaussianFamily, CustomFamily:
+		return math.Inf(-1), math.Inf(1)
+	default:
+		return 0, math.Inf(1)
+	}
+}
+
 func poissonLogLike(y []statmodel.Dtype, mn []float64, wt []statmodel.Dtype, scale float64, exact bool) float64 {
 
 	var ll float64
@@ -195,8 +340,17 @@ func binomialLogLike(y []statmodel.Dtype, mn []float64, wt []statmodel.Dtype, sc
 		if wt != nil {
 			w = float64(wt[i])
 		}
-		r := mn[i]/(1-mn[i]) + 1e-200
-		ll += w * (float64(y[i])*math.Log(r) + math.Log(1-mn[i]))
+
+		// The terms are omitted when their coefficients are zero,
+		// using 0*log(0) = 0, so that means of exactly 0 or 1 give
+		// a finite log-likelihood when they agree with the response.
+		yi := float64(y[i])
+		if yi > 0 {
+			ll += w * yi * math.Log(mn[i])
+		}
+		if yi < 1 {
+			ll += w * (1 - yi) * math.Log1p(-mn[i])
+		}
 	}
 	return ll
 }
@@ -217,6 +371,12 @@ func gaussianLogLike(y []statmodel.Dtype, mn []float64, wt []statmodel.Dtype, sc
 	return ll
 }
 
+// gammaLogLike returns the log-likelihood for the gamma family, with
+// shape parameter 1/scale.  If exact is true, the result includes the
+// terms involving the scale parameter and the log-gamma function, so
+// that it is the log of the gamma density and agrees with other
+// software.  If exact is false, these terms are omitted, which is only
+// appropriate for comparisons with the scale parameter held fixed.
 func gammaLogLike(y []statmodel.Dtype, mn []float64, wt []statmodel.Dtype, scale float64, exact bool) float64 {
 
 	var ll float64
@@ -276,6 +436,36 @@ func invGaussLogLike(y []statmodel.Dtype, mn []float64, wt []statmodel.Dtype, sc
 	return ll
 }
 
+// betaLogLike returns the log-likelihood for the beta family, with
+// precision 1/scale - 1, which requires the scale parameter to be less
+// than 1.  When exact is false, the terms -log(y) - log(1-y), which
+// depend on neither the mean nor the precision, are omitted.
+func betaLogLike(y []statmodel.Dtype, mn []float64, wt []statmodel.Dtype, scale float64, exact bool) float64 {
+
+	if scale <= 0 || scale >= 1 {
+		return math.NaN()
+	}
+	phi := 1/scale - 1
+
+	var ll float64
+	var w float64 = 1
+	c := lgamma(phi)
+	for i := range y {
+		if wt != nil {
+			w = float64(wt[i])
+		}
+		yi := float64(y[i])
+		a := mn[i] * phi
+		b := (1 - mn[i]) * phi
+		ll += w * (c - lgamma(a) - lgamma(b) + a*math.Log(yi) + b*math.Log(1-yi))
+		if exact {
+			ll -= w * (math.Log(yi) + math.Log(1-yi))
+		}
+	}
+
+	return ll
+}
+
 func poissonDeviance(y []statmodel.Dtype, mn []float64, wgt []statmodel.Dtype, scale float64) float64 {
 
 	var dev float64
@@ -286,6 +476,7 @@ func poissonDeviance(y []statmodel.Dtype, mn []float64, wgt []statmodel.Dtype, s
 			w = float64(wgt[i])
 		}
 
+		dev -= 2 * w * (float64(y[i]) - mn[i])
 		if y[i] > 0 {
 			dev += 2 * w * float64(y[i]) * math.Log(float64(y[i])/mn[i])
 		}
@@ -305,7 +496,15 @@ func binomialDeviance(y []statmodel.Dtype, mn []float64, wgt []statmodel.Dtype,
 			w = float64(wgt[i])
 		}
 
-		dev -= 2 * w * (float64(y[i])*math.Log(mn[i]) + (1-float64(y[i]))*math.Log(1-mn[i]))
+		// The saturated log-likelihood is zero for binary
+		// responses, using 0*log(0) = 0.
+		yi := float64(y[i])
+		if yi > 0 {
+			dev += 2 * w * yi * math.Log(yi/mn[i])
+		}
+		if yi < 1 {
+			dev += 2 * w * (1 - yi) * math.Log((1-yi)/(1-mn[i]))
+		}
 	}
 
 	return dev
@@ -488,10 +687,6 @@ func NewNegBinomFamily(alpha float64, link *Link) *Family {
 
 		var ll float64
 		var w float64 = 1
-		var lp []float64
-
-		lp = resize(lp, len(y))
-		link.Link(mn, lp)
 		c3, _ := math.Lgamma(1 / alpha)
 
 		for i := range y {
@@ -500,14 +695,12 @@ func NewNegBinomFamily(alpha float64, link *Link) *Family {
 				w = float64(wt[i])
 			}
 
-			elp := math.Exp(lp[i])
-
 			c1, _ := math.Lgamma(float64(y[i]) + 1/alpha)
 			c2, _ := math.Lgamma(float64(y[i]) + 1)
 			c := c1 - c2 - c3
 
-			v := float64(y[i]) * math.Log(alpha*elp/(1+alpha*elp))
-			v -= math.Log(1+alpha*elp) / alpha
+			v := float64(y[i]) * math.Log(alpha*mn[i]/(1+alpha*mn[i]))
+			v -= math.Log(1+alpha*mn[i]) / alpha
 
 			ll += w * (v + c)
 		}
@@ -515,42 +708,95 @@ func NewNegBinomFamily(alpha float64, link *Link) *Family {
 		return ll
 	}
 
-	deviance := func(y []statmodel.Dtype, mn []float64, wt []statmodel.Dtype, scale float64) float64 {
+	fam := &Family{
+		Name:       "NegBinom",
+		TypeCode:   NegBinomFamily,
+		LogLike:    loglike,
+		alpha:      alpha,
+		validLinks: []LinkType{LogLink, IdentityLink},
+		link:       link,
+		dispersionDefaultMethod: DispersionFree,
+	}
 
-		var dev float64
-		var w float64 = 1
-		var lp []float64
+	// The deviance is twice the log-likelihood ratio relative to the
+	// saturated model.
+	fam.Deviance = func(y []statmodel.Dtype, mn []float64, wt []statmodel.Dtype, scale float64) float64 {
+		return 2 * (fam.SaturatedLogLike(y, wt, scale) - loglike(y, mn, wt, scale, true)) / scale
+	}
 
-		lp = resize(lp, len(y))
-		link.Link(mn, lp)
+	return fam
+}
 
-		for i := 0; i < len(y); i++ {
-			if wt != nil {
-				w = float64(wt[i])
-			}
+// NewNegBinomTheta returns a new family object for the negative
+// binomial family, using the "theta" parameterization of the
+// overdispersion parameter that is used by glm.nb in the R MASS
+// package.  The variance for mean m is m + m^2/theta, so theta is the
+// reciprocal of the alpha parameter used by NewNegBinomFamily, and
+// larger values of theta correspond to less overdispersion.
+func NewNegBinomTheta(theta float64, link *Link) *Family {
 
-			if y[i] == 1 {
-				z1 := float64(y[i]) * math.Log(float64(y[i])/mn[i])
-				z2 := (1 + alpha*float64(y[i])) / alpha
-				z2 *= math.Log((1 + alpha*float64(y[i])) / (1 + alpha*mn[i]))
-				dev += w * (z1 - z2)
-			} else {
-				dev += 2 * w * math.Log(1+alpha*mn[i]) / alpha
-			}
-		}
-		dev /= scale
+	if theta <= 0 {
+		msg := fmt.Sprintf("Invalid negative binomial theta parameter: %f\n", theta)
+		panic(msg)
+	}
 
-		return dev
+	return NewNegBinomFamily(1/theta, link)
+}
+
+// NewCustomFamily returns a user-defined family that can be used to fit
+// a GLM with distributions that are not provided by this package.  The
+// variance is specified through a scalar function of the mean, and is
+// used by default, unless a different variance function is provided in
+// the GLM configuration.  The derivative of the variance function, which
+// is needed for the observed information, is obtained by numerically
+// differentiating variance.  The first element of links is the default
+// link for the family; links must contain at least one element, and the
+// remaining elements are the other links that are valid for the family.
+// The dispersion argument determines how the scale parameter is handled
+// by default; if it is DispersionFixed, the scale parameter is fixed at
+// 1.  The range of the mean is not constrained for custom families.
+func NewCustomFamily(name string, variance func(float64) float64, deviance DevianceFunc, loglike LogLikeFunc,
+	links []LinkType, dispersion DispersionForm) *Family {
+
+	if variance == nil || loglike == nil || deviance == nil {
+		panic("NewCustomFamily: the variance, log-likelihood, and deviance functions must not be nil")
+	}
+	if len(links) == 0 {
+		panic("NewCustomFamily: at least one link must be provided")
+	}
+	if dispersion != DispersionFixed && dispersion != DispersionFree {
+		panic("NewCustomFamily: dispersion must be DispersionFixed or DispersionFree")
+	}
+
+	// Central difference approximation to the derivative of the
+	// variance function, as for custom links.
+	vderiv := func(x float64) float64 {
+		h := 1e-5 * math.Max(1, math.Abs(x))
+		return (variance(x+h) - variance(x-h)) / (2 * h)
+	}
+
+	vari := &Variance{
+		Name: name,
+		Var: func(mn, va []float64) {
+			for i := range mn {
+				va[i] = variance(mn[i])
+			}
+		},
+		Deriv: func(mn, vd []float64) {
+			for i := range mn {
+				vd[i] = vderiv(mn[i])
+			}
+		},
 	}
 
 	return &Family{
-		Name:       "NegBinom",
-		TypeCode:   NegBinomFamily,
-		LogLike:    loglike,
-		Deviance:   deviance,
-		alpha:      alpha,
-		validLinks: []LinkType{LogLink, IdentityLink},
-		link:       link,
-		dispersionDefaultMethod: DispersionFree,
+		Name:                    name,
+		TypeCode:                CustomFamily,
+		LogLike:                 loglike,
+		Deviance:                deviance,
+		validLinks:              links,
+		vari:                    vari,
+		dispersionDefaultMethod: dispersion,
+		dispersionDefaultValue:  1,
 	}
 }
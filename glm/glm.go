@@ -5,11 +5,15 @@ import (
 	"log"
 	"math"
 	"os"
+	"runtime"
+	"sort"
 	"strings"
 	"sync"
 
 	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
 	"gonum.org/v1/gonum/optimize"
+	"gonum.org/v1/gonum/stat/distuv"
 
 	"github.com/kshedden/statmodel/statmodel"
 )
@@ -33,9 +37,30 @@ type GLM struct {
 	// Position of the offset variable, -1 if not present.
 	offsetpos int
 
+	// Position of the observation ID variable, -1 if not present.
+	idpos int
+
 	// Position of the weight variable, -1 if not present.
 	weightpos int
 
+	// Position of the case weights, -1 if not present.  This differs
+	// from weightpos only if there are per-observation dispersions, in
+	// which case the weights in weightpos are the case weights divided
+	// by the dispersions.
+	casewpos int
+
+	// Position of the per-observation dispersions, -1 if not present.
+	disppos int
+
+	// The number of columns in the dataset used to construct the model.
+	// The model's data may have additional columns, which hold the
+	// values of WeightVec, OffsetVec, and DispersionVec.
+	nuser int
+
+	// If true, the responses are binomial proportions and the case
+	// weights are the numbers of trials.
+	binomTrials bool
+
 	// The GLM family
 	fam *Family
 
@@ -62,6 +87,10 @@ type GLM struct {
 	l2wgtMap map[string]float64
 	l2wgt    []float64
 
+	// If true, the L2 penalty weights are a ridge penalty that is
+	// fit using penalized IRLS.
+	ridge bool
+
 	// Optimization settings
 	settings *optimize.Settings
 
@@ -81,23 +110,88 @@ type GLM struct {
 	// If the dispersion is fixed, it is held at this value.
 	dispersionValue float64
 
-	// A pool of n-dimensional slices
-	nslices [][]float64
+	// The statistic used to estimate the scale parameter
+	scaleEstimator ScaleEstimator
+
+	// If true, the observed rather than the expected information is
+	// used to obtain the standard errors.
+	obsInfo bool
+
+	// If true, the covariance matrix of the parameters is obtained
+	// from the Hessian in packed form.
+	packedHess bool
+
+	// If true, the Hessian is obtained by numerically differentiating
+	// the score.
+	numHess bool
+
+	// The strictness of the family/link compatibility check
+	linkCheck LinkCheck
+
+	// If true, the means are constrained to the range of the family
+	// during IRLS fitting
+	constrainMean bool
+
+	// If true, the IRLS updates use the observed information
+	newton bool
+
+	// The IRLS convergence criteria and tolerance
+	convergence ConvergenceCriterion
+	convtol     float64
+
+	// How an IRLS fit that does not converge is handled
+	nonConvergence NonConvergence
+
+	// If positive, the linear predictor is clamped to [-lpBound, lpBound]
+	lpBound float64
+
+	// Warnings about the model specification, these are included
+	// in the summary table.
+	warnings []string
+
+	// A pool of n-dimensional slices, if nil the slices are not pooled.
+	// Copies of the model that use different data must not share the
+	// pool.
+	nslices *nslicePool
+}
+
+// nslicePool is a pool of n-dimensional slices for the working storage
+// of a GLM.  It is safe for concurrent use, so that the methods of a
+// fitted model can be called from multiple goroutines.
+type nslicePool struct {
+	mu sync.Mutex
+	x  [][]float64
 }
 
 func (model *GLM) putNslice(x []float64) {
-	model.nslices = append(model.nslices, x)
+
+	pool := model.nslices
+	if pool == nil {
+		return
+	}
+
+	pool.mu.Lock()
+	pool.x = append(pool.x, x)
+	pool.mu.Unlock()
 }
 
 func (model *GLM) getNslice() []float64 {
 
-	if len(model.nslices) == 0 {
+	pool := model.nslices
+	if pool == nil {
+		return make([]float64, model.NumObs())
+	}
+
+	pool.mu.Lock()
+	q := len(pool.x) - 1
+	if q < 0 {
+		pool.mu.Unlock()
 		return make([]float64, model.NumObs())
 	}
-	q := len(model.nslices) - 1
-	x := model.nslices[q]
+	x := pool.x[q]
+	pool.x = pool.x[0:q]
+	pool.mu.Unlock()
 	zero(x)
-	model.nslices = model.nslices[0:q]
 
 	return x
 }
@@ -113,6 +207,132 @@ const (
 	DispersionEstimate
 )
 
+// LinkCheck determines how questionable family/link combinations are
+// handled.
+type LinkCheck uint8
+
+// LinkCheckWarn (the default), LinkCheckStrict, and LinkCheckOff define
+// how the compatibility of the link function with the family is checked.
+// A combination is questionable if the link is not one of the family's
+// valid links, or if the link can produce mean values outside the range
+// of the family (e.g. the identity link with the Gamma family).  With
+// LinkCheckWarn, questionable combinations produce a warning in the
+// summary table.  With LinkCheckStrict, NewGLM returns an error for
+// questionable combinations.  LinkCheckOff disables the check.  To
+// constrain the means to the range of the family during IRLS fitting,
+// use ConstrainMean.
+const (
+	LinkCheckWarn LinkCheck = iota
+	LinkCheckStrict
+	LinkCheckOff
+)
+
+// NonConvergence determines how an IRLS fit that does not converge is
+// handled.
+type NonConvergence uint8
+
+// NonConvergenceReturn (the default), NonConvergenceError, and
+// NonConvergencePanic define what happens when the IRLS iterations do
+// not converge within the maximum number of iterations.  With
+// NonConvergenceReturn, the results at the final iteration are returned,
+// FitInfo reports that the fit did not converge, and the summary table
+// includes a warning.  With NonConvergenceError, FitChecked returns an
+// error, so Fit panics.  With NonConvergencePanic, both Fit and
+// FitChecked panic.
+const (
+	NonConvergenceReturn NonConvergence = iota
+	NonConvergenceError
+	NonConvergencePanic
+)
+
+// ScaleEstimator indicates how the scale parameter is estimated.
+type ScaleEstimator uint8
+
+// ScalePearson (the default) estimates the scale parameter as the
+// Pearson chi-square statistic divided by its degrees of freedom, and
+// ScaleDeviance estimates the scale parameter as the deviance divided
+// by its degrees of freedom.
+const (
+	ScalePearson ScaleEstimator = iota
+	ScaleDeviance
+)
+
+// String returns the name of the scale estimator.
+func (se ScaleEstimator) String() string {
+	switch se {
+	case ScalePearson:
+		return "Pearson"
+	case ScaleDeviance:
+		return "Deviance"
+	default:
+		return fmt.Sprintf("ScaleEstimator(%d)", int(se))
+	}
+}
+
+// ConvergenceCriterion determines when IRLS fitting stops.  Criteria
+// can be combined using bitwise or, in which case the iterations stop
+// when any of the criteria is satisfied.
+type ConvergenceCriterion uint8
+
+// ConvergeDeviance (the default) stops when the absolute change in the
+// deviance is less than the tolerance.  ConvergeParams stops when the
+// largest absolute change in a parameter is less than the tolerance.
+// ConvergeGradient stops when the largest absolute value of the score
+// vector, divided by the sum of the case weights, is less than the
+// tolerance.  ConvergeRelDeviance stops when the relative change in
+// the deviance, |D - D_old| / (|D| + 0.1), is less than the tolerance,
+// which does not depend on the scale of the deviance.
+const (
+	ConvergeDeviance ConvergenceCriterion = 1 << iota
+	ConvergeParams
+	ConvergeGradient
+	ConvergeRelDeviance
+)
+
+// DefaultConvergenceTol is the default tolerance for the IRLS convergence
+// criteria.
+const DefaultConvergenceTol = 1e-8
+
+// String returns the names of the convergence criteria.
+func (cc ConvergenceCriterion) String() string {
+
+	var names []string
+	for _, c := range []struct {
+		cc   ConvergenceCriterion
+		name string
+	}{
+		{ConvergeDeviance, "Deviance"},
+		{ConvergeParams, "Params"},
+		{ConvergeGradient, "Gradient"},
+		{ConvergeRelDeviance, "RelDeviance"},
+	} {
+		if cc&c.cc != 0 {
+			names = append(names, c.name)
+		}
+	}
+
+	if len(names) == 0 || cc >= ConvergeRelDeviance<<1 {
+		return fmt.Sprintf("ConvergenceCriterion(%d)", int(cc))
+	}
+
+	return strings.Join(names, "|")
+}
+
+// FitInfo describes the convergence of the IRLS fitting algorithm.
+type FitInfo struct {
+
+	// The number of IRLS iterations
+	Iterations int
+
+	// True if the convergence criterion was satisfied before the
+	// maximum number of iterations was reached
+	Converged bool
+
+	// The criterion that triggered convergence, zero if the fit did
+	// not converge
+	Criterion ConvergenceCriterion
+}
+
 // GLMParams represents the model parameters for a GLM.
 type GLMParams struct {
 	coeff []float64
@@ -157,6 +377,12 @@ func (model *GLM) Dataset() [][]statmodel.Dtype {
 	return model.data
 }
 
+// VarNames returns the names of the data columns that are used to fit
+// the model.
+func (model *GLM) VarNames() []string {
+	return model.varnames
+}
+
 // ConcurrentIRLS sets the minimum chunk size for which concurrent
 // calculations are used during IRLS.
 func (model *GLM) ConcurrentIRLS(n int) *GLM {
@@ -165,10 +391,46 @@ func (model *GLM) ConcurrentIRLS(n int) *GLM {
 }
 
 // GLMResults describes the results of a fitted generalized linear model.
+// The methods that report estimates, standard errors, and predictions may
+// be called concurrently from multiple goroutines.  Update modifies the
+// results, and EvalAt uses working storage in the model, so these should
+// not be called concurrently with other methods.
 type GLMResults struct {
 	statmodel.BaseResults
 
 	scale float64
+
+	// Convergence information for IRLS fits, nil for fits that do
+	// not use IRLS.
+	fitInfo *FitInfo
+
+	// State used by Update to incorporate additional batches of
+	// data, nil until Update is first called.
+	online *onlineState
+
+	// The fit of the null model, obtained when first needed by
+	// nullModel.
+	nullOnce sync.Once
+	null     *GLMResults
+	nullErr  error
+
+	// The standard error of the dispersion parameter, obtained when
+	// first needed by DispersionStdErr.
+	dispOnce  sync.Once
+	dispSE    float64
+	dispSEErr error
+
+	// Free-form metadata about the fit, e.g. for provenance.
+	metadata map[string]string
+}
+
+// Information returns the type of Hessian (observed or expected) that
+// is used to obtain the standard errors.
+func (model *GLM) Information() statmodel.HessType {
+	if model.obsInfo {
+		return statmodel.ObsHess
+	}
+	return statmodel.ExpHess
 }
 
 // Scale returns the estimated scale parameter.
@@ -176,6 +438,263 @@ func (rslt *GLMResults) Scale() float64 {
 	return rslt.scale
 }
 
+// SetMetadata attaches a key/value pair to the results, e.g. the name of
+// the dataset or the time of the fit, replacing any existing value for
+// the key.  The metadata can be shown in the summary table.  SetMetadata
+// is not safe for concurrent use.
+func (rslt *GLMResults) SetMetadata(key, value string) *GLMResults {
+	if rslt.metadata == nil {
+		rslt.metadata = make(map[string]string)
+	}
+	rslt.metadata[key] = value
+	return rslt
+}
+
+// Metadata returns a copy of the metadata attached to the results with
+// SetMetadata.
+func (rslt *GLMResults) Metadata() map[string]string {
+	md := make(map[string]string, len(rslt.metadata))
+	for k, v := range rslt.metadata {
+		md[k] = v
+	}
+	return md
+}
+
+// DispersionStdErr returns the standard error of the estimated
+// dispersion parameter.  For the negative binomial family, this is the
+// standard error of the alpha parameter of the family, and for the
+// Gaussian, gamma, inverse Gaussian, and Tweedie families with a free
+// dispersion it is the standard error of the scale parameter.  The
+// standard error is obtained from the second derivative of the
+// log-likelihood with respect to the dispersion parameter, with the
+// coefficients held at their estimates.  Since the coefficients and
+// the dispersion parameter are orthogonal in a GLM, this block of the
+// information matrix determines the variance of the dispersion
+// estimate.  The derivative is evaluated at the reported estimate of
+// the dispersion parameter, which for the scale is the Pearson or
+// deviance estimate rather than the MLE.  Similarly, the alpha
+// parameter of a negative binomial family is not estimated when fitting
+// the GLM, so the standard error is only meaningful if the family was
+// constructed using an estimate of alpha, e.g. from NegBinomProfiler.
+// For the beta family, this is the standard error of the precision (see
+// Precision), which is not orthogonal to the coefficients, so it is
+// obtained from the joint information of the coefficients and the
+// precision.  An error is returned for the other families, if the dispersion is
+// fixed, or if the log-likelihood is not concave in the dispersion
+// parameter at its estimate.  The standard error is calculated when
+// first needed, and DispersionStdErr may be called from multiple
+// goroutines.
+func (rslt *GLMResults) DispersionStdErr() (float64, error) {
+	rslt.dispOnce.Do(func() {
+		rslt.dispSE, rslt.dispSEErr = rslt.dispersionStdErr()
+	})
+	return rslt.dispSE, rslt.dispSEErr
+}
+
+func (rslt *GLMResults) dispersionStdErr() (float64, error) {
+
+	model := rslt.Model().(*GLM).unpenalized()
+	coeff := rslt.Params()
+
+	var disp float64
+	var loglike func(float64) float64
+	switch model.fam.TypeCode {
+	case NegBinomFamily:
+		disp = model.fam.alpha
+		loglike = func(a float64) float64 {
+			m := *model
+			m.nslices = nil
+			m.fam = NewNegBinomFamily(a, model.link)
+			return m.LogLike(&GLMParams{coeff, rslt.scale}, true)
+		}
+	case BetaFamily:
+		_, va, err := model.betaVcov(coeff, 1/rslt.scale-1)
+		if err != nil {
+			return 0, err
+		}
+		return math.Sqrt(va), nil
+	case GaussianFamily, GammaFamily, InvGaussianFamily, TweedieFamily:
+		if model.dispersionMethod == DispersionFixed {
+			return 0, fmt.Errorf("DispersionStdErr: the dispersion parameter is fixed")
+		}
+		disp = rslt.scale
+		loglike = func(scale float64) float64 {
+			return model.LogLike(&GLMParams{coeff, scale}, true)
+		}
+	default:
+		msg := fmt.Sprintf("DispersionStdErr: the dispersion parameter is not estimated for the %s family\n", model.fam.Name)
+		return 0, fmt.Errorf(msg)
+	}
+
+	h := 1e-4 * disp
+	d2 := (loglike(disp+h) - 2*loglike(disp) + loglike(disp-h)) / (h * h)
+	if !(d2 < 0) {
+		msg := fmt.Sprintf("DispersionStdErr: the log-likelihood is not concave in the dispersion parameter (second derivative %v)\n", d2)
+		return 0, fmt.Errorf(msg)
+	}
+
+	return 1 / math.Sqrt(-d2), nil
+}
+
+// FitInfo returns information about the convergence of the IRLS
+// algorithm, or of Fisher scoring for the beta family.  The second
+// return value is false if the model was not fit using IRLS or Fisher
+// scoring.
+func (rslt *GLMResults) FitInfo() (FitInfo, bool) {
+	if rslt.fitInfo == nil {
+		return FitInfo{}, false
+	}
+	return *rslt.fitInfo, true
+}
+
+// FinalScore returns the score vector (the gradient of the log-likelihood)
+// at the parameter estimates, using the estimated scale parameter.  For a
+// converged fit the score is close to zero, so its largest absolute
+// value indicates the quality of the convergence.  The score includes
+// any L2 penalty.  For L1 regularized fits, the elements corresponding
+// to coefficients that are zero need not be close to zero.
+func (rslt *GLMResults) FinalScore() []float64 {
+
+	model := rslt.Model().(*GLM)
+	score := make([]float64, model.NumParams())
+	model.Score(&GLMParams{rslt.Params(), rslt.scale}, score)
+
+	return score
+}
+
+// Precision returns the estimated precision parameter of a beta
+// regression model, which is 1/scale - 1.  The precision is estimated
+// by maximum likelihood, jointly with the coefficients.  Precision
+// panics if the model is not a beta regression.
+func (rslt *GLMResults) Precision() float64 {
+	model := rslt.Model().(*GLM)
+	if model.fam.TypeCode != BetaFamily {
+		panic("Precision: the model is not a beta regression")
+	}
+	return 1/rslt.scale - 1
+}
+
+// EvalAt evaluates the log-likelihood, score vector, and expected
+// Hessian of the fitted model at the given coefficients, holding the
+// scale parameter fixed at its estimated value.  The model is not
+// refit.  The log-likelihood is exact (it includes all constant terms)
+// and includes any L2 penalty.  The Hessian is returned in vectorized
+// (row-major) form, and like the Hessian method of the model, it is not
+// divided by the scale parameter.  This is useful for plotting
+// likelihood surfaces or for implementing custom optimizers.
+func (rslt *GLMResults) EvalAt(params []float64) (float64, []float64, []float64) {
+
+	model := rslt.Model().(*GLM)
+	p := model.NumParams()
+	if len(params) != p {
+		msg := fmt.Sprintf("EvalAt: params has length %d, expected %d\n", len(params), p)
+		panic(msg)
+	}
+
+	par := &GLMParams{coeff: params, scale: rslt.scale}
+	ll := model.LogLike(par, true)
+
+	score := make([]float64, p)
+	model.Score(par, score)
+
+	hess := make([]float64, p*p)
+	model.Hessian(par, statmodel.ExpHess, hess)
+
+	return ll, score, hess
+}
+
+// ExpParams contains exponentiated parameter estimates and confidence
+// limits.  Label is "OR" (odds ratio) for the logit link, and "RR" for
+// the log link (a rate ratio for count families, or a ratio of means
+// for other families).
+type ExpParams struct {
+	Label    string
+	Names    []string
+	Estimate []float64
+	LCB      []float64
+	UCB      []float64
+}
+
+// ExpParams returns the exponentiated parameter estimates, along with
+// the exponentiated limits of confidence intervals with the given
+// coverage probability.  For the logit link these are odds ratios and
+// for the log link they are rate ratios.  An error is returned for other
+// links or if standard errors are not available.
+func (rslt *GLMResults) ExpParams(level float64) (*ExpParams, error) {
+
+	model := rslt.Model().(*GLM)
+
+	var label string
+	switch model.link.TypeCode {
+	case LogitLink:
+		label = "OR"
+	case LogLink:
+		label = "RR"
+	default:
+		msg := fmt.Sprintf("Exponentiated parameters are not available for the %s link\n", model.link.Name)
+		return nil, fmt.Errorf(msg)
+	}
+
+	lcb, ucb := rslt.ConfInt(level)
+	if lcb == nil {
+		return nil, fmt.Errorf("Standard errors are not available")
+	}
+
+	ep := &ExpParams{
+		Label: label,
+		Names: rslt.Names(),
+	}
+	for j, p := range rslt.Params() {
+		ep.Estimate = append(ep.Estimate, math.Exp(p))
+		ep.LCB = append(ep.LCB, math.Exp(lcb[j]))
+		ep.UCB = append(ep.UCB, math.Exp(ucb[j]))
+	}
+
+	return ep, nil
+}
+
+// CoefPlotRow contains the estimate and confidence limits for one
+// coefficient, for use in a coefficient (forest) plot.
+type CoefPlotRow struct {
+	Name     string
+	Estimate float64
+	Lower    float64
+	Upper    float64
+}
+
+// CoefPlot returns the parameter estimates and the limits of confidence
+// intervals with the given coverage probability, one row per coefficient
+// in the order of the coefficients.  If exp is true, the estimates and
+// limits are exponentiated as in ExpParams, which requires the logit or
+// log link.  An error is returned if standard errors are not available.
+func (rslt *GLMResults) CoefPlot(level float64, exp bool) ([]CoefPlotRow, error) {
+
+	if exp {
+		ep, err := rslt.ExpParams(level)
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]CoefPlotRow, len(ep.Names))
+		for j, na := range ep.Names {
+			rows[j] = CoefPlotRow{na, ep.Estimate[j], ep.LCB[j], ep.UCB[j]}
+		}
+		return rows, nil
+	}
+
+	lcb, ucb := rslt.ConfInt(level)
+	if lcb == nil {
+		return nil, fmt.Errorf("Standard errors are not available")
+	}
+
+	names := rslt.Names()
+	rows := make([]CoefPlotRow, len(names))
+	for j, p := range rslt.Params() {
+		rows[j] = CoefPlotRow{names[j], p, lcb[j], ucb[j]}
+	}
+
+	return rows, nil
+}
+
 // Config defines configuration parameters for a GLM.
 type Config struct {
 
@@ -194,30 +713,187 @@ type Config struct {
 	Start []float64
 
 	// WeightVar is the name of the variable for frequency-weighting the cases, if an empty
-	// string, all weights are equal to 1.
+	// string, all weights are equal to 1.  The weights must be non-negative and not all
+	// zero.  Cases with zero weight do not contribute to the fit, and are not counted in
+	// the effective sample size (the sum of the weights).
 	WeightVar string
 
+	// NormalizeWeights rescales the weights so that they sum to the number of cases with
+	// positive weight.  This is appropriate for analytic (precision) weights, where only
+	// the relative sizes of the weights are meaningful.
+	NormalizeWeights bool
+
+	// WeightVec contains the case weights, for use when the weights are
+	// not a variable in the dataset.  If not nil, it takes precedence over
+	// WeightVar, and its length must equal the number of observations.
+	WeightVec []float64
+
+	// BinomialTrials indicates that the responses of a binomial GLM are
+	// proportions of successes in [0, 1], and the case weights are the
+	// numbers of trials, which must be positive integers.  The
+	// estimates are the same as for case weights, but the exact
+	// log-likelihood (and hence the AIC and BIC) includes the
+	// binomial coefficients.  Case weights must be provided, and can
+	// not be normalized.
+	BinomialTrials bool
+
+	// DispersionVar is the name of a variable containing known per-observation
+	// dispersions, such as the sampling variances in a meta-analysis.  The variance
+	// of observation i is scale * d_i * V(mu_i) / w_i, where d_i is the dispersion and
+	// w_i is the case weight, so the working weights are divided by the dispersions.
+	// Unlike case weights, the dispersions do not contribute to the effective sample
+	// size.  The dispersions must be positive and finite.  To treat d_i * V(mu_i) as
+	// the complete variance, use DispersionFixed so that the scale is held at 1.
+	DispersionVar string
+
+	// DispersionVec contains the per-observation dispersions, for use when they are
+	// not a variable in the dataset.  If not nil, it takes precedence over
+	// DispersionVar, and its length must equal the number of observations.
+	DispersionVec []float64
+
 	// OffsetVar is the name of a variable providing an offset
 	OffsetVar string
 
+	// OffsetVec contains the offset, for use when the offset is not a
+	// variable in the dataset.  If not nil, it takes precedence over
+	// OffsetVar, and its length must equal the number of observations.
+	OffsetVec []float64
+
+	// IDVar is the name of a variable containing observation identifiers.
+	// It is not used in fitting the model, but the identifiers can be
+	// retrieved with the IDs method of the fitted results, in the same
+	// order as the residuals and other per-observation diagnostics.
+	IDVar string
+
 	// Family defines a GLMfamily.
 	Family *Family
 
-	// Link defines a GLM link function; if not provided the default link for the family is used.
+	// Link defines a GLM link function.  If nil, the default link for the family is used,
+	// which is the canonical link for most families (see Family.DefaultLink).
 	Link *Link
 
 	// VarFunc defines how the variance relates to the mean; if not provided, the default
 	// variance function for the family is used.
 	VarFunc *Variance
 
-	// L1Penalty gives the level of penalization for each variable, by name
+	// L1Penalty gives the level of penalization for each variable, by name.  All
+	// names must be predictors, and variables that are not named, or that have a
+	// zero weight, are not penalized.
 	L1Penalty map[string]float64
 
 	// L2Penalty gives the level of penalization for each variable, by name
 	L2Penalty map[string]float64
 
+	// PenaltyExempt contains the names of variables that are not
+	// penalized, regardless of the values in L1Penalty and L2Penalty.
+	// An unpenalized intercept, or a key exposure variable, can be
+	// specified this way.  Variables that are not named in the
+	// penalty maps are also unpenalized.
+	PenaltyExempt []string
+
+	// Ridge is an L2 penalty weight that is applied to every predictor
+	// except the intercept and the variables in PenaltyExempt.  The
+	// penalty is scaled as for L2Penalty, but unlike L2Penalty the model
+	// is fit with penalized IRLS, and the covariance matrix is based on
+	// the penalized Hessian.  Ridge can not be combined with L1Penalty
+	// or L2Penalty.
+	Ridge float64
+
 	// DispersionForm determines how the dispersion parameter is handled
 	DispersionForm DispersionForm
+
+	// ScaleEstimator determines whether the scale parameter is estimated
+	// using the Pearson statistic (the default) or the deviance.  It is
+	// only used if the dispersion is not fixed.
+	ScaleEstimator ScaleEstimator
+
+	// LinkCheck determines how questionable family/link combinations
+	// are handled.
+	LinkCheck LinkCheck
+
+	// ConstrainMean determines whether the fitted means are
+	// constrained to lie strictly within the range of the family
+	// during IRLS fitting, which keeps the iterations valid when the
+	// link can produce invalid means (e.g. the identity link with the
+	// Gamma family).  The means are not constrained by default.
+	ConstrainMean bool
+
+	// ObservedInfo determines whether the standard errors are based
+	// on the observed information (the negative Hessian of the
+	// log-likelihood) rather than the expected information (the
+	// default).  The two coincide for canonical links.
+	ObservedInfo bool
+
+	// PackedHessian determines whether the covariance matrix of the
+	// parameters is obtained from the Hessian stored in packed
+	// (lower triangular) form, which halves the memory used to store the
+	// Hessian.  This is useful for models with many parameters.  The
+	// covariance matrix is obtained from the Cholesky factorization of
+	// the negative Hessian, so an error results if the negative
+	// Hessian is not positive definite.
+	PackedHessian bool
+
+	// NumericalHessian determines whether the Hessian is obtained by
+	// numerically differentiating the score, using central differences,
+	// rather than from the analytic expressions in terms of the link
+	// and variance functions.  This allows the observed information to
+	// be used with variance functions that do not provide a derivative,
+	// e.g. a VarFunc without Deriv.  The numerical Hessian is the observed
+	// Hessian, and is used regardless of ObservedInfo.  It requires two
+	// evaluations of the score per parameter, so it is slower than the
+	// analytic Hessian for models with many parameters, and it is
+	// accurate to around six significant digits.
+	NumericalHessian bool
+
+	// NewtonRaphson determines whether the IRLS updates use the
+	// observed information, giving Newton-Raphson iterations, rather
+	// than the expected information, giving Fisher scoring (the
+	// default).  The two coincide for canonical links.  Newton-Raphson
+	// converges quadratically close to the MLE, and may need fewer
+	// iterations, but the observed information may not be positive
+	// definite far from the MLE, in which case a Fisher scoring
+	// update is used for that iteration.  Fisher scoring converges
+	// linearly for non-canonical links, but the expected information
+	// is always positive definite.  The first iteration, which starts
+	// from the initial means, is always a Fisher scoring update.  This
+	// setting only applies to IRLS fitting, and does not affect the
+	// standard errors, see ObservedInfo.
+	NewtonRaphson bool
+
+	// Convergence determines the criteria used to stop the IRLS
+	// iterations, ConvergeDeviance by default.  For gradient
+	// fitting, convergence is controlled by the optimization
+	// settings.
+	Convergence ConvergenceCriterion
+
+	// ConvergenceTol is the tolerance for the convergence criteria,
+	// DefaultConvergenceTol if zero.
+	ConvergenceTol float64
+
+	// NonConvergence determines how an IRLS fit that does not
+	// converge is handled, NonConvergenceReturn by default.
+	NonConvergence NonConvergence
+
+	// LinPredBound, if positive, is a bound on the absolute value of
+	// the linear predictor.  The linear predictor is clamped to the
+	// interval [-LinPredBound, LinPredBound] before the inverse link
+	// function is applied, in the log-likelihood, score, Hessian, IRLS
+	// updates, and fitted and predicted means.  This prevents overflow
+	// and fitted means of exactly 0 or 1 under near separation in
+	// logistic regression, or extreme means in Poisson regression, at
+	// the cost of a small bias for observations whose linear predictor
+	// is clamped.  The linear predictor is taken to be constant beyond
+	// the bound, so the clamped observations do not contribute to the
+	// score and Hessian, while the IRLS weights and working responses
+	// of these observations are evaluated at the bound.  A bound of
+	// around 30 is appropriate for the logit and log links.  The bound
+	// should not be used with links such as the identity link for
+	// which the linear predictor is on the scale of the response.
+	LinPredBound float64
+
+	// The first error encountered when building the configuration by
+	// chaining.
+	err error
 }
 
 // DefaultConfig returns default configuration values for a GLM.
@@ -250,10 +926,20 @@ func NewGLM(data statmodel.Dataset, outcome string, predictors []string, config
 		config = DefaultConfig()
 	}
 
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	if err := checkValid(data); err != nil {
 		return nil, err
 	}
 
+	if config.Link == nil {
+		if _, err := config.Family.DefaultLink(); err != nil {
+			return nil, err
+		}
+	}
+
 	pos := make(map[string]int)
 	for i, v := range data.Names() {
 		pos[v] = i
@@ -275,8 +961,39 @@ func NewGLM(data statmodel.Dataset, outcome string, predictors []string, config
 		xpos = append(xpos, xp)
 	}
 
+	dat := data.Data()
+	varnames := data.Names()
+	nobs := len(dat[ypos])
+
+	// Penalized fits are identified even if columns are duplicated
+	if len(config.L1Penalty) == 0 && len(config.L2Penalty) == 0 && config.Ridge == 0 {
+		if err := checkDuplicates(dat, predictors, xpos); err != nil {
+			return nil, err
+		}
+	}
+
+	// addVec appends a standalone variable to the model's data, without
+	// modifying the caller's data, and returns its position.
+	addVec := func(vec []float64, name, field string) (int, error) {
+		if len(vec) != nobs {
+			msg := fmt.Sprintf("%s has length %d, but the data have %d observations\n", field, len(vec), nobs)
+			return -1, fmt.Errorf(msg)
+		}
+		dat = append(dat[0:len(dat):len(dat)], vec)
+		varnames = append(varnames[0:len(varnames):len(varnames)], name)
+		return len(dat) - 1, nil
+	}
+
 	weightpos := -1
-	if config.WeightVar != "" {
+	weightName := config.WeightVar
+	if config.WeightVec != nil {
+		weightName = "WeightVec"
+		var err error
+		weightpos, err = addVec(config.WeightVec, "__weight", weightName)
+		if err != nil {
+			return nil, err
+		}
+	} else if config.WeightVar != "" {
 		var ok bool
 		weightpos, ok = pos[config.WeightVar]
 		if !ok {
@@ -285,8 +1002,31 @@ func NewGLM(data statmodel.Dataset, outcome string, predictors []string, config
 		}
 	}
 
+	if weightpos != -1 {
+		if err := checkWeights(dat[weightpos], weightName); err != nil {
+			return nil, err
+		}
+		if config.NormalizeWeights {
+			// Replace the weight column without modifying the caller's data
+			dat = append([][]statmodel.Dtype(nil), dat...)
+			dat[weightpos] = normalizeWeights(dat[weightpos])
+		}
+	}
+
+	if config.BinomialTrials {
+		if err := checkTrials(dat[ypos], dat[weightpos]); err != nil {
+			return nil, err
+		}
+	}
+
 	offsetpos := -1
-	if config.OffsetVar != "" {
+	if config.OffsetVec != nil {
+		var err error
+		offsetpos, err = addVec(config.OffsetVec, "__offset", "OffsetVec")
+		if err != nil {
+			return nil, err
+		}
+	} else if config.OffsetVar != "" {
 		var ok bool
 		offsetpos, ok = pos[config.OffsetVar]
 		if !ok {
@@ -295,7 +1035,71 @@ func NewGLM(data statmodel.Dataset, outcome string, predictors []string, config
 		}
 	}
 
-	varnames := data.Names()
+	// The case weights, before combining with any dispersions
+	casewpos := weightpos
+
+	disppos := -1
+	dispName := config.DispersionVar
+	if config.DispersionVec != nil {
+		dispName = "DispersionVec"
+		var err error
+		disppos, err = addVec(config.DispersionVec, "__dispersion", dispName)
+		if err != nil {
+			return nil, err
+		}
+	} else if config.DispersionVar != "" {
+		var ok bool
+		disppos, ok = pos[config.DispersionVar]
+		if !ok {
+			msg := fmt.Sprintf("Dispersion variable '%s' not found in dataset\n", config.DispersionVar)
+			return nil, fmt.Errorf(msg)
+		}
+	}
+
+	if disppos != -1 {
+		wd := make([]float64, nobs)
+		for i, d := range dat[disppos] {
+			if !(d > 0) || math.IsInf(float64(d), 0) {
+				msg := fmt.Sprintf("Dispersion variable '%s' has invalid value %v at position %d, dispersions must be positive and finite\n",
+					dispName, d, i)
+				return nil, fmt.Errorf(msg)
+			}
+			wd[i] = 1 / float64(d)
+			if weightpos != -1 {
+				wd[i] *= float64(dat[weightpos][i])
+			}
+		}
+		var err error
+		weightpos, err = addVec(wd, "__dispweight", dispName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	idpos := -1
+	if config.IDVar != "" {
+		var ok bool
+		idpos, ok = pos[config.IDVar]
+		if !ok {
+			msg := fmt.Sprintf("ID variable '%s' not found in dataset\n", config.IDVar)
+			return nil, fmt.Errorf(msg)
+		}
+		for _, k := range append([]int{ypos, casewpos, disppos, offsetpos}, xpos...) {
+			if k == idpos {
+				msg := fmt.Sprintf("ID variable '%s' can not also be used in the model\n", config.IDVar)
+				return nil, fmt.Errorf(msg)
+			}
+		}
+	}
+
+	l1pen, err := penaltyMap(config.L1Penalty, predictors, config.PenaltyExempt)
+	if err != nil {
+		return nil, err
+	}
+	l2pen, err := penaltyMap(config.L2Penalty, predictors, config.PenaltyExempt)
+	if err != nil {
+		return nil, err
+	}
 
 	penToSlice := func(m map[string]float64) []float64 {
 		if m == nil || len(m) == 0 {
@@ -309,12 +1113,18 @@ func NewGLM(data statmodel.Dataset, outcome string, predictors []string, config
 	}
 
 	model := &GLM{
-		data:             data.Data(),
-		varnames:         data.Names(),
+		nslices:          new(nslicePool),
+		data:             dat,
+		varnames:         varnames,
 		ypos:             ypos,
 		xpos:             xpos,
 		weightpos:        weightpos,
+		casewpos:         casewpos,
+		disppos:          disppos,
+		nuser:            len(data.Data()),
+		binomTrials:      config.BinomialTrials,
 		offsetpos:        offsetpos,
+		idpos:            idpos,
 		dispersionMethod: config.DispersionForm,
 		fitMethod:        config.FitMethod,
 		concurrentIRLS:   config.ConcurrentIRLS,
@@ -322,75 +1132,369 @@ func NewGLM(data statmodel.Dataset, outcome string, predictors []string, config
 		link:             config.Link,
 		vari:             config.VarFunc,
 		start:            config.Start,
-		l1wgt:            penToSlice(config.L1Penalty),
-		l2wgt:            penToSlice(config.L2Penalty),
-		l1wgtMap:         config.L1Penalty,
-		l2wgtMap:         config.L2Penalty,
+		l1wgt:            penToSlice(l1pen),
+		l2wgt:            penToSlice(l2pen),
+		l1wgtMap:         l1pen,
+		l2wgtMap:         l2pen,
 		log:              config.Log,
+		linkCheck:        config.LinkCheck,
+		constrainMean:    config.ConstrainMean,
+		scaleEstimator:   config.ScaleEstimator,
+		obsInfo:          config.ObservedInfo,
+		packedHess:       config.PackedHessian,
+		numHess:          config.NumericalHessian,
+		convergence:      config.Convergence,
+		convtol:          config.ConvergenceTol,
+		newton:           config.NewtonRaphson,
+		nonConvergence:   config.NonConvergence,
+		lpBound:          config.LinPredBound,
+	}
+
+	if config.Ridge > 0 {
+		ridge := make(map[string]float64)
+		icept := model.interceptPos()
+		for j, na := range predictors {
+			if j != icept {
+				ridge[na] = config.Ridge
+			}
+		}
+		if model.l2wgtMap, err = penaltyMap(ridge, predictors, config.PenaltyExempt); err != nil {
+			return nil, err
+		}
+		model.l2wgt = penToSlice(model.l2wgtMap)
+		model.ridge = true
 	}
 
 	model.init()
 
-	return model, nil
-}
+	if err := model.checkLink(); err != nil {
+		return nil, err
+	}
 
-func (model *GLM) setup() {
+	if model.obsInfo && model.vari.Deriv == nil && !model.numHess {
+		msg := fmt.Sprintf("The %s variance function does not provide a derivative, which is needed "+
+			"for the observed information, use NumericalHessian\n", model.vari.Name)
+		return nil, fmt.Errorf(msg)
+	}
 
-	if model.link == nil {
-		li := model.fam.validLinks[0]
-		if model.log != nil {
-			model.log.Printf("Using default link for family: %v\n", li)
-		}
-		model.link = NewLink(li)
+	if model.newton && (model.vari.Deriv == nil || model.link.Deriv2 == nil) {
+		return nil, fmt.Errorf("NewtonRaphson requires the derivative of the variance function and the second derivative of the link function")
 	}
 
-	if model.vari == nil {
-		// Set a default variance function
-		switch model.fam.TypeCode {
-		case BinomialFamily:
-			model.vari = NewVariance(BinomialVar)
-		case PoissonFamily:
-			model.vari = NewVariance(IdentityVar)
-		case QuasiPoissonFamily:
-			model.vari = NewVariance(IdentityVar)
-		case GaussianFamily:
-			model.vari = NewVariance(ConstantVar)
-		case GammaFamily:
-			model.vari = NewVariance(SquaredVar)
-		case InvGaussianFamily:
-			model.vari = NewVariance(CubedVar)
-		case NegBinomFamily:
-			model.vari = NewNegBinomVariance(model.fam.alpha)
-		case TweedieFamily:
-			model.vari = NewTweedieVariance(model.fam.alpha)
-		default:
-			msg := fmt.Sprintf("Unknown GLM family: %s\n", model.fam.Name)
-			panic(msg)
+	if model.fam.TypeCode == BetaFamily {
+		for i, y := range model.data[ypos] {
+			if y <= 0 || y >= 1 {
+				msg := fmt.Sprintf("Beta regression requires responses strictly between 0 and 1, observation %d has value %v\n", i, y)
+				return nil, fmt.Errorf(msg)
+			}
 		}
-		if model.log != nil {
-			model.log.Printf("Using default variance for family: %v\n", model.vari)
+		if model.l1wgt != nil || model.l2wgt != nil {
+			return nil, fmt.Errorf("Beta regression can not be used with regularization")
+		}
+		if model.disppos != -1 {
+			return nil, fmt.Errorf("Beta regression can not be used with per-observation dispersions")
+		}
+		if model.dispersionMethod == DispersionFixed {
+			return nil, fmt.Errorf("Beta regression requires the precision to be estimated")
 		}
 	}
+
+	return model, nil
 }
 
-func (model *GLM) check() {
+// checkTrials checks that the responses are proportions in [0, 1], and
+// that the numbers of trials are positive integers, for which the
+// numbers of successes are integers.
+func checkTrials(y, trials []statmodel.Dtype) error {
 
-	if model.l1wgt != nil && len(model.l1wgt) != len(model.xpos) {
-		msg := fmt.Sprintf("GLM: The L1 weight vector has length %d, but the model has %d covariates.\n",
-			len(model.l1wgt), len(model.xpos))
-		panic(msg)
+	for i := range y {
+		n := float64(trials[i])
+		if !(n >= 1) || n != math.Floor(n) || math.IsInf(n, 0) {
+			msg := fmt.Sprintf("The number of trials must be a positive integer, observation %d has value %v\n", i, trials[i])
+			return fmt.Errorf(msg)
+		}
+		yi := float64(y[i])
+		if !(yi >= 0 && yi <= 1) {
+			msg := fmt.Sprintf("The responses must be proportions in [0, 1], observation %d has value %v\n", i, y[i])
+			return fmt.Errorf(msg)
+		}
+		if k := n * yi; math.Abs(k-math.Round(k)) > 1e-6 {
+			msg := fmt.Sprintf("Observation %d has a non-integer number of successes %v\n", i, k)
+			return fmt.Errorf(msg)
+		}
 	}
 
-	if model.l2wgt != nil && len(model.l2wgt) != len(model.xpos) {
-		msg := fmt.Sprintf("GLM: The L2 weight vector has length %d, but the model has %d covariates.\n",
-			len(model.l2wgt), len(model.xpos))
-		panic(msg)
-	}
+	return nil
 }
 
-func (model *GLM) init() *GLM {
+// penaltyMap checks that the names in a penalty map are predictors, and
+// returns the penalty map with the exempt variables set to zero.  The
+// caller's map is not modified.
+func penaltyMap(pen map[string]float64, predictors, exempt []string) (map[string]float64, error) {
 
-	if model.fam == nil {
+	isPred := make(map[string]bool)
+	for _, na := range predictors {
+		isPred[na] = true
+	}
+
+	for _, na := range exempt {
+		if !isPred[na] {
+			msg := fmt.Sprintf("Penalty exempt variable '%s' is not a predictor\n", na)
+			return nil, fmt.Errorf(msg)
+		}
+	}
+
+	if len(pen) == 0 {
+		return pen, nil
+	}
+
+	for na := range pen {
+		if !isPred[na] {
+			msg := fmt.Sprintf("Penalized variable '%s' is not a predictor\n", na)
+			return nil, fmt.Errorf(msg)
+		}
+	}
+
+	if len(exempt) == 0 {
+		return pen, nil
+	}
+
+	npen := make(map[string]float64)
+	for na, v := range pen {
+		npen[na] = v
+	}
+	for _, na := range exempt {
+		npen[na] = 0
+	}
+
+	return npen, nil
+}
+
+// duplicateTol is the relative tolerance for considering two predictor
+// columns to be duplicates.
+const duplicateTol = 1e-10
+
+// checkDuplicates returns an error if a predictor is included more than
+// once, or if two predictors have identical or nearly identical values,
+// in which case the coefficients are not identified.  To avoid comparing
+// all pairs of columns, the columns are only compared if their sums and
+// sums of squares are nearly equal.
+func checkDuplicates(dat [][]statmodel.Dtype, predictors []string, xpos []int) error {
+
+	sum := make([]float64, len(xpos))
+	ssq := make([]float64, len(xpos))
+	amax := make([]float64, len(xpos))
+	for j, k := range xpos {
+		for _, v := range dat[k] {
+			x := float64(v)
+			sum[j] += x
+			ssq[j] += x * x
+			amax[j] = math.Max(amax[j], math.Abs(x))
+		}
+	}
+
+	n := 0.0
+	if len(xpos) > 0 {
+		n = float64(len(dat[xpos[0]]))
+	}
+
+	for j1 := range xpos {
+		for j2 := 0; j2 < j1; j2++ {
+
+			if xpos[j1] == xpos[j2] {
+				msg := fmt.Sprintf("Predictor '%s' is included more than once\n", predictors[j1])
+				return fmt.Errorf(msg)
+			}
+
+			tol := duplicateTol * math.Max(1, math.Max(amax[j1], amax[j2]))
+			if math.Abs(sum[j1]-sum[j2]) > n*tol || math.Abs(ssq[j1]-ssq[j2]) > 2*n*tol*(tol+math.Max(amax[j1], amax[j2])) {
+				continue
+			}
+
+			x1, x2 := dat[xpos[j1]], dat[xpos[j2]]
+			dup := true
+			for i := range x1 {
+				if math.Abs(float64(x1[i]-x2[i])) > tol {
+					dup = false
+					break
+				}
+			}
+			if dup {
+				msg := fmt.Sprintf("Predictors '%s' and '%s' are duplicates\n", predictors[j2], predictors[j1])
+				return fmt.Errorf(msg)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkWeights returns an error if any of the case weights are negative
+// or not finite, or if all of the weights are zero.
+func checkWeights(wgt []statmodel.Dtype, name string) error {
+
+	var ws float64
+	for i, w := range wgt {
+		if w < 0 || math.IsNaN(float64(w)) || math.IsInf(float64(w), 0) {
+			msg := fmt.Sprintf("Weight variable '%s' has invalid value %v at position %d, weights must be non-negative and finite\n",
+				name, w, i)
+			return fmt.Errorf(msg)
+		}
+		ws += float64(w)
+	}
+
+	if ws == 0 {
+		msg := fmt.Sprintf("Weight variable '%s' is zero for all cases\n", name)
+		return fmt.Errorf(msg)
+	}
+
+	return nil
+}
+
+// normalizeWeights returns a copy of the weights, scaled to sum to the
+// number of positive weights.
+func normalizeWeights(wgt []statmodel.Dtype) []statmodel.Dtype {
+
+	var ws float64
+	var npos int
+	for _, w := range wgt {
+		ws += float64(w)
+		if w > 0 {
+			npos++
+		}
+	}
+
+	f := float64(npos) / ws
+	nw := make([]statmodel.Dtype, len(wgt))
+	for i, w := range wgt {
+		nw[i] = statmodel.Dtype(float64(w) * f)
+	}
+
+	return nw
+}
+
+// checkLink checks whether the link function is compatible with the
+// family, returning an error or recording a warning as determined by
+// the linkCheck setting.
+func (model *GLM) checkLink() error {
+
+	if model.linkCheck == LinkCheckOff || model.link.TypeCode == CustomLink {
+		return nil
+	}
+
+	var msgs []string
+	if !model.fam.IsValidLink(model.link) {
+		msgs = append(msgs, fmt.Sprintf("The %s link is not a valid link for the %s family",
+			model.link.Name, model.fam.Name))
+	} else if model.link.TypeCode != model.fam.validLinks[0] {
+		// Canonical links are not checked for the range of the mean.
+		fl, fu := model.fam.meanBounds()
+		ll, lu := model.link.meanBounds()
+		if ll < fl || lu > fu {
+			msgs = append(msgs, fmt.Sprintf("The %s link may produce invalid means for the %s family",
+				model.link.Name, model.fam.Name))
+		}
+	}
+
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	if model.linkCheck == LinkCheckStrict {
+		return fmt.Errorf(msgs[0])
+	}
+
+	for _, msg := range msgs {
+		if model.log != nil {
+			model.log.Print(msg + "\n")
+		}
+		model.warnings = append(model.warnings, msg)
+	}
+
+	return nil
+}
+
+// clampMean constrains the mean values to lie within the range that is
+// valid for the family, if this was requested with ConstrainMean.
+func (model *GLM) clampMean(mn []float64) {
+
+	if !model.constrainMean {
+		return
+	}
+
+	eps := 1e-10
+	lb, ub := model.fam.meanBounds()
+	for i := range mn {
+		if mn[i] < lb+eps {
+			mn[i] = lb + eps
+		} else if mn[i] > ub-eps {
+			mn[i] = ub - eps
+		}
+	}
+}
+
+func (model *GLM) setup() {
+
+	if model.link == nil {
+		link, err := model.fam.DefaultLink()
+		if err != nil {
+			panic(err)
+		}
+		if model.log != nil {
+			model.log.Printf("Using default link for family: %v\n", link.Name)
+		}
+		model.link = link
+	}
+
+	if model.vari == nil {
+		// Set a default variance function
+		switch model.fam.TypeCode {
+		case BinomialFamily, BetaFamily:
+			model.vari = NewVariance(BinomialVar)
+		case PoissonFamily:
+			model.vari = NewVariance(IdentityVar)
+		case QuasiPoissonFamily:
+			model.vari = NewVariance(IdentityVar)
+		case GaussianFamily:
+			model.vari = NewVariance(ConstantVar)
+		case GammaFamily:
+			model.vari = NewVariance(SquaredVar)
+		case InvGaussianFamily:
+			model.vari = NewVariance(CubedVar)
+		case NegBinomFamily:
+			model.vari = NewNegBinomVariance(model.fam.alpha)
+		case TweedieFamily:
+			model.vari = NewTweedieVariance(model.fam.alpha)
+		case CustomFamily:
+			model.vari = model.fam.vari
+		default:
+			msg := fmt.Sprintf("Unknown GLM family: %s\n", model.fam.Name)
+			panic(msg)
+		}
+		if model.log != nil {
+			model.log.Printf("Using default variance for family: %v\n", model.vari)
+		}
+	}
+}
+
+func (model *GLM) check() {
+
+	if model.l1wgt != nil && len(model.l1wgt) != len(model.xpos) {
+		msg := fmt.Sprintf("GLM: The L1 weight vector has length %d, but the model has %d covariates.\n",
+			len(model.l1wgt), len(model.xpos))
+		panic(msg)
+	}
+
+	if model.l2wgt != nil && len(model.l2wgt) != len(model.xpos) {
+		msg := fmt.Sprintf("GLM: The L2 weight vector has length %d, but the model has %d covariates.\n",
+			len(model.l2wgt), len(model.xpos))
+		panic(msg)
+	}
+}
+
+func (model *GLM) init() *GLM {
+
+	if model.fam == nil {
 		msg := "A GLM family must be specified.\n"
 		panic(msg)
 	}
@@ -416,6 +1520,11 @@ func (model *GLM) setupDispersion() {
 		}
 	}
 
+	if model.dispersionMethod == DispersionFixed && model.dispersionValue == 0 {
+		// The dispersion was fixed in the configuration
+		model.dispersionValue = 1
+	}
+
 	if model.dispersionMethod == DispersionFixed && model.dispersionValue <= 0 {
 		panic("A fixed dispersion value must be a positive number.")
 	}
@@ -470,6 +1579,10 @@ func (model *GLM) SetFamily(fam FamilyType) *GLM {
 		model.fam = &invGaussian
 		model.link = NewLink(RecipSquaredLink)
 		model.vari = NewVariance(CubedVar)
+	case BetaFamily:
+		model.fam = &beta
+		model.link = NewLink(LogitLink)
+		model.vari = NewVariance(BinomialVar)
 	case NegBinomFamily:
 		panic("GLM: can't set family to NegBinom using SetFamily")
 	case TweedieFamily:
@@ -517,10 +1630,36 @@ func (model *GLM) LogLike(params statmodel.Parameter, exact bool) float64 {
 			linpred[i] += float64(off[i])
 		}
 	}
+	model.boundLinpred(linpred)
 
 	// Update the log likelihood value
-	model.link.InvLink(linpred, mn)
-	loglike := model.fam.LogLike(yda, mn, wgts, scale, exact)
+	// The binomial log-likelihood does not depend on the variance
+	// function, and for the logit and cloglog links it is computed from
+	// the linear predictor, which avoids the loss of precision in the
+	// means close to 0 or 1.
+	var loglike float64
+	binom := model.fam.TypeCode == BinomialFamily
+	if model.disppos != -1 {
+		model.link.InvLink(linpred, mn)
+		loglike = model.dispLogLike(yda, mn, scale, exact)
+	} else if binom && model.link.TypeCode == LogitLink {
+		loglike = logitLogLike(yda, linpred, wgts)
+	} else if binom && model.link.TypeCode == CloglogLink {
+		loglike = cloglogLogLike(yda, linpred, wgts)
+	} else {
+		model.link.InvLink(linpred, mn)
+		loglike = model.fam.LogLike(yda, mn, wgts, scale, exact)
+	}
+
+	// Include the binomial coefficients for proportions with trial counts
+	if exact && model.binomTrials {
+		trials := model.data[model.casewpos]
+		for i, y := range yda {
+			n := float64(trials[i])
+			k := math.Round(n * float64(y))
+			loglike += lgamma(n+1) - lgamma(k+1) - lgamma(n-k+1)
+		}
+	}
 
 	// Account for the L2 penalty
 	if model.l2wgt != nil {
@@ -535,6 +1674,118 @@ func (model *GLM) LogLike(params statmodel.Parameter, exact bool) float64 {
 	return loglike
 }
 
+// dispLogLike returns the log-likelihood for a model with per-observation
+// dispersions, in which observation i has its case weight and the scale
+// d_i*scale, where d_i is its dispersion.  The weights in weightpos, which
+// include the dispersions, are only appropriate for the estimating
+// equations, since the likelihood depends on the dispersions through the
+// normalizing constants as well.
+func (model *GLM) dispLogLike(y []statmodel.Dtype, mn []float64, scale float64, exact bool) float64 {
+
+	disp := model.data[model.disppos]
+	var wgts, w []statmodel.Dtype
+	if model.casewpos != -1 {
+		wgts = model.data[model.casewpos]
+	}
+
+	var ll float64
+	for i := range y {
+		if wgts != nil {
+			w = wgts[i : i+1]
+		}
+		ll += model.fam.LogLike(y[i:i+1], mn[i:i+1], w, float64(disp[i])*scale, exact)
+	}
+
+	return ll
+}
+
+// logitFast returns true if the model is a binomial GLM with the logit
+// link and the binomial variance function.  In this case the mean, score
+// and Hessian factors have simple closed forms in terms of the linear
+// predictor, which are used to avoid evaluating the link and variance
+// functions separately.
+func (model *GLM) logitFast() bool {
+	return model.fam.TypeCode == BinomialFamily && model.link.TypeCode == LogitLink &&
+		model.vari == &binomVariance
+}
+
+// logitLogLike returns the binomial log-likelihood under the logit link,
+// evaluated from the linear predictor.
+func logitLogLike(y []statmodel.Dtype, linpred []float64, wt []statmodel.Dtype) float64 {
+
+	var ll float64
+	var w float64 = 1
+	for i := range y {
+		if wt != nil {
+			w = float64(wt[i])
+		}
+
+		// log(1 + exp(lp)), computed without overflow.  Log is
+		// used rather than Log1p since it is much faster, and the
+		// absolute error is negligible.
+		lp := linpred[i]
+		a := math.Log(1 + math.Exp(-math.Abs(lp)))
+		if lp > 0 {
+			a += lp
+		}
+		ll += w * (float64(y[i])*lp - a)
+	}
+
+	return ll
+}
+
+// cloglogLogLike returns the binomial log-likelihood under the
+// complementary log-log link, evaluated from the linear predictor.
+func cloglogLogLike(y []statmodel.Dtype, linpred []float64, wt []statmodel.Dtype) float64 {
+
+	var ll float64
+	var w float64 = 1
+	for i := range y {
+		if wt != nil {
+			w = float64(wt[i])
+		}
+
+		// The mean is 1 - exp(-e), so log(1 - mean) = -e.  For small
+		// e, log(mean) = lp - e/2 + O(e^2).
+		lp := linpred[i]
+		e := math.Exp(lp)
+		yi := float64(y[i])
+		if yi > 0 {
+			if e < 1e-8 {
+				ll += w * yi * (lp - e/2)
+			} else {
+				ll += w * yi * math.Log(-math.Expm1(-e))
+			}
+		}
+		if yi < 1 {
+			ll -= w * (1 - yi) * e
+		}
+	}
+
+	return ll
+}
+
+// cloglogScoreFactor calculates the score factors (y - mean) / (g'(mean) *
+// V(mean)) for the binomial family with the complementary log-log link,
+// from the linear predictor.  Since 1 / g'(mean) = e * (1 - mean) and V(mean) =
+// mean * (1 - mean), where e = exp(lp), the factor simplifies to
+// (y - mean) * e / mean, which is finite when the mean is close to 0 or 1.
+func cloglogScoreFactor(yda []statmodel.Dtype, linpred, fac []float64) {
+	for i, y := range yda {
+		e := math.Exp(linpred[i])
+		mn := -math.Expm1(-e)
+		r := float64(y) - mn
+		switch {
+		case r == 0:
+			fac[i] = 0
+		case e < 1e-8:
+			fac[i] = r * (1 + e/2)
+		default:
+			fac[i] = r * e / mn
+		}
+	}
+}
+
 func scoreFactor(yda []statmodel.Dtype, mn, deriv, va, sfac []float64) {
 	for i, y := range yda {
 		sfac[i] = (float64(y) - mn[i]) / (deriv[i] * va[i])
@@ -542,7 +1793,9 @@ func scoreFactor(yda []statmodel.Dtype, mn, deriv, va, sfac []float64) {
 }
 
 // Score returns the score vector for the generalized linear model at
-// the given parameter values.
+// the given parameter values.  For the beta family, the score depends on
+// the precision parameter, which is obtained from the scale parameter as
+// 1/scale - 1.
 func (model *GLM) Score(params statmodel.Parameter, score []float64) {
 
 	gpar := params.(*GLMParams)
@@ -579,12 +1832,27 @@ func (model *GLM) Score(params statmodel.Parameter, score []float64) {
 			linpred[i] += float64(off[i])
 		}
 	}
+	model.boundLinpred(linpred)
 
-	model.link.InvLink(linpred, mn)
-	model.link.Deriv(mn, deriv)
-	model.vari.Var(mn, va)
-
-	scoreFactor(yda, mn, deriv, va, fac)
+	if model.logitFast() {
+		// The score factor is y - mean for the canonical link
+		for i, y := range yda {
+			fac[i] = float64(y) - 1/(1+math.Exp(-linpred[i]))
+		}
+	} else if model.fam.TypeCode == BinomialFamily && model.link.TypeCode == CloglogLink &&
+		model.vari == &binomVariance {
+		cloglogScoreFactor(yda, linpred, fac)
+	} else if model.fam.TypeCode == BetaFamily {
+		model.link.InvLink(linpred, mn)
+		model.link.Deriv(mn, deriv)
+		betaScoreFactor(yda, mn, deriv, scale, fac)
+	} else {
+		model.link.InvLink(linpred, mn)
+		model.link.Deriv(mn, deriv)
+		model.vari.Var(mn, va)
+		scoreFactor(yda, mn, deriv, va, fac)
+	}
+	model.zeroClamped(linpred, fac)
 
 	for j, k := range model.xpos {
 
@@ -624,7 +1892,97 @@ func (model *GLM) Score(params statmodel.Parameter, score []float64) {
 // returned as a one-dimensional array, which is the vectorized form
 // of the Hessian matrix.  Either the observed or expected Hessian can
 // be calculated.
+//
+// The expected Hessian is -X' W X, where W is diagonal with elements
+// w / (g1^2 V), for case weight w, variance V, and link derivative g1,
+// all evaluated at the mean.  The observed Hessian multiplies these
+// elements by 1 + (y - mu) (V g2 + g1 dV) / (g1 V), where g2 is the
+// second derivative of the link and dV is the derivative of the
+// variance function.  For the canonical link of a family, g1 = 1/V, so
+// V g2 + g1 dV = 0 and the observed and expected Hessians are equal.
+// Like the score, the Hessian does not include the scale parameter.  For
+// the beta family, the Hessian is that of the beta log-likelihood at the
+// precision 1/scale - 1, multiplied by the scale.
 func (model *GLM) Hessian(param statmodel.Parameter, ht statmodel.HessType, hess []float64) {
+	if model.numHess {
+		model.numericalHessian(param, hess)
+		return
+	}
+	model.hessian(param, ht, hess, false)
+}
+
+// PackedHessian returns the Hessian matrix for the model in packed form,
+// in which the lower triangle is stored by rows, see
+// statmodel.PackedIndex.  The length of hess must be p*(p+1)/2, where p
+// is the number of parameters.
+func (model *GLM) PackedHessian(param statmodel.Parameter, ht statmodel.HessType, hess []float64) {
+
+	if model.numHess {
+		p := model.NumParams()
+		full := make([]float64, p*p)
+		model.numericalHessian(param, full)
+		for j1 := 0; j1 < p; j1++ {
+			for j2 := 0; j2 <= j1; j2++ {
+				hess[statmodel.PackedIndex(j1, j2)] = full[j1*p+j2]
+			}
+		}
+		return
+	}
+
+	model.hessian(param, ht, hess, true)
+}
+
+// numericalHessian calculates the observed Hessian using central
+// differences of the score.  Like the analytic Hessian, the result
+// excludes the scale parameter.
+func (model *GLM) numericalHessian(param statmodel.Parameter, hess []float64) {
+
+	coeff := param.(*GLMParams).coeff
+	p := len(coeff)
+
+	// The beta score depends on the precision, which is obtained from
+	// the scale parameter.
+	scale := 1.0
+	if model.fam.TypeCode == BetaFamily {
+		scale = param.(*GLMParams).scale
+	}
+
+	x := make([]float64, p)
+	copy(x, coeff)
+	sp := make([]float64, p)
+	sm := make([]float64, p)
+
+	for j := range x {
+		h := 1e-5 * math.Max(1, math.Abs(coeff[j]))
+		x[j] = coeff[j] + h
+		model.Score(&GLMParams{x, scale}, sp)
+		x[j] = coeff[j] - h
+		model.Score(&GLMParams{x, scale}, sm)
+		x[j] = coeff[j]
+		for k := range sp {
+			hess[k*p+j] = scale * (sp[k] - sm[k]) / (2 * h)
+		}
+	}
+
+	// Symmetrize
+	for j1 := 0; j1 < p; j1++ {
+		for j2 := 0; j2 < j1; j2++ {
+			u := (hess[j1*p+j2] + hess[j2*p+j1]) / 2
+			hess[j1*p+j2] = u
+			hess[j2*p+j1] = u
+		}
+	}
+}
+
+// UsePackedHessian returns true if the covariance matrix of the
+// parameters is obtained from the packed Hessian.
+func (model *GLM) UsePackedHessian() bool {
+	return model.packedHess
+}
+
+// hessian calculates the Hessian matrix, in packed form if packed is
+// true, and otherwise as a full matrix.
+func (model *GLM) hessian(param statmodel.Parameter, ht statmodel.HessType, hess []float64, packed bool) {
 
 	gpar := param.(*GLMParams)
 	coeff := gpar.coeff
@@ -668,48 +2026,69 @@ func (model *GLM) Hessian(param statmodel.Parameter, ht statmodel.HessType, hess
 			linpred[i] += float64(off[i])
 		}
 	}
+	model.boundLinpred(linpred)
 
-	// The mean response
-	model.link.InvLink(linpred, mn)
+	if model.logitFast() {
+		// The observed and expected Hessians are equal for the
+		// canonical link, and the factor is the binomial variance.
+		for i := range linpred {
+			m := 1 / (1 + math.Exp(-linpred[i]))
+			fac[i] = m * (1 - m)
+		}
+	} else if model.fam.TypeCode == BetaFamily {
+		model.link.InvLink(linpred, mn)
+		model.link.Deriv(mn, lderiv)
+		if ht == statmodel.ObsHess {
+			model.link.Deriv2(mn, lderiv2)
+		}
+		betaHessFactor(yda, mn, lderiv, lderiv2, gpar.scale, ht, fac)
+	} else {
+		// The mean response
+		model.link.InvLink(linpred, mn)
 
-	model.link.Deriv(mn, lderiv)
-	model.vari.Var(mn, va)
+		model.link.Deriv(mn, lderiv)
+		model.vari.Var(mn, va)
 
-	// Factor for the expected Hessian
-	for i := 0; i < len(lderiv); i++ {
-		fac[i] = 1 / (lderiv[i] * lderiv[i] * va[i])
+		// Factor for the expected Hessian
+		for i := 0; i < len(lderiv); i++ {
+			fac[i] = 1 / (lderiv[i] * lderiv[i] * va[i])
+		}
 	}
 
 	// Adjust the factor for the observed Hessian
-	if ht == statmodel.ObsHess {
+	if ht == statmodel.ObsHess && !model.logitFast() && model.fam.TypeCode != BetaFamily {
 		model.link.Deriv2(mn, lderiv2)
 		model.vari.Deriv(mn, vad)
 		scoreFactor(yda, mn, lderiv, va, sfac)
 
 		for i := range fac {
 			h := va[i]*lderiv2[i] + lderiv[i]*vad[i]
-			h *= sfac[i] * fac[i]
-			if wgts != nil {
-				h *= float64(wgts[i])
-			}
+			h *= sfac[i]
 			fac[i] *= 1 + h
 		}
 	}
+	model.zeroClamped(linpred, fac)
 
 	// Update the Hessian matrix
-	model.hessXprod(xdat, fac, wgts, hess)
+	model.hessXprod(xdat, fac, wgts, hess, packed)
 
 	// Fill in the upper triangle
-	for j1 := range model.xpos {
-		for j2 := 0; j2 < j1; j2++ {
-			hess[j2*nvar+j1] = hess[j1*nvar+j2]
+	if !packed {
+		for j1 := range model.xpos {
+			for j2 := 0; j2 < j1; j2++ {
+				hess[j2*nvar+j1] = hess[j1*nvar+j2]
+			}
 		}
 	}
 
 	// Account for the L2 penalty
 	if model.l2wgt != nil {
 		for j, v := range model.l2wgt {
-			hess[j*nvar+j] -= float64(nobs) * v
+			if packed {
+				hess[statmodel.PackedIndex(j, j)] -= float64(nobs) * v
+			} else {
+				hess[j*nvar+j] -= float64(nobs) * v
+			}
 		}
 	}
 
@@ -723,34 +2102,93 @@ func (model *GLM) Hessian(param statmodel.Parameter, ht statmodel.HessType, hess
 	model.putNslice(sfac)
 }
 
-func (model *GLM) hessXprod(xdat [][]statmodel.Dtype, fac []float64, wgts []statmodel.Dtype, hess []float64) {
+// hessBlockSize is the number of observations in each of the blocks
+// that are processed concurrently when calculating the Hessian.
+const hessBlockSize = 1000
+
+// hessXprod calculates the lower triangle of the Hessian, which is stored
+// in packed form if packed is true.  The data are read in a single pass
+// over the observations, which are split into blocks of hessBlockSize
+// observations that are processed concurrently, up to GOMAXPROCS blocks
+// at a time.  Each block accumulates the lower triangle of its
+// contribution in packed form, and the contributions of the blocks are
+// summed in order, so the result does not depend on the number of
+// blocks that are processed concurrently.
+func (model *GLM) hessXprod(xdat [][]statmodel.Dtype, fac []float64, wgts []statmodel.Dtype, hess []float64, packed bool) {
 
 	nvar := len(xdat)
+	if nvar == 0 {
+		return
+	}
+	nobs := len(fac)
+	ntri := nvar * (nvar + 1) / 2
+
+	nblock := (nobs + hessBlockSize - 1) / hessBlockSize
+	nwork := runtime.GOMAXPROCS(0)
+	if nwork > nblock {
+		nwork = nblock
+	}
 
-	var wg sync.WaitGroup
+	tri := make([]float64, nwork*ntri)
+	tot := make([]float64, ntri)
 
-	for j1 := range model.xpos {
-		for j2 := 0; j2 <= j1; j2++ {
+	for b0 := 0; b0 < nblock; b0 += nwork {
+		zero(tri)
+		nb := nblock - b0
+		if nb > nwork {
+			nb = nwork
+		}
 
+		var wg sync.WaitGroup
+		for w := 0; w < nb; w++ {
 			wg.Add(1)
-			go func(j1, j2 int) {
-				x1 := xdat[j1]
-				x2 := xdat[j2]
-				if wgts == nil {
-					for i := range x1 {
-						hess[j1*nvar+j2] -= fac[i] * float64(x1[i]*x2[i])
+			go func(w int) {
+				defer wg.Done()
+				i0 := (b0 + w) * hessBlockSize
+				i1 := i0 + hessBlockSize
+				if i1 > nobs {
+					i1 = nobs
+				}
+				t := tri[w*ntri : (w+1)*ntri]
+				row := make([]float64, nvar)
+				for i := i0; i < i1; i++ {
+					f := fac[i]
+					if wgts != nil {
+						f *= float64(wgts[i])
 					}
-				} else {
-					for i := range x1 {
-						hess[j1*nvar+j2] -= fac[i] * float64(x1[i]*x2[i]*wgts[i])
+					for j := range row {
+						row[j] = float64(xdat[j][i])
+					}
+					k := 0
+					for j1, x1 := range row {
+						u := f * x1
+						for _, x2 := range row[0 : j1+1] {
+							t[k] += u * x2
+							k++
+						}
 					}
 				}
-				wg.Done()
-			}(j1, j2)
+			}(w)
+		}
+		wg.Wait()
+
+		for w := 0; w < nb; w++ {
+			floats.Add(tot, tri[w*ntri:(w+1)*ntri])
 		}
 	}
 
-	wg.Wait()
+	k := 0
+	for j1 := 0; j1 < nvar; j1++ {
+		for j2 := 0; j2 <= j1; j2++ {
+			u := tot[k]
+			if packed {
+				hess[k] -= u
+			} else {
+				hess[j1*nvar+j2] -= u
+			}
+			k++
+		}
+	}
 }
 
 // Focus returns a new GLM instance with a single variable, which is variable j in the
@@ -764,6 +2202,9 @@ func (model *GLM) Focus(pos int, coeff []float64, offset []float64) statmodel.Re
 	fmodel.data = [][]statmodel.Dtype{model.data[model.ypos], model.data[model.xpos[pos]]}
 	fmodel.xpos = []int{1}
 	fmodel.ypos = 0
+	fmodel.idpos = -1
+	fmodel.casewpos = -1
+	fmodel.disppos = -1
 	fmodel.start = nil
 	fmodel.settings = nil
 	fmodel.settings = nil
@@ -775,6 +2216,7 @@ func (model *GLM) Focus(pos int, coeff []float64, offset []float64) statmodel.Re
 		fmodel.varnames = append(fmodel.varnames, model.varnames[model.weightpos])
 		fmodel.data = append(fmodel.data, model.data[model.weightpos])
 		fmodel.weightpos = len(fmodel.data) - 1
+		fmodel.casewpos = fmodel.weightpos
 	}
 
 	// Allocate a new slice for the offset
@@ -832,7 +2274,7 @@ func (model *GLM) NumObs() int {
 // coordinate descent optimization.  For fitting with no L1
 // regularization (with or without L2 regularization), call
 // fitGradient which invokes gradient optimization.
-func (model *GLM) fitRegularized() *GLMResults {
+func (model *GLM) fitRegularized() (*GLMResults, error) {
 
 	if model.log != nil {
 		model.log.Print("Regularized fitting\n")
@@ -848,6 +2290,10 @@ func (model *GLM) fitRegularized() *GLMResults {
 	par := statmodel.FitL1Reg(model, start, model.l1wgt, offset, checkstep)
 	coeff := par.GetCoeff()
 
+	if !allFinite(coeff) || math.IsNaN(model.LogLike(&GLMParams{coeff, 1}, false)) {
+		return nil, model.nonFiniteError("L1 regularized fitting", coeff)
+	}
+
 	// Covariate names
 	var xna []string
 	for _, j := range model.xpos {
@@ -861,15 +2307,31 @@ func (model *GLM) fitRegularized() *GLMResults {
 		scale:       scale,
 	}
 
-	return results
+	return results, nil
 }
 
 // Fit estimates the parameters of the GLM and returns a results
 // object.  Unregularized fits and fits involving L2 regularization
 // can be obtained, but if L1 regularization is desired use
-// FitRegularized instead of Fit.
+// FitRegularized instead of Fit.  Fit panics if the fitting fails,
+// use FitChecked to obtain an error instead.
 func (model *GLM) Fit() *GLMResults {
 
+	rslt, err := model.FitChecked()
+	if err != nil {
+		panic(err)
+	}
+
+	return rslt
+}
+
+// FitChecked estimates the parameters of the GLM like Fit, but returns
+// an error if the fitting fails.  In particular, an error describing
+// the likely cause is returned if the log-likelihood, deviance, or
+// score is not finite during the fitting, which may result from fitted
+// means that are not valid for the family, or from extreme coefficients.
+func (model *GLM) FitChecked() (*GLMResults, error) {
+
 	if model.l1wgt != nil {
 		return model.fitRegularized()
 	}
@@ -884,28 +2346,85 @@ func (model *GLM) Fit() *GLMResults {
 		start = make([]float64, nvar)
 	}
 
-	if model.l2wgt != nil {
+	if model.l2wgt != nil && !model.ridge {
 		model.fitMethod = "gradient"
 	}
 
 	var params []float64
+	var scale float64
+	var info *FitInfo
 
-	if strings.ToLower(model.fitMethod) == "gradient" {
+	if model.fam.TypeCode == BetaFamily {
+		if model.log != nil {
+			model.log.Print("Beta regression fitting using Fisher scoring\n")
+		}
+		var fi FitInfo
+		var err error
+		params, scale, fi, err = model.fitBeta(start, maxiter)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.Converged {
+			msg := fmt.Sprintf("Fisher scoring did not converge in %d iterations\n", fi.Iterations)
+			switch model.nonConvergence {
+			case NonConvergenceError:
+				return nil, fmt.Errorf(msg)
+			case NonConvergencePanic:
+				panic(msg)
+			}
+		}
+		info = &fi
+	} else if nvar == 0 {
+		// Nothing to estimate, the fitted means are determined by the
+		// offset.
+		params = []float64{}
+	} else if strings.ToLower(model.fitMethod) == "gradient" {
 		if model.log != nil {
 			model.log.Print("Unregularized fitting using gradient optimization\n")
 		}
-		params, _ = model.fitGradient(start)
+		var err error
+		params, _, err = model.fitGradient(start)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		if model.log != nil {
-			model.log.Print("Unregularized fitting using IRLS\n")
+			if model.ridge {
+				model.log.Print("Ridge regularized fitting using IRLS\n")
+			} else {
+				model.log.Print("Unregularized fitting using IRLS\n")
+			}
+		}
+		var fi FitInfo
+		var err error
+		params, fi, err = model.fitIRLS(start, maxiter)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.Converged {
+			msg := fmt.Sprintf("IRLS did not converge in %d iterations\n", fi.Iterations)
+			switch model.nonConvergence {
+			case NonConvergenceError:
+				return nil, fmt.Errorf(msg)
+			case NonConvergencePanic:
+				panic(msg)
+			}
 		}
-		params = model.fitIRLS(start, maxiter)
+		info = &fi
 	}
 
-	scale := model.EstimateScale(params)
+	var vcov []float64
+	if model.fam.TypeCode == BetaFamily {
+		// The coefficients and the precision are not orthogonal, so
+		// the covariance is obtained from their joint information.
+		vcov, _, _ = model.betaVcov(params, 1/scale-1)
+	} else {
+		scale = model.EstimateScale(params)
 
-	vcov, _ := statmodel.GetVcov(model, &GLMParams{params, scale})
-	floats.Scale(scale, vcov)
+		// The Hessian excludes the scale parameter, so the covariance
+		// is scaled here.
+		vcov, _ = statmodel.GetVcovScaled(model, &GLMParams{params, scale}, model.Information(), scale)
+	}
 
 	ll := model.LogLike(&GLMParams{params, scale}, true)
 
@@ -917,18 +2436,31 @@ func (model *GLM) Fit() *GLMResults {
 	results := &GLMResults{
 		BaseResults: statmodel.NewBaseResults(model, ll, params, xna, vcov),
 		scale:       scale,
+		fitInfo:     info,
 	}
 
-	return results
+	return results, nil
 }
 
 // fitGradient uses gradient-based optimization to obtain the fitted
 // GLM parameters.
-func (model *GLM) fitGradient(start []float64) ([]float64, float64) {
+func (model *GLM) fitGradient(start []float64) ([]float64, float64, error) {
+
+	// The first point at which the log-likelihood or score is NaN.  An
+	// infinite log-likelihood at a trial point is not an error, since
+	// the line search can step back from it.
+	var nanx []float64
 
 	p := optimize.Problem{
 		Func: func(x []float64) float64 {
-			return -model.LogLike(&GLMParams{x, 1}, false)
+			f := -model.LogLike(&GLMParams{x, 1}, false)
+			if math.IsNaN(f) {
+				if nanx == nil {
+					nanx = append([]float64(nil), x...)
+				}
+				return math.Inf(1)
+			}
+			return f
 		},
 		Grad: func(grad, x []float64) {
 			if len(grad) != len(x) {
@@ -936,6 +2468,9 @@ func (model *GLM) fitGradient(start []float64) ([]float64, float64) {
 			}
 			model.Score(&GLMParams{x, 1}, grad)
 			floats.Scale(-1, grad)
+			if nanx == nil && !allFinite(grad) {
+				nanx = append([]float64(nil), x...)
+			}
 		},
 	}
 
@@ -950,12 +2485,18 @@ func (model *GLM) fitGradient(start []float64) ([]float64, float64) {
 	}
 
 	optrslt, err := optimize.Minimize(p, start, model.settings, model.method)
+	if err == nil {
+		err = optrslt.Status.Err()
+	}
+	if err == nil && (math.IsInf(optrslt.F, 0) || !allFinite(optrslt.X) || !allFinite(optrslt.Gradient)) {
+		return nil, 0, model.nonFiniteError("gradient optimization", optrslt.X)
+	}
 	if err != nil {
+		if nanx != nil {
+			return nil, 0, model.nonFiniteError("gradient optimization", nanx)
+		}
 		model.failMessage(optrslt)
-		panic(err)
-	}
-	if err = optrslt.Status.Err(); err != nil {
-		panic(err)
+		return nil, 0, err
 	}
 
 	params := make([]float64, len(optrslt.X))
@@ -965,7 +2506,62 @@ func (model *GLM) fitGradient(start []float64) ([]float64, float64) {
 
 	fvalue := -optrslt.F
 
-	return params, fvalue
+	return params, fvalue, nil
+}
+
+// allFinite returns true if none of the elements of x are NaN or
+// infinite.
+func allFinite(x []float64) bool {
+	for _, v := range x {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// nonFiniteError returns an error for a fit in which a non-finite
+// log-likelihood, deviance, or score was encountered at the given
+// parameters, describing the likely cause.
+func (model *GLM) nonFiniteError(what string, params []float64) error {
+
+	for j, v := range params {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			msg := fmt.Sprintf("%s failed: the coefficient for '%s' is %v, the coefficients may be diverging (e.g. due to separation or collinearity)\n",
+				what, model.varnames[model.xpos[j]], v)
+			return fmt.Errorf(msg)
+		}
+	}
+
+	lp := model.LinearPredictor(&GLMParams{params, 1}, nil)
+	mn := make([]float64, len(lp))
+	model.link.InvLink(lp, mn)
+	lb, ub := model.fam.meanBounds()
+	for i, m := range mn {
+		if !(m > lb && m < ub) {
+			msg := fmt.Sprintf("%s failed: the fitted mean %v for observation %d (linear predictor %v) is not valid for the %s family, consider a different link function\n",
+				what, m, i, lp[i], model.fam.Name)
+			return fmt.Errorf(msg)
+		}
+	}
+
+	for i, v := range lp {
+		if math.Abs(v) > 700 {
+			msg := fmt.Sprintf("%s failed: the linear predictor %v for observation %d is extreme, the coefficients may be too large or the covariates poorly scaled\n",
+				what, v, i)
+			return fmt.Errorf(msg)
+		}
+	}
+
+	for i, y := range model.data[model.ypos] {
+		if math.IsNaN(float64(y)) || math.IsInf(float64(y), 0) {
+			msg := fmt.Sprintf("%s failed: the response for observation %d is %v\n", what, i, y)
+			return fmt.Errorf(msg)
+		}
+	}
+
+	msg := fmt.Sprintf("%s failed: the log-likelihood or score is not finite\n", what)
+	return fmt.Errorf(msg)
 }
 
 // OptSettings allows the caller to provide an optimization settings
@@ -1014,16 +2610,70 @@ func (model *GLM) failMessage(optrslt *optimize.Result) {
 }
 
 // EstimateScale returns an estimate of the GLM scale parameter at the
-// given parameter values.
+// given parameter values.  The estimate is based on either the Pearson
+// statistic or the deviance, as determined by the ScaleEstimator
+// configuration setting.
 func (model *GLM) EstimateScale(params []float64) float64 {
 
 	if model.dispersionMethod == DispersionFixed {
 		return model.dispersionValue
 	}
 
-	nvar := model.NumParams()
-	var ws float64
 	var scale float64
+	switch model.scaleEstimator {
+	case ScaleDeviance:
+		scale = model.deviance(params)
+	default:
+		scale, _ = model.pearsonChi2(params)
+	}
+	scale /= model.residDF()
+
+	return scale
+}
+
+// residDF returns the residual degrees of freedom of the unregularized
+// model, which is the sum of the case weights minus the number of
+// covariates.
+func (model *GLM) residDF() float64 {
+	return model.sumWeights() - float64(model.NumParams())
+}
+
+// deviance returns the (unscaled) deviance at the given parameter values.
+func (model *GLM) deviance(params []float64) float64 {
+
+	var wgt []statmodel.Dtype
+	if model.weightpos != -1 {
+		wgt = model.data[model.weightpos]
+	}
+
+	mn := model.Mean(&GLMParams{params, 1}, nil)
+
+	return model.fam.Deviance(model.data[model.ypos], mn, wgt, 1)
+}
+
+// sumWeights returns the sum of the case weights, which is the number
+// of observations if there are no weights.
+func (model *GLM) sumWeights() float64 {
+
+	if model.casewpos == -1 {
+		return float64(model.NumObs())
+	}
+
+	var ws float64
+	for _, w := range model.data[model.casewpos] {
+		ws += float64(w)
+	}
+
+	return ws
+}
+
+// pearsonChi2 returns the Pearson chi-square statistic (the weighted sum
+// of squared residuals divided by the variance function) at the given
+// parameter values, along with the sum of the case weights.
+func (model *GLM) pearsonChi2(params []float64) (float64, float64) {
+
+	var ws float64
+	var chi2 float64
 	var wgt, off []statmodel.Dtype
 
 	yda := model.data[model.ypos]
@@ -1049,6 +2699,7 @@ func (model *GLM) EstimateScale(params []float64) float64 {
 			linpred[i] += float64(off[i])
 		}
 	}
+	model.boundLinpred(linpred)
 
 	// The mean response and variance
 	model.link.InvLink(linpred, mn)
@@ -1057,21 +2708,405 @@ func (model *GLM) EstimateScale(params []float64) float64 {
 	for i := range yda {
 		r := float64(yda[i]) - mn[i]
 		if wgt == nil {
-			scale += r * r / va[i]
+			chi2 += r * r / va[i]
 			ws += 1
 		} else {
-			scale += float64(wgt[i]) * r * r / va[i]
+			chi2 += float64(wgt[i]) * r * r / va[i]
 			ws += float64(wgt[i])
 		}
 	}
 
-	scale /= (ws - float64(nvar))
-
 	model.putNslice(linpred)
 	model.putNslice(mn)
 	model.putNslice(va)
 
-	return scale
+	return chi2, ws
+}
+
+// Deviance returns the (unscaled) residual deviance of the fitted model.
+func (rslt *GLMResults) Deviance() float64 {
+	model := rslt.Model().(*GLM)
+	return model.deviance(rslt.Params())
+}
+
+// nullModel returns the fit of the null model.  If the fitted model has
+// an intercept (a covariate that is a nonzero constant), the null model
+// contains only the intercept, otherwise the null model contains no
+// covariates.  The case weights, any offset, and the dispersion settings
+// are retained in the null model, and any penalties are dropped.  The
+// null model is fit once, and the fit is shared by all the statistics
+// that depend on it.
+func (rslt *GLMResults) nullModel() (*GLMResults, error) {
+
+	rslt.nullOnce.Do(func() {
+		model := rslt.Model().(*GLM)
+
+		nmodel := *model
+		nmodel.nslices = new(nslicePool)
+		nmodel.l1wgt = nil
+		nmodel.l1wgtMap = nil
+		nmodel.l2wgt = nil
+		nmodel.l2wgtMap = nil
+		nmodel.ridge = false
+		nmodel.start = nil
+		nmodel.fitMethod = "IRLS"
+		nmodel.xpos = nil
+		if j := model.interceptPos(); j != -1 {
+			nmodel.xpos = []int{model.xpos[j]}
+		}
+
+		rslt.null, rslt.nullErr = nmodel.FitChecked()
+	})
+
+	return rslt.null, rslt.nullErr
+}
+
+// NullModel returns the fit of the null model, which contains only the
+// intercept if the fitted model has one, and otherwise contains no
+// covariates.  The case weights, any offset, and the dispersion settings
+// are retained, and any penalties are dropped.  The null model is fit
+// when first needed, and the fit is reused by NullDeviance, NullLogLike,
+// and DevianceR2.  If the null model can not be fit, nil is returned.
+func (rslt *GLMResults) NullModel() statmodel.BaseResultser {
+
+	null, err := rslt.nullModel()
+	if err != nil {
+		return nil
+	}
+
+	return null
+}
+
+// NullDeviance returns the (unscaled) deviance of the null model, see
+// NullModel.  If the null model can not be fit, NaN is returned.
+func (rslt *GLMResults) NullDeviance() float64 {
+
+	null, err := rslt.nullModel()
+	if err != nil {
+		return math.NaN()
+	}
+
+	return null.Deviance()
+}
+
+// NullLogLike returns the log-likelihood of the null model, see
+// NullModel.  If the null model can not be fit, NaN is returned.
+func (rslt *GLMResults) NullLogLike() float64 {
+
+	null, err := rslt.nullModel()
+	if err != nil {
+		return math.NaN()
+	}
+
+	return null.LogLike()
+}
+
+// LargeConditionNumber is the threshold above which the condition number
+// is flagged in the summary.
+const LargeConditionNumber = 1e10
+
+// MinEventsPerParam is the threshold below which the number of events
+// (or observations) per parameter is flagged in the summary, see
+// EventsPerParam.
+const MinEventsPerParam = 10
+
+// EventsPerParam returns the number of events per parameter, a rule of
+// thumb for whether the data are sufficient for reliable estimates and
+// inference, with values below around 10 indicating that the estimates
+// may be unstable or overfit.  For the binomial family, the number of
+// events is the size of the smaller of the two outcome classes, i.e. the
+// lesser of the weighted sums of y and 1 - y.  For the other families,
+// it is the number of observations (the sum of the case weights).  If
+// the model has no parameters, +Inf is returned.
+func (rslt *GLMResults) EventsPerParam() float64 {
+
+	model := rslt.Model().(*GLM)
+
+	p := model.NumParams()
+	if p == 0 {
+		return math.Inf(1)
+	}
+
+	n := model.sumWeights()
+	if model.fam.TypeCode == BinomialFamily {
+		var ev float64
+		for i, y := range model.data[model.ypos] {
+			w := 1.0
+			if model.casewpos != -1 {
+				w = float64(model.data[model.casewpos][i])
+			}
+			ev += w * float64(y)
+		}
+		n = math.Min(ev, n-ev)
+	}
+
+	return n / float64(p)
+}
+
+// ConditionNumber returns the condition number of the information
+// matrix (the negative Hessian of the log-likelihood, excluding the
+// scale) at the fitted parameters, which is the ratio of its largest to
+// its smallest singular value.  This is the square of the condition
+// number of the design matrix weighted by the IRLS weights.  Large
+// values indicate collinearity among the covariates, in which case the
+// standard errors may be numerically unreliable.  If the information
+// matrix is singular, +Inf is returned.
+func (rslt *GLMResults) ConditionNumber() float64 {
+
+	model := rslt.Model().(*GLM)
+	nvar := model.NumParams()
+	if nvar == 0 {
+		return math.NaN()
+	}
+
+	hess := make([]float64, nvar*nvar)
+	model.Hessian(&GLMParams{rslt.Params(), rslt.scale}, model.Information(), hess)
+
+	var svd mat.SVD
+	if !svd.Factorize(mat.NewDense(nvar, nvar, hess), mat.SVDNone) {
+		return math.Inf(1)
+	}
+	s := svd.Values(nil)
+
+	if s[nvar-1] <= 0 {
+		return math.Inf(1)
+	}
+
+	return s[0] / s[nvar-1]
+}
+
+// DevianceR2 returns the deviance-based coefficient of determination,
+// 1 - D/D0, where D is the residual deviance and D0 is the null deviance.
+// For a Gaussian model with an intercept this is the usual R^2.
+func (rslt *GLMResults) DevianceR2() float64 {
+	return 1 - rslt.Deviance()/rslt.NullDeviance()
+}
+
+// DevianceGoF returns the residual deviance, its degrees of freedom, and
+// the p-value of the goodness-of-fit test based on comparing the deviance
+// to a chi-square distribution.  The chi-square approximation is only
+// valid for grouped data with large counts per group, such as Poisson
+// counts with large means, or binomial proportions based on many trials
+// per observation.  It should not be used for sparse data, such as
+// binary (0/1) responses.
+func (rslt *GLMResults) DevianceGoF() (float64, float64, float64) {
+
+	dev := rslt.Deviance()
+	df := rslt.ResidDF()
+	pv := distuv.ChiSquared{K: df}.Survival(dev)
+
+	return dev, df, pv
+}
+
+// GoFStats contains the deviance and Pearson chi-square goodness-of-fit
+// statistics, which share the residual degrees of freedom.
+type GoFStats struct {
+
+	// The (unscaled) residual deviance and its p-value
+	Deviance       float64
+	DeviancePValue float64
+
+	// The Pearson chi-square statistic and its p-value
+	Pearson       float64
+	PearsonPValue float64
+
+	// The residual degrees of freedom
+	DF float64
+}
+
+// GoFStats returns the deviance and Pearson chi-square statistics, with
+// their residual degrees of freedom and p-values based on the chi-square
+// distribution.  Under a correctly specified model each statistic is
+// approximately equal to its degrees of freedom, so the ratios of the
+// statistics to the degrees of freedom estimate the dispersion, and a
+// large discrepancy between the two statistics indicates problems with
+// the model.  As for DevianceGoF, the p-values are only valid for
+// grouped data with large counts.
+func (rslt *GLMResults) GoFStats() *GoFStats {
+
+	model := rslt.Model().(*GLM)
+
+	dev, df, dpv := rslt.DevianceGoF()
+	chi2, _ := model.pearsonChi2(rslt.Params())
+
+	return &GoFStats{
+		Deviance:       dev,
+		DeviancePValue: dpv,
+		Pearson:        chi2,
+		PearsonPValue:  distuv.ChiSquared{K: df}.Survival(chi2),
+		DF:             df,
+	}
+}
+
+// unpenalized returns a copy of the model with no L2 penalty.
+func (model *GLM) unpenalized() *GLM {
+	umodel := *model
+	umodel.l2wgt = nil
+	umodel.l2wgtMap = nil
+	umodel.nslices = nil
+	return &umodel
+}
+
+// EffectiveDF returns the effective degrees of freedom of the fitted
+// model.  For unregularized fits this is the number of parameters.  For
+// fits with an L2 (ridge) penalty, this is the trace of the hat matrix
+// of the penalized fit, tr[(I + P)^-1 I], where I is the information
+// matrix and P is the diagonal penalty matrix.  For fits with an L1
+// penalty, the calculation is restricted to the coefficients that are
+// not equal to zero, so that with no L2 penalty the effective degrees
+// of freedom is the number of nonzero coefficients.
+func (rslt *GLMResults) EffectiveDF() float64 {
+
+	model := rslt.Model().(*GLM)
+	params := rslt.Params()
+
+	if model.l1wgt == nil && model.l2wgt == nil {
+		return float64(model.NumParams())
+	}
+
+	// The active set of coefficients
+	var active []int
+	for j := range params {
+		if model.l1wgt == nil || params[j] != 0 {
+			active = append(active, j)
+		}
+	}
+
+	if model.l2wgt == nil || len(active) == 0 {
+		return float64(len(active))
+	}
+
+	p := model.NumParams()
+	hess := make([]float64, p*p)
+	model.unpenalized().Hessian(&GLMParams{params, 1}, statmodel.ExpHess, hess)
+
+	q := len(active)
+	info := mat.NewDense(q, q, nil)
+	pinfo := mat.NewDense(q, q, nil)
+	nobs := float64(model.NumObs())
+	for j1, k1 := range active {
+		for j2, k2 := range active {
+			info.Set(j1, j2, -hess[k1*p+k2])
+			pinfo.Set(j1, j2, -hess[k1*p+k2])
+		}
+		pinfo.Set(j1, j1, pinfo.At(j1, j1)+nobs*model.l2wgt[k1])
+	}
+
+	var hat mat.Dense
+	if err := hat.Solve(pinfo, info); err != nil {
+		return math.NaN()
+	}
+
+	return mat.Trace(&hat)
+}
+
+// ResidDF returns the residual degrees of freedom, which is the sum of
+// the case weights (the number of observations if there are no weights)
+// minus the effective degrees of freedom of the model (see EffectiveDF).
+// Offsets are not counted as parameters.  If Update has been called, all
+// batches of data are included in the sum of the weights.
+func (rslt *GLMResults) ResidDF() float64 {
+
+	model := rslt.Model().(*GLM)
+
+	ws := model.sumWeights()
+	if rslt.online != nil {
+		ws = rslt.online.wsum
+	}
+
+	return ws - rslt.EffectiveDF()
+}
+
+// ModelDF returns the model degrees of freedom, which is the effective
+// degrees of freedom of the model (see EffectiveDF) minus one if the
+// covariates include an intercept (a nonzero constant covariate).  For
+// L1 regularized fits, an intercept is only taken into account if its
+// coefficient is nonzero.
+func (rslt *GLMResults) ModelDF() float64 {
+
+	model := rslt.Model().(*GLM)
+
+	// A zero coefficient does not contribute to the effective degrees
+	// of freedom of an L1 regularized fit.
+	df := rslt.EffectiveDF()
+	if j := model.interceptPos(); j != -1 && (model.l1wgt == nil || rslt.Params()[j] != 0) {
+		df--
+	}
+
+	return df
+}
+
+// interceptPos returns the position among the covariates of the first
+// covariate that is a nonzero constant, or -1 if there is no such
+// covariate.
+func (model *GLM) interceptPos() int {
+	for j, k := range model.xpos {
+		x := model.data[k]
+		if len(x) > 0 && x[0] != 0 && isConstant(x) {
+			return j
+		}
+	}
+	return -1
+}
+
+// HasIntercept returns true if the model includes an intercept, which
+// is a covariate that is a nonzero constant.  This depends only on the
+// covariates, not on the estimated coefficients, so it is also true for
+// an L1 regularized fit in which the intercept coefficient is zero.
+// The null model contains the intercept if HasIntercept is true.
+func (rslt *GLMResults) HasIntercept() bool {
+	model := rslt.Model().(*GLM)
+	return model.interceptPos() != -1
+}
+
+// numEstimated returns the number of estimated parameters that are
+// accounted for in information criteria, which is the effective
+// degrees of freedom plus one if the scale parameter is estimated.
+func (rslt *GLMResults) numEstimated() float64 {
+
+	model := rslt.Model().(*GLM)
+
+	df := rslt.EffectiveDF()
+	if model.dispersionMethod != DispersionFixed {
+		df++
+	}
+
+	return df
+}
+
+// unpenalizedLogLike returns the log-likelihood at the estimated
+// parameters, excluding any penalty terms.
+func (rslt *GLMResults) unpenalizedLogLike() float64 {
+
+	model := rslt.Model().(*GLM)
+	if model.l1wgt == nil && model.l2wgt == nil {
+		return rslt.LogLike()
+	}
+
+	return model.unpenalized().LogLike(&GLMParams{rslt.Params(), rslt.scale}, true)
+}
+
+// AIC returns the Akaike information criterion for the fitted model.
+// The log-likelihood excludes any penalty terms, and the number of
+// parameters is the effective degrees of freedom (see EffectiveDF),
+// plus one if the scale parameter is estimated.  The log-likelihood
+// includes all normalizing constants, and is evaluated at the estimated
+// scale parameter.  Note that some software (e.g. R for the gamma family)
+// instead uses the deviance divided by the sample size as the scale
+// parameter when calculating the AIC, so the values may differ slightly.
+func (rslt *GLMResults) AIC() float64 {
+	return -2*rslt.unpenalizedLogLike() + 2*rslt.numEstimated()
+}
+
+// BIC returns the Bayesian information criterion for the fitted model.
+// The log-likelihood and number of parameters are as in AIC, and the
+// sample size is the sum of the case weights.
+func (rslt *GLMResults) BIC() float64 {
+
+	model := rslt.Model().(*GLM)
+	ws := model.sumWeights()
+
+	return -2*rslt.unpenalizedLogLike() + math.Log(ws)*rslt.numEstimated()
 }
 
 // resize returns a float64 slice of length n, using the initial
@@ -1120,8 +3155,27 @@ type GLMSummary struct {
 
 	// Messages that are appended to the table
 	messages []string
+
+	// If true, coefficients with p-values below HighlightLevel are
+	// highlighted using ANSI color codes.
+	color bool
+
+	// If true, the parameter table is transposed, with one column
+	// per coefficient.
+	transpose bool
+
+	// Positions of the coefficients shown in the parameter table, if
+	// nil all coefficients are shown.
+	keep []int
+
+	// If true, the metadata of the results are shown.
+	metadata bool
 }
 
+// HighlightLevel is the p-value threshold below which coefficients are
+// highlighted in summary tables, if color output is enabled.
+const HighlightLevel = 0.05
+
 // SetScale sets the scale on which the parameter results are
 // displayed in the summary.  'xf' is a function that maps
 // parameters and confidence limits from the linear scale to
@@ -1133,6 +3187,53 @@ func (gs *GLMSummary) SetScale(xf func(float64) float64, msg string) *GLMSummary
 	return gs
 }
 
+// SetColor determines whether the summary table highlights coefficients
+// with p-values less than HighlightLevel using ANSI color codes.  Color
+// should only be enabled when writing to a terminal.
+func (gs *GLMSummary) SetColor(color bool) *GLMSummary {
+	gs.color = color
+	return gs
+}
+
+// SetTranspose determines whether the parameter table is transposed, so
+// that the statistics are shown in rows and the coefficients in
+// columns.  This is more compact for models with few covariates.  Rows
+// are not highlighted in the transposed table.
+func (gs *GLMSummary) SetTranspose(transpose bool) *GLMSummary {
+	gs.transpose = transpose
+	return gs
+}
+
+// SetShowMetadata determines whether the metadata attached to the results
+// with SetMetadata are shown at the top of the summary table, ordered by
+// key.
+func (gs *GLMSummary) SetShowMetadata(show bool) *GLMSummary {
+	gs.metadata = show
+	return gs
+}
+
+// SetParams restricts the parameter table to the coefficients of the
+// named covariates, shown in the given order.  The model is fit with all
+// of the covariates, so the displayed results are the same as in the
+// full table.  If no names are given, all coefficients are shown.
+// SetParams panics if a name is not a covariate in the model, or if a
+// name is repeated.
+func (gs *GLMSummary) SetParams(names ...string) *GLMSummary {
+
+	if len(names) == 0 {
+		gs.keep = nil
+		return gs
+	}
+
+	keep, err := gs.results.ParamPositions(names)
+	if err != nil {
+		panic(err)
+	}
+	gs.keep = keep
+
+	return gs
+}
+
 // String returns a string representation of a summary table for the model.
 func (gs *GLMSummary) String() string {
 
@@ -1144,8 +3245,15 @@ func (gs *GLMSummary) String() string {
 		xf = gs.paramXform
 	}
 
+	var msgs []string
+	msgs = append(msgs, gs.model.warnings...)
+	msgs = append(msgs, gs.messages...)
+	if fi, ok := gs.results.FitInfo(); ok && !fi.Converged {
+		msgs = append(msgs, fmt.Sprintf("IRLS did not converge in %d iterations", fi.Iterations))
+	}
+
 	sum := &statmodel.SummaryTable{
-		Msg: gs.messages,
+		Msg: msgs,
 	}
 
 	sum.Title = "Generalized linear model analysis"
@@ -1158,8 +3266,65 @@ func (gs *GLMSummary) String() string {
 		fmt.Sprintf("Scale:    %f", gs.results.scale),
 	}
 
+	// The beta scale parameter is obtained from the maximum likelihood
+	// estimate of the precision.
+	isBeta := gs.model.fam.TypeCode == BetaFamily
+	if gs.model.dispersionMethod != DispersionFixed && !isBeta {
+		sum.Top = append(sum.Top, fmt.Sprintf("Scale estimator: %s", gs.model.scaleEstimator))
+	}
+
+	if se, err := gs.results.DispersionStdErr(); err == nil {
+		if gs.model.fam.TypeCode == NegBinomFamily {
+			sum.Top = append(sum.Top, fmt.Sprintf("Alpha:    %f (SE %f)", gs.model.fam.alpha, se))
+		} else if isBeta {
+			sum.Top = append(sum.Top, fmt.Sprintf("Precision: %f (SE %f)", gs.results.Precision(), se))
+		} else {
+			sum.Top = append(sum.Top, fmt.Sprintf("Scale SE: %f", se))
+		}
+	}
+
 	l1 := gs.model.l1wgt != nil
 
+	if !l1 {
+		sum.Top = append(sum.Top, fmt.Sprintf("Information: %s", gs.model.Information()))
+	}
+
+	if gs.metadata {
+		var keys []string
+		for k := range gs.results.metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sum.Top = append(sum.Top, fmt.Sprintf("%s: %s", k, gs.results.metadata[k]))
+		}
+	}
+
+	sum.Top = append(sum.Top, fmt.Sprintf("Deviance R^2: %f", gs.results.DevianceR2()))
+
+	if !l1 && gs.model.NumParams() > 0 {
+		cn := gs.results.ConditionNumber()
+		sum.Top = append(sum.Top, fmt.Sprintf("Condition number: %.4g", cn))
+		if cn > LargeConditionNumber {
+			sum.Msg = append(sum.Msg, fmt.Sprintf("The condition number is large (%.4g), the standard errors may be unreliable due to collinearity", cn))
+		}
+	}
+
+	if epv := gs.results.EventsPerParam(); epv < MinEventsPerParam {
+		what := "observations"
+		if gs.model.fam.TypeCode == BinomialFamily {
+			what = "events"
+		}
+		sum.Msg = append(sum.Msg, fmt.Sprintf("There are %.3g %s per parameter, the estimates may be unstable or overfit", epv, what))
+	}
+
+	switch gs.model.fam.TypeCode {
+	case PoissonFamily, QuasiPoissonFamily, NegBinomFamily:
+		gof := gs.results.GoFStats()
+		sum.Msg = append(sum.Msg, fmt.Sprintf("Deviance = %.4g, Pearson chi^2 = %.4g, residual DF = %.4g",
+			gof.Deviance, gof.Pearson, gof.DF))
+	}
+
 	if !l1 {
 		if gs.paramXform == nil {
 			sum.ColNames = []string{"Variable   ", "Parameter", "SE", "LCB", "UCB", "Z-score", "P-value"}
@@ -1236,6 +3401,13 @@ func (gs *GLMSummary) String() string {
 				gs.results.PValues(),
 			}
 		}
+
+		if gs.color {
+			sum.Color = true
+			for _, pv := range gs.results.PValues() {
+				sum.Highlight = append(sum.Highlight, pv < HighlightLevel)
+			}
+		}
 	} else {
 		sum.Cols = []interface{}{
 			gs.results.Names(),
@@ -1243,9 +3415,49 @@ func (gs *GLMSummary) String() string {
 		}
 	}
 
+	if gs.keep != nil {
+		for j, c := range sum.Cols {
+			switch c := c.(type) {
+			case []string:
+				x := make([]string, len(gs.keep))
+				for i, k := range gs.keep {
+					x[i] = c[k]
+				}
+				sum.Cols[j] = x
+			case []float64:
+				x := make([]float64, len(gs.keep))
+				for i, k := range gs.keep {
+					x[i] = c[k]
+				}
+				sum.Cols[j] = x
+			}
+		}
+		if sum.Highlight != nil {
+			hl := make([]bool, len(gs.keep))
+			for i, k := range gs.keep {
+				hl[i] = sum.Highlight[k]
+			}
+			sum.Highlight = hl
+		}
+		sum.Msg = append(sum.Msg, fmt.Sprintf("Showing %d of %d coefficients", len(gs.keep), gs.model.NumParams()))
+	}
+
+	if gs.transpose {
+		return sum.Transpose().String()
+	}
+
 	return sum.String()
 }
 
+// ParamPositions returns the positions in the coefficient vector of the
+// named covariates, in the given order, for restricting inference to a
+// subset of the coefficients, e.g. the positions can be passed to
+// ProfileLogLike.  An error is returned if a name is not a covariate in
+// the model, or if a name is repeated.
+func (rslt *GLMResults) ParamPositions(names []string) ([]int, error) {
+	return statmodel.ParamPositions(rslt.Model(), names)
+}
+
 // Summary displays a summary table of the model results.
 func (rslt *GLMResults) Summary() *GLMSummary {
 
@@ -1257,8 +3469,34 @@ func (rslt *GLMResults) Summary() *GLMSummary {
 	}
 }
 
+// boundLinpred clamps the linear predictor to [-lpBound, lpBound], if a
+// bound was set in the configuration.
+func (model *GLM) boundLinpred(lp []float64) {
+	if model.lpBound <= 0 {
+		return
+	}
+	for i, v := range lp {
+		lp[i] = math.Max(-model.lpBound, math.Min(model.lpBound, v))
+	}
+}
+
+// zeroClamped sets x[i] to zero for the observations whose linear
+// predictor lp[i] is at the bound, since the log-likelihood does not
+// depend on the coefficients through these observations.
+func (model *GLM) zeroClamped(lp, x []float64) {
+	if model.lpBound <= 0 {
+		return
+	}
+	for i, v := range lp {
+		if math.Abs(v) >= model.lpBound {
+			x[i] = 0
+		}
+	}
+}
+
 // LinearPredictor returns the linear combination of the model covariates based
-// on the provided parameter vector.  The provided slice is used if it is large
+// on the provided parameter vector, clamped to the bound on the linear
+// predictor if one was set in the configuration.  The provided slice is used if it is large
 // enough, otherwise a new slice is allocated.  The linear predictor is returned.
 func (model *GLM) LinearPredictor(params *GLMParams, lp []float64) []float64 {
 
@@ -1285,6 +3523,7 @@ func (model *GLM) LinearPredictor(params *GLMParams, lp []float64) []float64 {
 			lp[i] += coeff[j] * float64(xda[i])
 		}
 	}
+	model.boundLinpred(lp)
 
 	return lp
 }
@@ -1298,12 +3537,469 @@ func (rslt *GLMResults) LinearPredictor(lp []float64) []float64 {
 	return model.LinearPredictor(params, nil)
 }
 
+// predictData returns the data to use for prediction.  If da is nil, the
+// data used to fit the model are returned.  Otherwise da must have either
+// the columns of the dataset used to construct the model, or the columns
+// of the model's data, which in addition contain the values of any
+// WeightVec, OffsetVec and DispersionVec.  In the former case, the
+// additional columns are added as zeros, so an offset that was given as
+// OffsetVec is not included in predictions for new data.
+func (model *GLM) predictData(da [][]statmodel.Dtype) [][]statmodel.Dtype {
+
+	if da == nil {
+		return model.data
+	}
+	if len(da) != model.nuser || len(da) == len(model.data) {
+		return da
+	}
+
+	var n int
+	if len(da) > 0 {
+		n = len(da[0])
+	}
+	z := make([]statmodel.Dtype, n)
+	ext := append([][]statmodel.Dtype(nil), da...)
+	for len(ext) < len(model.data) {
+		ext = append(ext, z)
+	}
+
+	return ext
+}
+
+// FittedValues returns the fitted linear predictor for the given data,
+// excluding any offset.  If da is nil, the fitted values are for the data
+// used to fit the model.  The columns holding the values of WeightVec,
+// OffsetVec and DispersionVec may be omitted from da, see predictData.
+func (rslt *GLMResults) FittedValues(da [][]statmodel.Dtype) []float64 {
+	return rslt.BaseResults.FittedValues(rslt.Model().(*GLM).predictData(da))
+}
+
+// PredictMean returns the predicted means for the given data, which must
+// have the same columns as the data used to fit the model.  If da is nil,
+// the predictions are for the data used to fit the model.  If the model
+// has an offset, the offset values are taken from da.  The columns
+// holding the values of WeightVec, OffsetVec and DispersionVec may be
+// omitted from da, see predictData.
+func (rslt *GLMResults) PredictMean(da [][]statmodel.Dtype) []float64 {
+
+	model := rslt.Model().(*GLM)
+	da = model.predictData(da)
+
+	mn := rslt.FittedValues(da)
+	if model.offsetpos != -1 {
+		off := da[model.offsetpos]
+		for i := range mn {
+			mn[i] += float64(off[i])
+		}
+	}
+	model.boundLinpred(mn)
+	model.link.InvLink(mn, mn)
+
+	return mn
+}
+
+// Predict returns the predicted means for the given data, as in
+// PredictMean.  It implements the statmodel.Predictor interface.
+func (rslt *GLMResults) Predict(da [][]statmodel.Dtype) []float64 {
+	return rslt.PredictMean(da)
+}
+
+// DefaultPredictBlock is the default number of rows that PredictMeanBatch
+// processes at a time.
+const DefaultPredictBlock = 4096
+
+// PredictMeanBatch returns the predicted means for the given data, like
+// PredictMean, but processes the rows in blocks of the given size so that
+// the working storage for each block remains in cache.  This is faster
+// than PredictMean for scoring large datasets.  If the provided slice mn
+// is large enough to hold the result it is used, otherwise a new slice is
+// allocated, so repeated calls with the same mn make no allocations.  If
+// blocksize is not positive, DefaultPredictBlock is used.
+func (rslt *GLMResults) PredictMeanBatch(da [][]statmodel.Dtype, mn []float64, blocksize int) []float64 {
+
+	model := rslt.Model().(*GLM)
+	params := rslt.Params()
+
+	da = model.predictData(da)
+	if blocksize <= 0 {
+		blocksize = DefaultPredictBlock
+	}
+
+	nobs := len(da[0])
+	if cap(mn) < nobs {
+		mn = make([]float64, nobs)
+	} else {
+		mn = mn[0:nobs]
+	}
+
+	for i0 := 0; i0 < nobs; i0 += blocksize {
+		i1 := i0 + blocksize
+		if i1 > nobs {
+			i1 = nobs
+		}
+		blk := mn[i0:i1]
+
+		if model.offsetpos != -1 {
+			for i, v := range da[model.offsetpos][i0:i1] {
+				blk[i] = float64(v)
+			}
+		} else {
+			zero(blk)
+		}
+
+		for j, k := range model.xpos {
+			c := params[j]
+			for i, v := range da[k][i0:i1] {
+				blk[i] += c * float64(v)
+			}
+		}
+
+		model.boundLinpred(blk)
+		model.link.InvLink(blk, blk)
+	}
+
+	return mn
+}
+
+// PredictCounts returns predicted success probabilities and predicted
+// success counts for a binomial GLM, using the given data, which must
+// have the same columns as the data used to fit the model.  The number
+// of trials for each observation in da is given by trials, and the
+// predicted counts are the products of the number of trials and the
+// predicted probabilities.  If da is nil, the predictions are for the
+// data used to fit the model.
+func (rslt *GLMResults) PredictCounts(da [][]statmodel.Dtype, trials []float64) ([]float64, []float64, error) {
+
+	model := rslt.Model().(*GLM)
+
+	if model.fam.TypeCode != BinomialFamily {
+		return nil, nil, fmt.Errorf("PredictCounts is only available for the binomial family")
+	}
+
+	da = model.predictData(da)
+
+	if len(trials) != len(da[0]) {
+		msg := fmt.Sprintf("Length of trials (%d) does not match the number of observations (%d)\n",
+			len(trials), len(da[0]))
+		return nil, nil, fmt.Errorf(msg)
+	}
+
+	prob := rslt.PredictMean(da)
+	counts := make([]float64, len(prob))
+	for i, p := range prob {
+		counts[i] = trials[i] * p
+	}
+
+	return prob, counts, nil
+}
+
+// MeanInterval determines how confidence intervals for predicted means
+// are constructed.
+type MeanInterval int
+
+const (
+	// MeanIntervalLink intervals are obtained by applying the inverse
+	// link function to the limits of the Wald interval for the linear
+	// predictor.  These intervals are asymmetric, and always lie within
+	// the range of the mean.
+	MeanIntervalLink MeanInterval = iota
+
+	// MeanIntervalDelta intervals are Wald intervals on the response
+	// scale, mu +/- z*se(mu), where se(mu) is obtained by the delta
+	// method.  These intervals are symmetric, and may extend beyond the
+	// range of the mean near its boundaries.
+	MeanIntervalDelta
+)
+
+// MeanCI contains predicted means, with their standard errors and
+// confidence limits.
+type MeanCI struct {
+
+	// The predicted means
+	Mean []float64
+
+	// The delta-method standard errors of the predicted means,
+	// |d mu / d eta| * se(eta)
+	StdErr []float64
+
+	// The lower and upper confidence limits
+	LCB []float64
+	UCB []float64
+}
+
+// PredictMeanCI returns the predicted means for the given data, along with
+// their standard errors and the limits of confidence intervals with the
+// given coverage probability, constructed as specified by method.  The
+// data must have the same columns as the data used to fit the model, and
+// if da is nil, the predictions are for the data used to fit the model.
+// Any offset is taken from da, and is treated as known.  An error is
+// returned if the covariance matrix of the parameters is not available.
+func (rslt *GLMResults) PredictMeanCI(da [][]statmodel.Dtype, level float64, method MeanInterval) (*MeanCI, error) {
+
+	model := rslt.Model().(*GLM)
+
+	vcov := rslt.VCov()
+	if vcov == nil {
+		return nil, fmt.Errorf("Standard errors are not available")
+	}
+	if method != MeanIntervalLink && method != MeanIntervalDelta {
+		msg := fmt.Sprintf("PredictMeanCI: unknown interval method %d\n", method)
+		return nil, fmt.Errorf(msg)
+	}
+
+	da = model.predictData(da)
+
+	lp := rslt.FittedValues(da)
+	if model.offsetpos != -1 {
+		for i, v := range da[model.offsetpos] {
+			lp[i] += float64(v)
+		}
+	}
+	model.boundLinpred(lp)
+
+	// The standard errors of the linear predictor
+	p := len(model.xpos)
+	selp := make([]float64, len(lp))
+	xr := make([]float64, p)
+	for i := range lp {
+		for j, k := range model.xpos {
+			xr[j] = float64(da[k][i])
+		}
+		var q float64
+		for j1 := range xr {
+			for j2 := range xr {
+				q += xr[j1] * vcov[j1*p+j2] * xr[j2]
+			}
+		}
+		selp[i] = math.Sqrt(q)
+	}
+
+	n := len(lp)
+	ci := &MeanCI{
+		Mean:   make([]float64, n),
+		StdErr: make([]float64, n),
+		LCB:    make([]float64, n),
+		UCB:    make([]float64, n),
+	}
+
+	model.link.InvLink(lp, ci.Mean)
+	lderiv := make([]float64, n)
+	model.link.Deriv(ci.Mean, lderiv)
+
+	z := distuv.UnitNormal.Quantile((1 + level) / 2)
+	for i := range lp {
+		ci.StdErr[i] = selp[i] / math.Abs(lderiv[i])
+		switch method {
+		case MeanIntervalDelta:
+			ci.LCB[i] = ci.Mean[i] - z*ci.StdErr[i]
+			ci.UCB[i] = ci.Mean[i] + z*ci.StdErr[i]
+		case MeanIntervalLink:
+			ci.LCB[i] = lp[i] - z*selp[i]
+			ci.UCB[i] = lp[i] + z*selp[i]
+		}
+	}
+
+	if method == MeanIntervalLink {
+		model.link.InvLink(ci.LCB, ci.LCB)
+		model.link.InvLink(ci.UCB, ci.UCB)
+
+		// Decreasing links, e.g. the reciprocal link, reverse the limits
+		for i := range ci.LCB {
+			if ci.LCB[i] > ci.UCB[i] {
+				ci.LCB[i], ci.UCB[i] = ci.UCB[i], ci.LCB[i]
+			}
+		}
+	}
+
+	return ci, nil
+}
+
+// GroupTotals contains the totals of the predicted means within groups,
+// with their standard errors.
+type GroupTotals struct {
+
+	// The distinct group labels, in increasing order
+	Groups []statmodel.Dtype
+
+	// The sums of the predicted means within each group
+	Total []float64
+
+	// The delta-method standard errors of the totals
+	StdErr []float64
+}
+
+// PredictGroupTotals returns the sums of the predicted means within the
+// groups defined by the labels in groups, e.g. the predicted number of
+// events in each region for a Poisson model.  The data must have the same
+// columns as the data used to fit the model, and if da is nil, the
+// predictions are for the data used to fit the model.  Any offset is
+// taken from da.  The standard error of each total is obtained by the
+// delta method, as sqrt(d' V d), where V is the covariance matrix of the
+// parameters and d is the sum over the group of the gradients of the
+// predicted means with respect to the parameters, so the standard errors
+// account for the correlations among the predictions.  An error is
+// returned if groups does not have one label per row of da, or if the
+// covariance matrix of the parameters is not available.
+func (rslt *GLMResults) PredictGroupTotals(da [][]statmodel.Dtype, groups []statmodel.Dtype) (*GroupTotals, error) {
+
+	model := rslt.Model().(*GLM)
+
+	vcov := rslt.VCov()
+	if vcov == nil {
+		return nil, fmt.Errorf("Standard errors are not available")
+	}
+
+	da = model.predictData(da)
+	mn := rslt.PredictMean(da)
+	if len(groups) != len(mn) {
+		msg := fmt.Sprintf("PredictGroupTotals: groups has length %d, but there are %d observations\n", len(groups), len(mn))
+		return nil, fmt.Errorf(msg)
+	}
+
+	lderiv := make([]float64, len(mn))
+	model.link.Deriv(mn, lderiv)
+
+	gix := make(map[statmodel.Dtype]int)
+	var labels []statmodel.Dtype
+	for _, g := range groups {
+		if _, ok := gix[g]; !ok {
+			gix[g] = 0
+			labels = append(labels, g)
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i] < labels[j] })
+	for k, g := range labels {
+		gix[g] = k
+	}
+
+	// The totals, and the gradients of the totals with respect to the
+	// parameters
+	p := len(model.xpos)
+	gt := &GroupTotals{
+		Groups: labels,
+		Total:  make([]float64, len(labels)),
+		StdErr: make([]float64, len(labels)),
+	}
+	grad := make([]float64, len(labels)*p)
+	for i, g := range groups {
+		k := gix[g]
+		gt.Total[k] += mn[i]
+		for j, c := range model.xpos {
+			grad[k*p+j] += float64(da[c][i]) / lderiv[i]
+		}
+	}
+
+	for k := range labels {
+		d := grad[k*p : (k+1)*p]
+		var v float64
+		for j1 := range d {
+			for j2 := range d {
+				v += d[j1] * vcov[j1*p+j2] * d[j2]
+			}
+		}
+		gt.StdErr[k] = math.Sqrt(v)
+	}
+
+	return gt, nil
+}
+
+// RepresentativeData returns data for predictions at representative
+// covariate values, in which the variable named vname takes the values
+// in grid, and the other covariates are held fixed.  The fixed value of
+// a covariate is taken from base if it is present there, and is
+// otherwise the mean of the covariate in the data used to fit the
+// model.  The offset, if the model has one, is handled in the same way.
+// The returned data have the same columns as the data used to fit the
+// model, with one row per grid value, so they can be passed to
+// PredictMean or PredictMeanCI.  The columns that are not used for
+// prediction, e.g. the response, are set to zero.
+func (rslt *GLMResults) RepresentativeData(vname string, grid []float64, base map[string]float64) ([][]statmodel.Dtype, error) {
+
+	model := rslt.Model().(*GLM)
+
+	use := make(map[int]bool)
+	for _, k := range model.xpos {
+		use[k] = true
+	}
+	if model.offsetpos != -1 {
+		use[model.offsetpos] = true
+	}
+
+	vpos := -1
+	for _, k := range model.xpos {
+		if model.varnames[k] == vname {
+			vpos = k
+		}
+	}
+	if vpos == -1 {
+		msg := fmt.Sprintf("RepresentativeData: '%s' is not a covariate in the model\n", vname)
+		return nil, fmt.Errorf(msg)
+	}
+
+	pos := make(map[string]int)
+	for k := range use {
+		pos[model.varnames[k]] = k
+	}
+	for na := range base {
+		if _, ok := pos[na]; !ok {
+			msg := fmt.Sprintf("RepresentativeData: '%s' is not a covariate or offset in the model\n", na)
+			return nil, fmt.Errorf(msg)
+		}
+	}
+
+	da := make([][]statmodel.Dtype, len(model.data))
+	for k := range da {
+		da[k] = make([]statmodel.Dtype, len(grid))
+		if !use[k] {
+			continue
+		}
+		if k == vpos {
+			for i, v := range grid {
+				da[k][i] = statmodel.Dtype(v)
+			}
+			continue
+		}
+
+		v, ok := base[model.varnames[k]]
+		if !ok {
+			for _, x := range model.data[k] {
+				v += float64(x)
+			}
+			v /= float64(model.NumObs())
+		}
+		for i := range da[k] {
+			da[k][i] = statmodel.Dtype(v)
+		}
+	}
+
+	return da, nil
+}
+
+// AdjustedPredictions returns the predicted means, with confidence
+// intervals, at the representative covariate values obtained from
+// RepresentativeData, i.e. with the variable named vname taking the
+// values in grid and the other covariates held at the values in base,
+// or at their means.  The level and method arguments are as in
+// PredictMeanCI.  Plotting the predicted means against the grid gives
+// an adjusted prediction (effect) plot for the variable.
+func (rslt *GLMResults) AdjustedPredictions(vname string, grid []float64, base map[string]float64,
+	level float64, method MeanInterval) (*MeanCI, error) {
+
+	da, err := rslt.RepresentativeData(vname, grid, base)
+	if err != nil {
+		return nil, err
+	}
+
+	return rslt.PredictMeanCI(da, level, method)
+}
+
 // Mean returns the fitted mean of the GLM for the given parameter.  If
 // the provided slice 'mn' is large enough to hold the result, it is used,
 // otherwise a new slice is allocated.  The fitted means are returned.
 func (model *GLM) Mean(pa *GLMParams, mn []float64) []float64 {
 
 	mn = model.LinearPredictor(pa, mn)
+	model.boundLinpred(mn)
 	model.link.InvLink(mn, mn)
 
 	return mn
@@ -1318,6 +4014,55 @@ func (rslt *GLMResults) Mean() []float64 {
 	return model.Mean(params, nil)
 }
 
+// IDs returns the observation identifiers from the variable named by
+// IDVar in the configuration, or nil if no ID variable was specified.
+// The identifiers are in the same order as the per-observation results,
+// e.g. from Resid, PearsonResid, and Mean, so that these results can be
+// matched to the original observations after the data have been
+// filtered or subsetted.
+func (rslt *GLMResults) IDs() []statmodel.Dtype {
+	model := rslt.Model().(*GLM)
+	if model.idpos == -1 {
+		return nil
+	}
+	return model.data[model.idpos]
+}
+
+// FittedData returns a dataset containing the fitted values and residuals
+// of the observations used to fit the model, so that these can be used in
+// further analyses.  The dataset has variables named "fitted" (the fitted
+// means), "linpred" (the fitted linear predictor), "resid" (the response
+// residuals), "pearson_resid", and "deviance_resid".  If an ID variable was
+// specified in the configuration, it is included as the first variable,
+// under its original name, so that the rows can be matched to the
+// original observations.
+func (rslt *GLMResults) FittedData() statmodel.Dataset {
+
+	model := rslt.Model().(*GLM)
+
+	tod := func(x []float64) []statmodel.Dtype {
+		z := make([]statmodel.Dtype, len(x))
+		for i, v := range x {
+			z[i] = statmodel.Dtype(v)
+		}
+		return z
+	}
+
+	var data [][]statmodel.Dtype
+	var names []string
+	if ids := rslt.IDs(); ids != nil {
+		data = append(data, ids)
+		names = append(names, model.varnames[model.idpos])
+	}
+
+	mn := rslt.Mean()
+	data = append(data, tod(mn), tod(rslt.LinearPredictor(nil)), tod(rslt.Resid(nil)),
+		tod(rslt.PearsonResid(nil)), tod(rslt.devianceResid(mn)))
+	names = append(names, "fitted", "linpred", "resid", "pearson_resid", "deviance_resid")
+
+	return statmodel.NewDataset(data, names)
+}
+
 // Resid returns the residuals (observed minus fitted values) for the model,
 // at the given parameter vector.
 func (model *GLM) Resid(pa *GLMParams, resid []float64) []float64 {
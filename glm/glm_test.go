@@ -5,13 +5,21 @@ This set of tests uses very small datasets.  The results for comparison are take
 package glm
 
 import (
+	"fmt"
 	"log"
 	"math"
+	"math/rand"
 	"os"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/kshedden/statmodel/statmodel"
+	"gonum.org/v1/gonum/diff/fd"
 	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/distuv"
 )
 
 func scalarClose(x, y, eps float64) bool {
@@ -702,3 +710,2971 @@ func TestSetLink(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckLink(t *testing.T) {
+
+	for _, tc := range []struct {
+		family  FamilyType
+		link    LinkType
+		warning bool
+	}{
+		{PoissonFamily, LogLink, false},
+		{PoissonFamily, IdentityLink, true},
+		{GammaFamily, RecipLink, false},
+		{GammaFamily, IdentityLink, true},
+		{GammaFamily, LogitLink, true},
+		{BinomialFamily, CloglogLink, false},
+		{GaussianFamily, LogLink, false},
+	} {
+		for _, lc := range []LinkCheck{LinkCheckWarn, LinkCheckStrict, LinkCheckOff} {
+			config := DefaultConfig()
+			config.Family = NewFamily(tc.family)
+			config.Link = NewLink(tc.link)
+			config.LinkCheck = lc
+
+			model, err := NewGLM(data4(), "y", []string{"x1", "x2"}, config)
+
+			switch lc {
+			case LinkCheckWarn:
+				if err != nil || (len(model.warnings) > 0) != tc.warning {
+					t.Logf("%v %v: unexpected warning status\n", tc.family, tc.link)
+					t.Fail()
+				}
+			case LinkCheckStrict:
+				if (err != nil) != tc.warning {
+					t.Logf("%v %v: unexpected error status\n", tc.family, tc.link)
+					t.Fail()
+				}
+			case LinkCheckOff:
+				if err != nil || len(model.warnings) > 0 {
+					t.Fail()
+				}
+			}
+		}
+	}
+
+	// The means are only constrained if this is requested
+	for _, constrain := range []bool{false, true} {
+		config := DefaultConfig().WithFamily(NewFamily(GammaFamily)).WithLink(NewLink(IdentityLink)).
+			WithConstrainMean(constrain)
+		model, err := NewGLM(data4(), "y", []string{"x1", "x2"}, config)
+		if err != nil {
+			panic(err)
+		}
+		mn := []float64{-1, 2}
+		model.clampMean(mn)
+		if (mn[0] > 0) != constrain || mn[1] != 2 {
+			t.Fail()
+		}
+	}
+}
+
+func TestExpParams(t *testing.T) {
+
+	for _, tc := range []struct {
+		family FamilyType
+		label  string
+	}{
+		{BinomialFamily, "OR"},
+		{PoissonFamily, "RR"},
+		{GaussianFamily, ""},
+	} {
+		config := DefaultConfig()
+		config.Family = NewFamily(tc.family)
+		config.WeightVar = "w"
+		model, err := NewGLM(data2(), "y", []string{"x1", "x2"}, config)
+		if err != nil {
+			panic(err)
+		}
+		result := model.Fit()
+
+		ep, err := result.ExpParams(0.95)
+		if tc.label == "" {
+			if err == nil {
+				t.Fail()
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ep.Label != tc.label {
+			t.Fail()
+		}
+
+		for j, p := range result.Params() {
+			se := result.StdErr()[j]
+			if !scalarClose(ep.Estimate[j], math.Exp(p), 1e-10) ||
+				!scalarClose(ep.LCB[j], math.Exp(p-1.959964*se), 1e-5) ||
+				!scalarClose(ep.UCB[j], math.Exp(p+1.959964*se), 1e-5) {
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestCoefPlot(t *testing.T) {
+
+	config := DefaultConfig()
+	config.Family = NewFamily(BinomialFamily)
+	model, err := NewGLM(data2(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	lcb, ucb := result.ConfInt(0.9)
+	ep, err := result.ExpParams(0.9)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, exp := range []bool{false, true} {
+		rows, err := result.CoefPlot(0.9, exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("expected 2 rows, got %d", len(rows))
+		}
+		for j, row := range rows {
+			est, lo, hi := result.Params()[j], lcb[j], ucb[j]
+			if exp {
+				est, lo, hi = ep.Estimate[j], ep.LCB[j], ep.UCB[j]
+			}
+			if row.Name != result.Names()[j] || row.Estimate != est ||
+				row.Lower != lo || row.Upper != hi || !(lo < est && est < hi) {
+				t.Fail()
+			}
+		}
+	}
+
+	// Exponentiation is not available for the identity link
+	config.Family = NewFamily(GaussianFamily)
+	model, err = NewGLM(data2(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	if _, err := model.Fit().CoefPlot(0.9, true); err == nil {
+		t.Fail()
+	}
+}
+
+func TestPredict(t *testing.T) {
+
+	config := DefaultConfig()
+	config.Family = NewFamily(PoissonFamily)
+	config.OffsetVar = "off"
+	model, err := NewGLM(data5(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	// Predictions for the training data agree with the fitted means.
+	if !floats.EqualApprox(result.PredictMean(nil), result.Mean(), 1e-10) {
+		t.Fail()
+	}
+
+	// Predictions for new data
+	da := [][]statmodel.Dtype{{0, 0}, {1, 1}, {2, 0}, {0, 1}, {1, 1}}
+	pa := result.Params()
+	mn := []float64{math.Exp(pa[0] + 2*pa[1]), math.Exp(pa[0] + 1)}
+	if !floats.EqualApprox(result.PredictMean(da), mn, 1e-10) {
+		t.Fail()
+	}
+
+	// The results can be used through the Predictor interface
+	var pr statmodel.Predictor = result
+	if !floats.EqualApprox(pr.Predict(da), mn, 1e-10) {
+		t.Fail()
+	}
+
+	// PredictCounts is only for the binomial family
+	if _, _, err := result.PredictCounts(da, []float64{1, 1}); err == nil {
+		t.Fail()
+	}
+
+	config = DefaultConfig()
+	config.Family = NewFamily(BinomialFamily)
+	model, err = NewGLM(data2(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result = model.Fit()
+
+	da = [][]statmodel.Dtype{{0, 0}, {1, 1}, {2, -1}, {0, 0}, {0, 0}}
+	prob, counts, err := result.PredictCounts(da, []float64{10, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !scalarClose(counts[0], 10*prob[0], 1e-10) || !scalarClose(counts[1], 5*prob[1], 1e-10) {
+		t.Fail()
+	}
+
+	if _, _, err := result.PredictCounts(da, []float64{10}); err == nil {
+		t.Fail()
+	}
+}
+
+func TestDevianceGoF(t *testing.T) {
+
+	// Poisson, the deviance is twice the difference between the
+	// saturated and fitted log-likelihoods.
+	config := DefaultConfig()
+	config.Family = NewFamily(PoissonFamily)
+	config.WeightVar = "w"
+	model, err := NewGLM(data4(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	da := data4().Data()
+	y := make([]float64, len(da[0]))
+	for i := range y {
+		y[i] = float64(da[0][i])
+	}
+	llsat := poissonLogLike(da[0], y, da[4], 1, true)
+	dev, df, pv := result.DevianceGoF()
+	if !scalarClose(dev, 2*(llsat-result.LogLike()), 1e-8) {
+		t.Fail()
+	}
+	if df != 15 {
+		t.Fail()
+	}
+	if !scalarClose(pv, distuv.ChiSquared{K: 15}.Survival(dev), 1e-10) {
+		t.Fail()
+	}
+
+	// The Pearson statistic shares the residual degrees of freedom
+	gof := result.GoFStats()
+	mn := result.Mean()
+	var chi2 float64
+	for i := range y {
+		chi2 += float64(da[4][i]) * (y[i] - mn[i]) * (y[i] - mn[i]) / mn[i]
+	}
+	if gof.Deviance != dev || gof.DF != df || gof.DeviancePValue != pv ||
+		!scalarClose(gof.Pearson, chi2, 1e-8) ||
+		!scalarClose(gof.PearsonPValue, distuv.ChiSquared{K: 15}.Survival(chi2), 1e-10) {
+		t.Fail()
+	}
+	if !strings.Contains(result.Summary().String(), "Pearson chi^2") {
+		t.Fail()
+	}
+
+	// Binary binomial, the saturated log-likelihood is zero.
+	config = DefaultConfig()
+	config.Family = NewFamily(BinomialFamily)
+	model, err = NewGLM(data2(), "y", []string{"x1", "x2", "x3"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result = model.Fit()
+	if !scalarClose(result.Deviance(), -2*result.LogLike(), 1e-8) {
+		t.Fail()
+	}
+}
+
+func TestEffectiveDF(t *testing.T) {
+
+	fit := func(l1, l2 float64) *GLMResults {
+		config := DefaultConfig()
+		config.Family = NewFamily(BinomialFamily)
+		if l1 > 0 {
+			config.L1Penalty = map[string]float64{"x1": l1, "x2": l1, "x3": l1}
+		}
+		if l2 > 0 {
+			config.L2Penalty = map[string]float64{"x1": l2, "x2": l2, "x3": l2}
+		}
+		model, err := NewGLM(data2(), "y", []string{"x1", "x2", "x3"}, config)
+		if err != nil {
+			panic(err)
+		}
+		return model.Fit()
+	}
+
+	// No penalty
+	result := fit(0, 0)
+	if result.EffectiveDF() != 3 {
+		t.Fail()
+	}
+	if !scalarClose(result.AIC(), -2*result.LogLike()+6, 1e-10) {
+		t.Fail()
+	}
+	if !scalarClose(result.BIC(), -2*result.LogLike()+3*math.Log(7), 1e-10) {
+		t.Fail()
+	}
+
+	// Weak and strong ridge penalties
+	df1 := fit(0, 1e-8).EffectiveDF()
+	df2 := fit(0, 0.1).EffectiveDF()
+	df3 := fit(0, 100).EffectiveDF()
+	if !scalarClose(df1, 3, 1e-4) || df2 >= 3 || df2 <= df3 || df3 > 0.1 {
+		t.Logf("%v %v %v\n", df1, df2, df3)
+		t.Fail()
+	}
+
+	// Lasso
+	result = fit(0.05, 0)
+	var nz float64
+	for _, v := range result.Params() {
+		if v != 0 {
+			nz++
+		}
+	}
+	if result.EffectiveDF() != nz {
+		t.Fail()
+	}
+}
+
+func TestScaleEstimator(t *testing.T) {
+
+	for _, se := range []ScaleEstimator{ScalePearson, ScaleDeviance} {
+		config := DefaultConfig()
+		config.Family = NewFamily(GammaFamily)
+		config.Link = NewLink(LogLink)
+		config.WeightVar = "w"
+		config.ScaleEstimator = se
+		model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+		if err != nil {
+			panic(err)
+		}
+		result := model.Fit()
+
+		chi2, ws := model.pearsonChi2(result.Params())
+		scale := chi2 / (ws - 3)
+		if se == ScaleDeviance {
+			scale = result.Deviance() / (ws - 3)
+		}
+		if !scalarClose(result.Scale(), scale, 1e-10) {
+			t.Fail()
+		}
+
+		if !strings.Contains(result.Summary().String(), "Scale estimator: "+se.String()) {
+			t.Fail()
+		}
+	}
+}
+
+func TestConfigBuilder(t *testing.T) {
+
+	config := DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithLink(NewLink(LogLink)).
+		WithWeight("w").WithOffset("off")
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	model, err := NewGLM(data5(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.weightpos != 4 || model.offsetpos != 3 {
+		t.Fail()
+	}
+
+	for _, config := range []*Config{
+		DefaultConfig().WithFamily(nil),
+		DefaultConfig().WithWeight(""),
+		DefaultConfig().WithWeight("w").WithOffset("w"),
+		DefaultConfig().WithFitMethod("newton"),
+		DefaultConfig().WithL2Penalty(map[string]float64{"x1": -1}),
+	} {
+		if config.Validate() == nil {
+			t.Fail()
+		}
+		if _, err := NewGLM(data5(), "y", []string{"x1", "x2"}, config); err == nil {
+			t.Fail()
+		}
+	}
+}
+
+func TestNegBinomTheta(t *testing.T) {
+
+	var results []*GLMResults
+	for _, fam := range []*Family{NewNegBinomFamily(0.5, NewLink(LogLink)), NewNegBinomTheta(2, NewLink(LogLink))} {
+		config := DefaultConfig()
+		config.Family = fam
+		config.Link = NewLink(LogLink)
+		model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+		if err != nil {
+			panic(err)
+		}
+		results = append(results, model.Fit())
+	}
+
+	if !floats.EqualApprox(results[0].Params(), results[1].Params(), 1e-10) {
+		t.Fail()
+	}
+	if !floats.EqualApprox(results[0].StdErr(), results[1].StdErr(), 1e-10) {
+		t.Fail()
+	}
+	if !scalarClose(results[0].LogLike(), results[1].LogLike(), 1e-10) {
+		t.Fail()
+	}
+}
+
+func TestEvalAt(t *testing.T) {
+
+	config := DefaultConfig()
+	config.Family = NewFamily(PoissonFamily)
+	model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	// At the MLE, the score is zero and the Hessian recovers the
+	// covariance matrix.
+	ll, score, hess := result.EvalAt(result.Params())
+	if !scalarClose(ll, result.LogLike(), 1e-8) {
+		t.Fail()
+	}
+	if !floats.EqualApprox(score, make([]float64, 3), 1e-6) {
+		t.Fail()
+	}
+	hm := mat.NewDense(3, 3, hess)
+	var vc mat.Dense
+	if err := vc.Inverse(hm); err != nil {
+		t.Fatal(err)
+	}
+	vc.Scale(-1, &vc)
+	if !floats.EqualApprox(vc.RawMatrix().Data, result.VCov(), 1e-6) {
+		t.Fail()
+	}
+
+	// The log-likelihood is smaller away from the MLE.
+	pa := make([]float64, 3)
+	copy(pa, result.Params())
+	pa[1] += 0.1
+	ll2, _, _ := result.EvalAt(pa)
+	if ll2 >= ll {
+		t.Fail()
+	}
+}
+
+func TestFinalScore(t *testing.T) {
+
+	for _, ridge := range []float64{0, 0.5} {
+		config := DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithWeight("w").WithRidge(ridge)
+		model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+		if err != nil {
+			panic(err)
+		}
+		result := model.Fit()
+
+		score := result.FinalScore()
+		if len(score) != 3 || floats.Norm(score, math.Inf(1)) > 1e-6 {
+			t.Errorf("ridge=%v score=%v", ridge, score)
+		}
+	}
+}
+
+func TestObservedInfo(t *testing.T) {
+
+	// The Gamma family with a log link is not canonical, so the
+	// observed and expected information differ.
+	var results []*GLMResults
+	for _, obs := range []bool{false, true} {
+		config := DefaultConfig().WithFamily(NewFamily(GammaFamily)).WithLink(NewLink(LogLink)).WithWeight("w").WithObservedInfo(obs)
+		model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+		if err != nil {
+			panic(err)
+		}
+		result := model.Fit()
+
+		ht := statmodel.ExpHess
+		if obs {
+			ht = statmodel.ObsHess
+		}
+		if model.Information() != ht {
+			t.Fail()
+		}
+		if !strings.Contains(result.Summary().String(), fmt.Sprintf("Information: %s", ht)) {
+			t.Fail()
+		}
+
+		vcov, err := statmodel.GetVcovHess(model, &GLMParams{result.Params(), 1}, ht)
+		if err != nil {
+			t.Fatal(err)
+		}
+		floats.Scale(result.Scale(), vcov)
+		if !floats.EqualApprox(vcov, result.VCov(), 1e-8) {
+			t.Fail()
+		}
+		results = append(results, result)
+	}
+
+	// The point estimates agree but the standard errors do not.
+	if !floats.EqualApprox(results[0].Params(), results[1].Params(), 1e-8) {
+		t.Fail()
+	}
+	if floats.EqualApprox(results[0].StdErr(), results[1].StdErr(), 1e-4) {
+		t.Fail()
+	}
+}
+
+func TestPredictMeanBatch(t *testing.T) {
+
+	config := DefaultConfig()
+	config.Family = NewFamily(PoissonFamily)
+	config.OffsetVar = "off"
+	model, err := NewGLM(data5(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	mn := result.PredictMean(nil)
+	for _, bs := range []int{0, 1, 2, 3, 100} {
+		if !floats.EqualApprox(result.PredictMeanBatch(nil, nil, bs), mn, 1e-12) {
+			t.Fail()
+		}
+	}
+
+	// The provided slice is reused
+	buf := make([]float64, 0, 100)
+	mn2 := result.PredictMeanBatch(nil, buf, 2)
+	if &mn2[0] != &buf[:1][0] {
+		t.Fail()
+	}
+}
+
+// benchData returns a large dataset with the given number of observations,
+// for benchmarking prediction.
+func benchData(n, p int) ([][]statmodel.Dtype, []string) {
+
+	rng := rand.New(rand.NewSource(3))
+	da := make([][]statmodel.Dtype, p+1)
+	names := make([]string, p+1)
+	names[0] = "y"
+	da[0] = make([]statmodel.Dtype, n)
+	for j := 1; j <= p; j++ {
+		names[j] = fmt.Sprintf("x%d", j)
+		da[j] = make([]statmodel.Dtype, n)
+		for i := range da[j] {
+			da[j][i] = statmodel.Dtype(rng.NormFloat64())
+		}
+	}
+	for i := range da[0] {
+		da[0][i] = statmodel.Dtype(rng.NormFloat64()) + da[1][i]
+	}
+
+	return da, names
+}
+
+func benchResult() (*GLMResults, [][]statmodel.Dtype) {
+
+	da, names := benchData(200000, 20)
+	model, err := NewGLM(statmodel.NewDataset(da, names), "y", names[1:], nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return model.Fit(), da
+}
+
+func BenchmarkPredictMean(b *testing.B) {
+
+	result, da := benchResult()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result.PredictMean(da)
+	}
+}
+
+func BenchmarkPredictMeanBatch(b *testing.B) {
+
+	result, da := benchResult()
+	var mn []float64
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mn = result.PredictMeanBatch(da, mn, 0)
+	}
+}
+
+// benchLogistic returns a logistic regression model for a large
+// dataset, along with parameter values at which to evaluate it.  If
+// generic is true, the model uses a copy of the binomial variance
+// function, so that the general code path is used rather than the
+// logistic fast path.
+func benchLogistic(generic bool) (*GLM, *GLMParams) {
+
+	da, names := benchData(200000, 10)
+	for i := range da[0] {
+		if da[0][i] > 0 {
+			da[0][i] = 1
+		} else {
+			da[0][i] = 0
+		}
+	}
+	config := DefaultConfig().WithFamily(NewFamily(BinomialFamily))
+	if generic {
+		vari := *NewVariance(BinomialVar)
+		config = config.WithVarFunc(&vari)
+	}
+	model, err := NewGLM(statmodel.NewDataset(da, names), "y", names[1:], config)
+	if err != nil {
+		panic(err)
+	}
+
+	params := make([]float64, 10)
+	params[0] = 1
+
+	return model, &GLMParams{params, 1}
+}
+
+func BenchmarkLogistic(b *testing.B) {
+
+	for _, generic := range []bool{false, true} {
+		model, pa := benchLogistic(generic)
+		p := model.NumParams()
+		score := make([]float64, p)
+		hess := make([]float64, p*p)
+		name := "fast"
+		if generic {
+			name = "generic"
+		}
+
+		b.Run(name+"/LogLike", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				model.LogLike(pa, false)
+			}
+		})
+		b.Run(name+"/Score", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				model.Score(pa, score)
+			}
+		})
+		b.Run(name+"/Hessian", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				model.Hessian(pa, statmodel.ExpHess, hess)
+			}
+		})
+	}
+}
+
+// BenchmarkHessian evaluates the observed and expected Hessians, in
+// full and packed storage, for a Poisson regression with many
+// covariates and case weights.
+func BenchmarkHessian(b *testing.B) {
+
+	da, names := benchData(200000, 20)
+	w := make([]statmodel.Dtype, len(da[0]))
+	for i := range da[0] {
+		da[0][i] = statmodel.Dtype(math.Floor(math.Abs(float64(da[0][i]))))
+		w[i] = statmodel.Dtype(1 + i%3)
+	}
+	da = append(da, w)
+	names = append(names, "w")
+
+	config := DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithWeight("w")
+	model, err := NewGLM(statmodel.NewDataset(da, names), "y", names[1:len(names)-1], config)
+	if err != nil {
+		panic(err)
+	}
+
+	p := model.NumParams()
+	params := make([]float64, p)
+	params[0] = 0.1
+	pa := &GLMParams{params, 1}
+	hess := make([]float64, p*p)
+	packed := make([]float64, p*(p+1)/2)
+
+	for _, ht := range []statmodel.HessType{statmodel.ObsHess, statmodel.ExpHess} {
+		name := "observed"
+		if ht == statmodel.ExpHess {
+			name = "expected"
+		}
+		b.Run(name+"/full", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				model.Hessian(pa, ht, hess)
+			}
+		})
+		b.Run(name+"/packed", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				model.PackedHessian(pa, ht, packed)
+			}
+		})
+	}
+}
+
+func TestHessianReproducible(t *testing.T) {
+
+	// The Hessian does not depend on the number of blocks that are
+	// processed concurrently.
+	da, names := benchData(5500, 5)
+	model, err := NewGLM(statmodel.NewDataset(da, names), "y", names[1:], DefaultConfig())
+	if err != nil {
+		panic(err)
+	}
+	p := model.NumParams()
+	pa := &GLMParams{make([]float64, p), 1}
+
+	nproc := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(nproc)
+
+	var hess [][]float64
+	for _, n := range []int{1, 2, 4} {
+		runtime.GOMAXPROCS(n)
+		h := make([]float64, p*p)
+		model.Hessian(pa, statmodel.ExpHess, h)
+		hess = append(hess, h)
+	}
+	if !floats.Equal(hess[0], hess[1]) || !floats.Equal(hess[0], hess[2]) {
+		t.Fail()
+	}
+}
+
+func TestLogisticFastPath(t *testing.T) {
+
+	// The fast path agrees with the general code path
+	fast, pa := benchLogistic(false)
+	generic, _ := benchLogistic(true)
+	if !fast.logitFast() || generic.logitFast() {
+		t.Fail()
+	}
+	p := fast.NumParams()
+
+	if !scalarClose(fast.LogLike(pa, false), generic.LogLike(pa, false), 1e-10) {
+		t.Fail()
+	}
+
+	s1, s2 := make([]float64, p), make([]float64, p)
+	fast.Score(pa, s1)
+	generic.Score(pa, s2)
+	if !floats.EqualApprox(s1, s2, 1e-8) {
+		t.Fail()
+	}
+
+	h1, h2 := make([]float64, p*p), make([]float64, p*p)
+	for _, ht := range []statmodel.HessType{statmodel.ExpHess, statmodel.ObsHess} {
+		fast.Hessian(pa, ht, h1)
+		generic.Hessian(pa, ht, h2)
+		if !floats.EqualApprox(h1, h2, 1e-8) {
+			t.Fail()
+		}
+	}
+}
+
+func TestGammaLogLike(t *testing.T) {
+
+	y := []statmodel.Dtype{0.5, 1.2, 3.1, 0.8}
+	mn := []float64{0.7, 1.5, 2.0, 1.1}
+	wt := []statmodel.Dtype{1, 2, 1, 3}
+
+	for _, scale := range []float64{0.3, 1, 2.5} {
+
+		// The exact log-likelihood is the weighted sum of gamma
+		// log densities, with shape 1/scale and mean mn.
+		var ll float64
+		for i := range y {
+			g := distuv.Gamma{Alpha: 1 / scale, Beta: 1 / (scale * mn[i])}
+			ll += float64(wt[i]) * g.LogProb(float64(y[i]))
+		}
+		if !scalarClose(gammaLogLike(y, mn, wt, scale, true), ll, 1e-10) {
+			t.Fail()
+		}
+	}
+}
+
+func TestCustomFamily(t *testing.T) {
+
+	// A custom family that reproduces the Poisson family
+	pois := NewFamily(PoissonFamily)
+	fam := NewCustomFamily("MyPoisson", func(m float64) float64 { return m }, pois.Deviance, pois.LogLike,
+		[]LinkType{LogLink, IdentityLink}, DispersionFixed)
+
+	var results []*GLMResults
+	for _, f := range []*Family{pois, fam} {
+		config := DefaultConfig()
+		config.Family = f
+		model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+		if err != nil {
+			panic(err)
+		}
+		results = append(results, model.Fit())
+	}
+
+	if !floats.EqualApprox(results[0].Params(), results[1].Params(), 1e-8) {
+		t.Fail()
+	}
+	if !floats.EqualApprox(results[0].StdErr(), results[1].StdErr(), 1e-8) {
+		t.Fail()
+	}
+	if !scalarClose(results[0].LogLike(), results[1].LogLike(), 1e-8) {
+		t.Fail()
+	}
+	if results[1].Scale() != 1 {
+		t.Fail()
+	}
+	if !strings.Contains(results[1].Summary().String(), "MyPoisson") {
+		t.Fail()
+	}
+
+	// Only the listed links are valid
+	config := DefaultConfig().WithFamily(fam).WithLink(NewLink(LogitLink)).WithLinkCheck(LinkCheckStrict)
+	if _, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config); err == nil {
+		t.Fail()
+	}
+
+	// The observed information uses the numerical derivative of the
+	// variance function.
+	gam := NewFamily(GammaFamily)
+	mygam := NewCustomFamily("MyGamma", func(m float64) float64 { return m * m }, gam.Deviance, gam.LogLike,
+		[]LinkType{LogLink}, DispersionFree)
+	results = results[:0]
+	for _, f := range []*Family{gam, mygam} {
+		config := DefaultConfig().WithFamily(f).WithLink(NewLink(LogLink)).WithWeight("w").WithObservedInfo(true)
+		model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+		if err != nil {
+			panic(err)
+		}
+		results = append(results, model.Fit())
+	}
+	if !floats.EqualApprox(results[0].StdErr(), results[1].StdErr(), 1e-6) {
+		t.Errorf("%v != %v", results[0].StdErr(), results[1].StdErr())
+	}
+}
+
+func TestCustomLink(t *testing.T) {
+
+	// The square root link, as a custom link
+	link := NewCustomLink("Sqrt", math.Sqrt, func(x float64) float64 { return x * x },
+		func(x float64) float64 { return 0.5 / math.Sqrt(x) })
+
+	var results []*GLMResults
+	for _, li := range []*Link{NewPowerLink(0.5), link} {
+		config := DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithLink(li)
+		model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+		if err != nil {
+			panic(err)
+		}
+		results = append(results, model.Fit())
+	}
+
+	if !floats.EqualApprox(results[0].Params(), results[1].Params(), 1e-8) {
+		t.Fail()
+	}
+	if !floats.EqualApprox(results[0].StdErr(), results[1].StdErr(), 1e-8) {
+		t.Fail()
+	}
+	if !strings.Contains(results[1].Summary().String(), "Sqrt") {
+		t.Fail()
+	}
+
+	// The numerical second derivative
+	mn := []float64{0.5, 1, 3, 10}
+	d2a := make([]float64, 4)
+	d2b := make([]float64, 4)
+	NewPowerLink(0.5).Deriv2(mn, d2a)
+	link.Deriv2(mn, d2b)
+	if !floats.EqualApprox(d2a, d2b, 1e-6) {
+		t.Fail()
+	}
+}
+
+func TestPowerLink(t *testing.T) {
+
+	mn := []float64{0.2, 0.5, 1, 2.5, 7}
+	n := len(mn)
+	for _, pw := range []float64{-2, -1, -0.5, 0, 1.0 / 3, 0.5, 2} {
+		link := NewPowerLink(pw)
+
+		// The inverse link inverts the link
+		lp := make([]float64, n)
+		mn2 := make([]float64, n)
+		link.Link(mn, lp)
+		link.InvLink(lp, mn2)
+		if !floats.EqualApprox(mn, mn2, 1e-10) {
+			t.Fail()
+		}
+
+		// Check the derivatives against finite differences
+		d1 := make([]float64, n)
+		d2 := make([]float64, n)
+		link.Deriv(mn, d1)
+		link.Deriv2(mn, d2)
+		h := 1e-6
+		mp := make([]float64, n)
+		mm := make([]float64, n)
+		for i := range mn {
+			mp[i] = mn[i] + h
+			mm[i] = mn[i] - h
+		}
+		lpp := make([]float64, n)
+		lpm := make([]float64, n)
+		link.Link(mp, lpp)
+		link.Link(mm, lpm)
+		d1p := make([]float64, n)
+		d1m := make([]float64, n)
+		link.Deriv(mp, d1p)
+		link.Deriv(mm, d1m)
+		for i := range mn {
+			nd1 := (lpp[i] - lpm[i]) / (2 * h)
+			nd2 := (d1p[i] - d1m[i]) / (2 * h)
+			if math.Abs(nd1-d1[i]) > 1e-5*math.Max(1, math.Abs(d1[i])) {
+				t.Logf("pw=%v: %v != %v\n", pw, nd1, d1[i])
+				t.Fail()
+			}
+			if math.Abs(nd2-d2[i]) > 1e-4*math.Max(1, math.Abs(d2[i])) {
+				t.Logf("pw=%v: %v != %v\n", pw, nd2, d2[i])
+				t.Fail()
+			}
+		}
+	}
+
+	// The power link is valid for the gamma family
+	config := DefaultConfig().WithFamily(NewFamily(GammaFamily)).WithLink(NewPowerLink(-0.5)).WithLinkCheck(LinkCheckStrict)
+	if _, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config); err != nil {
+		t.Fail()
+	}
+}
+
+func TestResidDF(t *testing.T) {
+
+	// Sum of weights is 17, with three covariates including an intercept
+	config := DefaultConfig().WithFamily(NewFamily(GammaFamily)).WithLink(NewLink(LogLink)).WithWeight("w")
+	model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+	if result.ResidDF() != 14 || result.ModelDF() != 2 {
+		t.Fail()
+	}
+
+	// The scale estimate uses the residual degrees of freedom
+	chi2, _ := model.pearsonChi2(result.Params())
+	if !scalarClose(result.Scale(), chi2/result.ResidDF(), 1e-10) {
+		t.Fail()
+	}
+
+	// No intercept
+	model, err = NewGLM(data4(), "y", []string{"x2", "x3"}, nil)
+	if err != nil {
+		panic(err)
+	}
+	result = model.Fit()
+	if result.ResidDF() != 5 || result.ModelDF() != 2 {
+		t.Fail()
+	}
+}
+
+func TestSummaryColor(t *testing.T) {
+
+	model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, nil)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	// Only the intercept is significant
+	var nsig int
+	for _, pv := range result.PValues() {
+		if pv < HighlightLevel {
+			nsig++
+		}
+	}
+	if nsig != 1 {
+		t.Fail()
+	}
+
+	if strings.Contains(result.Summary().String(), "\x1b[") {
+		t.Fail()
+	}
+	if strings.Count(result.Summary().SetColor(true).String(), "\x1b[32m") != nsig {
+		t.Fail()
+	}
+}
+
+func TestSummaryTranspose(t *testing.T) {
+
+	model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, nil)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	// The header row of the coefficient block holds the variable names
+	lines := strings.Split(result.Summary().SetTranspose(true).String(), "\n")
+	var found bool
+	for i, line := range lines {
+		f := strings.Fields(line)
+		if len(f) == 3 && f[0] == "x1" && f[1] == "x2" && f[2] == "x3" {
+			found = true
+			p := strings.Fields(lines[i+2])
+			if len(p) != 4 || p[0] != "Parameter" || p[1] != fmt.Sprintf("%.4f", result.Params()[0]) {
+				t.Errorf("unexpected parameter row: %s", lines[i+2])
+			}
+		}
+	}
+	if !found {
+		t.Fail()
+	}
+}
+
+func TestSummaryParams(t *testing.T) {
+
+	model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, nil)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	pos, err := result.ParamPositions([]string{"x3", "x1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pos) != 2 || pos[0] != 2 || pos[1] != 0 {
+		t.Fail()
+	}
+	if _, err := result.ParamPositions([]string{"x1", "x4"}); err == nil {
+		t.Fail()
+	}
+	if _, err := result.ParamPositions([]string{"x1", "x1"}); err == nil {
+		t.Fail()
+	}
+
+	// Only the selected coefficients appear, in the given order
+	var rows []string
+	for _, line := range strings.Split(result.Summary().SetParams("x3", "x1").String(), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 7 && strings.HasPrefix(f[0], "x") {
+			rows = append(rows, f[0])
+			j := map[string]int{"x1": 0, "x3": 2}[f[0]]
+			if f[1] != fmt.Sprintf("%.4f", result.Params()[j]) {
+				t.Errorf("unexpected row: %s", line)
+			}
+		}
+	}
+	if len(rows) != 2 || rows[0] != "x3" || rows[1] != "x1" {
+		t.Errorf("unexpected rows: %v", rows)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fail()
+			}
+		}()
+		result.Summary().SetParams("x5")
+	}()
+}
+
+func TestMetadata(t *testing.T) {
+
+	model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, nil)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	if len(result.Metadata()) != 0 {
+		t.Fail()
+	}
+	result.SetMetadata("dataset", "data4").SetMetadata("version", "1.0")
+	result.Metadata()["dataset"] = "other"
+	md := result.Metadata()
+	if len(md) != 2 || md["dataset"] != "data4" || md["version"] != "1.0" {
+		t.Errorf("unexpected metadata: %v", md)
+	}
+
+	// The metadata are only shown if requested, in the order of the keys
+	if strings.Contains(result.Summary().String(), "dataset: data4") {
+		t.Fail()
+	}
+	s := result.Summary().SetShowMetadata(true).String()
+	i1 := strings.Index(s, "dataset: data4")
+	i2 := strings.Index(s, "version: 1.0")
+	if i1 == -1 || i2 < i1 {
+		t.Errorf("metadata not found in summary:\n%s", s)
+	}
+}
+
+func TestFitSubset(t *testing.T) {
+
+	da := data4()
+	sub := statmodel.Subset(da, []int{0, 1, 2, 3, 4})
+	model, err := NewGLM(sub, "y", []string{"x1", "x2"}, nil)
+	if err != nil {
+		panic(err)
+	}
+	if model.NumObs() != 5 {
+		t.Fail()
+	}
+	result := model.Fit()
+
+	// The same model fit to a copy of the subset
+	data := statmodel.Resample(da.Data(), []int{0, 1, 2, 3, 4})
+	model2, err := NewGLM(statmodel.NewDataset(data, da.Names()), "y", []string{"x1", "x2"}, nil)
+	if err != nil {
+		panic(err)
+	}
+	result2 := model2.Fit()
+
+	if !floats.EqualApprox(result.Params(), result2.Params(), 1e-10) {
+		t.Fail()
+	}
+	if !floats.EqualApprox(result.StdErr(), result2.StdErr(), 1e-10) {
+		t.Fail()
+	}
+}
+
+// Accessing the results concurrently should not produce a data race, run
+// with "go test -race" to check this.
+func TestConcurrentResults(t *testing.T) {
+
+	model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, nil)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	se := append([]float64(nil), result.StdErr()...)
+	pv := append([]float64(nil), result.PValues()...)
+	sum := result.Summary().String()
+	cn := result.ConditionNumber()
+	dse, _ := result.DispersionStdErr()
+
+	// A second fit, whose lazily computed values are first requested
+	// concurrently
+	fresh := model.Fit()
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 40)
+	for k := 0; k < 40; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !floats.Equal(result.StdErr(), se) || !floats.Equal(result.PValues(), pv) {
+				errs <- "standard errors or p-values differ"
+			}
+			result.ZScores()
+			result.ConfInt(0.95)
+			result.PredictMean(nil)
+			if d, _ := fresh.DispersionStdErr(); d != dse {
+				errs <- "dispersion standard errors differ"
+			}
+			if result.ConditionNumber() != cn {
+				errs <- "condition numbers differ"
+			}
+			if result.Summary().String() != sum {
+				errs <- "summaries differ"
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for msg := range errs {
+		t.Error(msg)
+	}
+}
+
+func TestCoefficientOrder(t *testing.T) {
+
+	// The coefficients are reported in the order that the covariates
+	// are specified, regardless of their order in the dataset.
+	xnames := []string{"x3", "x1", "x2"}
+	model, err := NewGLM(data4(), "y", xnames, nil)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	for j, na := range result.Names() {
+		if na != xnames[j] {
+			t.Fail()
+		}
+		if result.ParamsMap()[na] != result.Params()[j] {
+			t.Fail()
+		}
+	}
+
+	// The summary rows are in the same order, and repeated summaries
+	// are identical.
+	sum := result.Summary().String()
+	lines := strings.Split(sum, "\n")
+	var rows []string
+	for _, line := range lines {
+		f := strings.Fields(line)
+		if len(f) > 0 && strings.HasPrefix(f[0], "x") {
+			rows = append(rows, f[0])
+		}
+	}
+	if strings.Join(rows, ",") != strings.Join(xnames, ",") {
+		t.Fail()
+	}
+	for k := 0; k < 10; k++ {
+		if result.Summary().String() != sum {
+			t.Fail()
+		}
+	}
+}
+
+// dataBeta simulates beta distributed responses whose mean follows a
+// logistic model with coefficients (0.5, -1) for an intercept and a
+// standard normal covariate, with precision 20.
+func dataBeta(n int) statmodel.Dataset {
+
+	rng := rand.New(rand.NewSource(331))
+	var y, icept, x []statmodel.Dtype
+	for i := 0; i < n; i++ {
+		xv := rng.NormFloat64()
+		mn := 1 / (1 + math.Exp(-(0.5 - xv)))
+		b := distuv.Beta{Alpha: 20 * mn, Beta: 20 * (1 - mn)}
+		y = append(y, statmodel.Dtype(b.Quantile(rng.Float64())))
+		icept = append(icept, 1)
+		x = append(x, statmodel.Dtype(xv))
+	}
+
+	return statmodel.NewDataset([][]statmodel.Dtype{y, icept, x}, []string{"y", "icept", "x"})
+}
+
+func TestBetaFamily(t *testing.T) {
+
+	data := dataBeta(2000)
+	config := DefaultConfig().WithFamily(NewFamily(BetaFamily))
+	model, err := NewGLM(data, "y", []string{"icept", "x"}, config)
+	if err != nil {
+		panic(err)
+	}
+	if model.link.TypeCode != LogitLink {
+		t.Fail()
+	}
+	result := model.Fit()
+
+	params := result.Params()
+	se := result.StdErr()
+	if math.Abs(params[0]-0.5) > 4*se[0] || math.Abs(params[1]+1) > 4*se[1] {
+		t.Logf("%v %v\n", params, se)
+		t.Fail()
+	}
+	if math.Abs(result.Precision()-20) > 2 {
+		t.Logf("%v\n", result.Precision())
+		t.Fail()
+	}
+
+	// The exact log-likelihood agrees with the beta density.
+	phi := result.Precision()
+	mn := result.PredictMean(nil)
+	var ll float64
+	for i, y := range data.Data()[0] {
+		ll += distuv.Beta{Alpha: phi * mn[i], Beta: phi * (1 - mn[i])}.LogProb(float64(y))
+	}
+	if !scalarClose(ll, result.LogLike(), 1e-8) {
+		t.Logf("%v %v\n", ll, result.LogLike())
+		t.Fail()
+	}
+
+	// The estimates maximize the log-likelihood with respect to the
+	// coefficients and the precision.
+	score := result.FinalScore()
+	if floats.Norm(score, math.Inf(1)) > 1e-6 {
+		t.Logf("%v\n", score)
+		t.Fail()
+	}
+	for _, f := range []float64{0.99, 1.01} {
+		scale := 1 / (1 + f*phi)
+		if model.LogLike(&GLMParams{params, scale}, true) >= result.LogLike() {
+			t.Fail()
+		}
+	}
+	if !scalarClose(result.AIC(), -2*result.LogLike()+6, 1e-10) {
+		t.Fail()
+	}
+
+	// The standard errors agree with the numerical Hessian of the
+	// log-likelihood with respect to the coefficients and the
+	// precision, when using the observed information.
+	model, err = NewGLM(data, "y", []string{"icept", "x"}, config.WithObservedInfo(true))
+	if err != nil {
+		panic(err)
+	}
+	result = model.Fit()
+	phi = result.Precision()
+	loglike := func(x []float64) float64 {
+		return model.LogLike(&GLMParams{x[0:2], 1 / (1 + x[2])}, true)
+	}
+	nhess := mat.NewSymDense(3, nil)
+	fd.Hessian(nhess, loglike, []float64{result.Params()[0], result.Params()[1], phi}, nil)
+	nhess.ScaleSym(-1, nhess)
+	var nvcov mat.SymDense
+	var chol mat.Cholesky
+	if !chol.Factorize(nhess) {
+		t.Fatal("the numerical Hessian is not negative definite")
+	}
+	chol.InverseTo(&nvcov)
+	se = result.StdErr()
+	for j := 0; j < 2; j++ {
+		if !scalarClose(se[j]/math.Sqrt(nvcov.At(j, j)), 1, 1e-3) {
+			t.Logf("%d %v %v\n", j, se[j], math.Sqrt(nvcov.At(j, j)))
+			t.Fail()
+		}
+	}
+	dse, err := result.DispersionStdErr()
+	if err != nil || !scalarClose(dse/math.Sqrt(nvcov.At(2, 2)), 1, 1e-3) {
+		t.Logf("%v %v %v\n", dse, math.Sqrt(nvcov.At(2, 2)), err)
+		t.Fail()
+	}
+
+	// The response must lie strictly between 0 and 1.
+	da := data.Data()
+	y := make([]statmodel.Dtype, len(da[0]))
+	copy(y, da[0])
+	y[3] = 1
+	data = statmodel.NewDataset([][]statmodel.Dtype{y, da[1], da[2]}, []string{"y", "icept", "x"})
+	if _, err := NewGLM(data, "y", []string{"icept", "x"}, config); err == nil {
+		t.Fail()
+	}
+
+	// Regularization is not supported.
+	config = DefaultConfig().WithFamily(NewFamily(BetaFamily)).WithL2Penalty(map[string]float64{"x": 0.1})
+	if _, err := NewGLM(dataBeta(100), "y", []string{"icept", "x"}, config); err == nil {
+		t.Fail()
+	}
+}
+
+// TestBetaScoreHess checks the score and Hessian of the beta family
+// against numerical derivatives of the log-likelihood, away from the
+// estimates.
+func TestBetaScoreHess(t *testing.T) {
+
+	data := dataBeta(50)
+	wgt := make([]float64, 50)
+	for i := range wgt {
+		wgt[i] = float64(1 + i%3)
+	}
+
+	for _, link := range []LinkType{LogitLink, CloglogLink} {
+		config := DefaultConfig().WithFamily(NewFamily(BetaFamily)).WithLink(NewLink(link)).WithWeightVec(wgt)
+		model, err := NewGLM(data, "y", []string{"icept", "x"}, config)
+		if err != nil {
+			panic(err)
+		}
+
+		for _, params := range [][]float64{{0, 0}, {0.3, -0.5}, {0.5, 0.5}} {
+			for _, phi := range []float64{5, 30} {
+				scale := 1 / (1 + phi)
+				par := &GLMParams{params, scale}
+
+				loglike := func(x []float64) float64 {
+					return model.LogLike(&GLMParams{x, scale}, true)
+				}
+				ngrad := make([]float64, 2)
+				fd.Gradient(ngrad, loglike, params, nil)
+				score := make([]float64, 2)
+				model.Score(par, score)
+				if !floats.EqualApprox(score, ngrad, 1e-5) {
+					t.Logf("%v %v %v %v\n", link, params, score, ngrad)
+					t.Fail()
+				}
+
+				// The Hessian is the derivative of the score
+				// multiplied by the scale.
+				njac := mat.NewDense(2, 2, nil)
+				fd.Jacobian(njac, func(y, x []float64) {
+					model.Score(&GLMParams{x, scale}, y)
+				}, params, nil)
+				hess := make([]float64, 4)
+				model.Hessian(par, statmodel.ObsHess, hess)
+				floats.Scale(1/scale, hess)
+				if !floats.EqualApprox(hess, njac.RawMatrix().Data, 1e-5) {
+					t.Logf("%v %v %v %v\n", link, params, hess, njac.RawMatrix().Data)
+					t.Fail()
+				}
+
+				// The derivatives with respect to the precision
+				d1, cross, d2 := model.betaPrecisionDeriv(params, phi, statmodel.ObsHess)
+				nd1 := fd.Derivative(func(x float64) float64 {
+					return model.LogLike(&GLMParams{params, 1 / (1 + x)}, true)
+				}, phi, nil)
+				d1p := func(x []float64) float64 {
+					d, _, _ := model.betaPrecisionDeriv(x[0:2], x[2], statmodel.ObsHess)
+					return d
+				}
+				ngrad = make([]float64, 3)
+				fd.Gradient(ngrad, d1p, []float64{params[0], params[1], phi}, nil)
+				if !scalarClose(d1/nd1, 1, 1e-4) || !floats.EqualApprox(append(cross, d2), ngrad, 1e-5) {
+					t.Logf("%v %v %v %v %v %v %v\n", link, params, d1, nd1, cross, d2, ngrad)
+					t.Fail()
+				}
+			}
+		}
+	}
+}
+
+func TestWeightValidation(t *testing.T) {
+
+	da := data4().Data()
+	xnames := []string{"x1", "x2", "x3"}
+	names := []string{"y", "x1", "x2", "x3", "w"}
+	withWeights := func(w []statmodel.Dtype) statmodel.Dataset {
+		return statmodel.NewDataset([][]statmodel.Dtype{da[0], da[1], da[2], da[3], w}, names)
+	}
+
+	// Invalid weights
+	for _, w := range [][]statmodel.Dtype{
+		{3, 3, -2, 3, 1, 3, 2},
+		{0, 0, 0, 0, 0, 0, 0},
+		{3, 3, statmodel.Dtype(math.NaN()), 3, 1, 3, 2},
+	} {
+		config := DefaultConfig().WithWeight("w")
+		if _, err := NewGLM(withWeights(w), "y", xnames, config); err == nil {
+			t.Logf("%v\n", w)
+			t.Fail()
+		}
+	}
+
+	// A case with zero weight is equivalent to dropping the case
+	w := []statmodel.Dtype{3, 3, 0, 3, 1, 3, 2}
+	config := DefaultConfig().WithWeight("w")
+	model, err := NewGLM(withWeights(w), "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	r1 := model.Fit()
+	sub := statmodel.Subset(withWeights(w), []int{0, 1, 3, 4, 5, 6})
+	model, err = NewGLM(sub, "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	r2 := model.Fit()
+	if !floats.EqualApprox(r1.Params(), r2.Params(), 1e-8) ||
+		!floats.EqualApprox(r1.StdErr(), r2.StdErr(), 1e-8) ||
+		!scalarClose(r1.ResidDF(), r2.ResidDF(), 1e-8) {
+		t.Fail()
+	}
+
+	// Normalized weights give the same coefficients, but the effective
+	// sample size is the number of cases with positive weight.
+	config = DefaultConfig().WithWeight("w").WithNormalizedWeights(true)
+	model, err = NewGLM(withWeights(w), "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	r3 := model.Fit()
+	if !floats.EqualApprox(r1.Params(), r3.Params(), 1e-8) {
+		t.Fail()
+	}
+	if !scalarClose(model.sumWeights(), 6, 1e-8) || !scalarClose(r3.ResidDF(), 3, 1e-8) {
+		t.Fail()
+	}
+	if w[0] != 3 {
+		// The caller's data must not be modified
+		t.Fail()
+	}
+}
+
+func TestDevianceR2(t *testing.T) {
+
+	// For a weighted Gaussian model, the deviance R^2 is the usual R^2.
+	config := DefaultConfig().WithWeight("w")
+	model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	da := data4().Data()
+	var ws, ym float64
+	for i, y := range da[0] {
+		ws += float64(da[4][i])
+		ym += float64(da[4][i] * y)
+	}
+	ym /= ws
+	var tss float64
+	for i, y := range da[0] {
+		tss += float64(da[4][i]) * (float64(y) - ym) * (float64(y) - ym)
+	}
+	if !scalarClose(result.NullDeviance(), tss, 1e-8) {
+		t.Fail()
+	}
+	if !scalarClose(result.DevianceR2(), 1-result.Deviance()/tss, 1e-8) {
+		t.Fail()
+	}
+	if !strings.Contains(result.Summary().String(), "Deviance R^2") {
+		t.Fail()
+	}
+
+	// For a Poisson model with an offset, the null deviance is the
+	// deviance of the intercept-only model with the same offset.
+	config = DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithWeight("w").WithOffset("off")
+	model, err = NewGLM(data5(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result = model.Fit()
+	nmodel, err := NewGLM(data5(), "y", []string{"x1"}, config)
+	if err != nil {
+		panic(err)
+	}
+	nresult := nmodel.Fit()
+	if !scalarClose(result.NullDeviance(), nresult.Deviance(), 1e-6) {
+		t.Logf("%v %v\n", result.NullDeviance(), nresult.Deviance())
+		t.Fail()
+	}
+	r2 := result.DevianceR2()
+	if r2 < 0 || r2 > 1 {
+		t.Fail()
+	}
+
+	// The null model is fit once and shared
+	null := result.NullModel()
+	if null == nil || null != result.NullModel() {
+		t.Fatal("the null model is not memoized")
+	}
+	if !floats.EqualApprox(null.Params(), nresult.Params(), 1e-6) ||
+		!scalarClose(result.NullLogLike(), nresult.LogLike(), 1e-6) {
+		t.Fail()
+	}
+}
+
+func TestOffsetOnly(t *testing.T) {
+
+	da := data5().Data()
+	var swy, swe float64
+	for i, y := range da[0] {
+		swy += float64(da[4][i] * y)
+		swe += float64(da[4][i]) * math.Exp(float64(da[3][i]))
+	}
+
+	// Intercept and offset, the MLE of the intercept has a closed form.
+	for _, method := range []string{"IRLS", "gradient"} {
+		config := DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithWeight("w").WithOffset("off")
+		config.FitMethod = method
+		model, err := NewGLM(data5(), "y", []string{"x1"}, config)
+		if err != nil {
+			panic(err)
+		}
+		result := model.Fit()
+		if !scalarClose(result.Params()[0], math.Log(swy/swe), 1e-5) {
+			t.Logf("%s %v %v\n", method, result.Params()[0], math.Log(swy/swe))
+			t.Fail()
+		}
+		if !scalarClose(result.NullDeviance(), result.Deviance(), 1e-6) {
+			t.Fail()
+		}
+		_ = result.Summary().String()
+	}
+
+	// Offset only, there are no free parameters.
+	config := DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithWeight("w").WithOffset("off")
+	model, err := NewGLM(data5(), "y", nil, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+	if len(result.Params()) != 0 {
+		t.Fail()
+	}
+	var ll float64
+	for i, y := range da[0] {
+		ll += float64(da[4][i]) * distuv.Poisson{Lambda: math.Exp(float64(da[3][i]))}.LogProb(float64(y))
+	}
+	if !scalarClose(result.LogLike(), ll, 1e-8) {
+		t.Logf("%v %v\n", result.LogLike(), ll)
+		t.Fail()
+	}
+	if !scalarClose(result.NullDeviance(), result.Deviance(), 1e-8) {
+		t.Fail()
+	}
+	_ = result.Summary().String()
+}
+
+func TestPenaltyExempt(t *testing.T) {
+
+	xnames := []string{"x1", "x2", "x3"}
+	pen := map[string]float64{"x1": 10, "x2": 10, "x3": 10}
+
+	// Exempting variables is equivalent to giving them zero weight
+	config := DefaultConfig().WithWeight("w").WithL1Penalty(pen).WithPenaltyExempt("x1", "x2")
+	model, err := NewGLM(data4(), "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	r1 := model.Fit()
+
+	config = DefaultConfig().WithWeight("w").WithL1Penalty(map[string]float64{"x1": 0, "x2": 0, "x3": 10})
+	model, err = NewGLM(data4(), "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	r2 := model.Fit()
+
+	if !floats.EqualApprox(r1.Params(), r2.Params(), 1e-8) {
+		t.Fail()
+	}
+	if r1.Params()[0] == 0 || r1.Params()[1] == 0 || r1.Params()[2] != 0 {
+		t.Logf("%v\n", r1.Params())
+		t.Fail()
+	}
+	if pen["x1"] != 10 {
+		// The caller's map must not be modified
+		t.Fail()
+	}
+
+	// Names must refer to predictors
+	config = DefaultConfig().WithL1Penalty(map[string]float64{"x4": 1})
+	if _, err := NewGLM(data4(), "y", xnames, config); err == nil {
+		t.Fail()
+	}
+	config = DefaultConfig().WithL2Penalty(pen).WithPenaltyExempt("w")
+	if _, err := NewGLM(data4(), "y", xnames, config); err == nil {
+		t.Fail()
+	}
+}
+
+func TestConvergence(t *testing.T) {
+
+	xnames := []string{"x1", "x2"}
+	fam := NewFamily(PoissonFamily)
+
+	config := DefaultConfig().WithFamily(fam).WithWeight("w").WithOffset("off")
+	model, err := NewGLM(data5(), "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	r0 := model.Fit()
+	fi, ok := r0.FitInfo()
+	if !ok || !fi.Converged || fi.Criterion != ConvergeDeviance || fi.Iterations == 0 {
+		t.Logf("%+v\n", fi)
+		t.Fail()
+	}
+
+	for _, cc := range []ConvergenceCriterion{ConvergeParams, ConvergeGradient, ConvergeRelDeviance, ConvergeParams | ConvergeGradient} {
+		config := DefaultConfig().WithFamily(fam).WithWeight("w").WithOffset("off").WithConvergence(cc, 1e-10)
+		model, err := NewGLM(data5(), "y", xnames, config)
+		if err != nil {
+			panic(err)
+		}
+		result := model.Fit()
+		fi, _ := result.FitInfo()
+		if !fi.Converged || fi.Criterion&cc == 0 {
+			t.Logf("%v %+v\n", cc, fi)
+			t.Fail()
+		}
+		if !floats.EqualApprox(result.Params(), r0.Params(), 1e-6) {
+			t.Fail()
+		}
+	}
+
+	// Under perfect separation, the logistic regression parameters
+	// diverge.  The means are constrained so that the iterations
+	// continue until the iteration limit.
+	sep := statmodel.NewDataset([][]statmodel.Dtype{{0, 0, 0, 1, 1, 1}, {1, 1, 1, 1, 1, 1}, {1, 2, 3, 4, 5, 6}},
+		[]string{"y", "x1", "x2"})
+	config = DefaultConfig().WithFamily(NewFamily(BinomialFamily)).WithConvergence(ConvergeParams, 0).
+		WithConstrainMean(true)
+	model, err = NewGLM(sep, "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+	fi, _ = result.FitInfo()
+	if fi.Converged || fi.Criterion != 0 {
+		t.Fail()
+	}
+	if !strings.Contains(result.Summary().String(), "IRLS did not converge") {
+		t.Fail()
+	}
+
+	// Non-convergence can be treated as an error
+	model, err = NewGLM(sep, "y", xnames, config.WithNonConvergence(NonConvergenceError))
+	if err != nil {
+		panic(err)
+	}
+	if _, err := model.FitChecked(); err == nil {
+		t.Fail()
+	}
+	model, err = NewGLM(sep, "y", xnames, config.WithNonConvergence(NonConvergencePanic))
+	if err != nil {
+		panic(err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic")
+			}
+		}()
+		model.FitChecked()
+	}()
+	if config.WithNonConvergence(NonConvergencePanic+1).Validate() == nil {
+		t.Fail()
+	}
+
+	// Gradient fitting does not use IRLS
+	config = DefaultConfig().WithFamily(fam).WithWeight("w").WithOffset("off").WithFitMethod("gradient")
+	model, err = NewGLM(data5(), "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	if _, ok := model.Fit().FitInfo(); ok {
+		t.Fail()
+	}
+
+	if ConvergeDeviance.String() != "Deviance" || (ConvergeParams|ConvergeGradient).String() != "Params|Gradient" ||
+		ConvergeRelDeviance.String() != "RelDeviance" {
+		t.Fail()
+	}
+
+	config = DefaultConfig().WithConvergence(ConvergeRelDeviance<<1, 0)
+	if _, err := NewGLM(data5(), "y", xnames, config); err == nil {
+		t.Fail()
+	}
+}
+
+func TestSaturatedLogLike(t *testing.T) {
+
+	binary := statmodel.NewDataset([][]statmodel.Dtype{{0, 1, 1, 0, 1, 0, 1}, {1, 1, 1, 1, 1, 1, 1},
+		{4, 1, -1, 3, 5, -5, 3}, {1, 2, 2, 3, 1, 3, 2}}, []string{"y", "x1", "x2", "w"})
+
+	for _, tc := range []struct {
+		fam  *Family
+		data statmodel.Dataset
+	}{
+		{NewFamily(GaussianFamily), data4()},
+		{NewFamily(GammaFamily), data4()},
+		{NewFamily(InvGaussianFamily), data4()},
+		{NewFamily(PoissonFamily), data5()},
+		{NewFamily(QuasiPoissonFamily), data5()},
+		{NewFamily(BinomialFamily), binary},
+		{NewNegBinomFamily(1.5, NewLink(LogLink)), data5()},
+		{NewTweedieFamily(1.5, NewLink(LogLink)), data5()},
+	} {
+		config := DefaultConfig().WithFamily(tc.fam).WithWeight("w")
+		if tc.fam.TypeCode == GammaFamily || tc.fam.TypeCode == InvGaussianFamily {
+			config = config.WithLink(NewLink(LogLink))
+		}
+		model, err := NewGLM(tc.data, "y", []string{"x1", "x2"}, config)
+		if err != nil {
+			panic(err)
+		}
+		result := model.Fit()
+
+		y := tc.data.Data()[0]
+		w := tc.data.Data()[len(tc.data.Data())-1]
+		mn := result.PredictMean(nil)
+
+		// The deviance of the discrete families does not depend on
+		// the scale.
+		scale := result.Scale()
+		f := scale
+		switch tc.fam.TypeCode {
+		case PoissonFamily, QuasiPoissonFamily, BinomialFamily, NegBinomFamily:
+			f = 1
+		}
+		sat := tc.fam.SaturatedLogLike(y, w, scale)
+		ll := tc.fam.LogLike(y, mn, w, scale, true)
+		dev := tc.fam.Deviance(y, mn, w, 1)
+
+		if !scalarClose(dev, 2*f*(sat-ll), 1e-6) {
+			t.Logf("%s %v %v\n", tc.fam.Name, dev, 2*f*(sat-ll))
+			t.Fail()
+		}
+	}
+
+	// The negative binomial deviance agrees with the closed form, and
+	// does not depend on the link.
+	alpha := 1.5
+	y := []statmodel.Dtype{0, 1, 3, 0, 2, 7}
+	w := []statmodel.Dtype{1, 2, 1, 3, 1, 2}
+	mn := []float64{0.5, 1.2, 2.5, 0.3, 2.2, 5}
+	var dev float64
+	for i := range y {
+		yi := float64(y[i])
+		d := math.Log(1+alpha*mn[i]) / alpha
+		if yi > 0 {
+			d = yi*math.Log(yi/mn[i]) - (1+alpha*yi)/alpha*math.Log((1+alpha*yi)/(1+alpha*mn[i]))
+		}
+		dev += 2 * float64(w[i]) * d
+	}
+	nblog := NewNegBinomFamily(alpha, NewLink(LogLink))
+	nbid := NewNegBinomFamily(alpha, NewLink(IdentityLink))
+	if !scalarClose(nblog.Deviance(y, mn, w, 1), dev, 1e-10) ||
+		!scalarClose(nbid.Deviance(y, mn, w, 1), dev, 1e-10) ||
+		!scalarClose(nbid.LogLike(y, mn, w, 1, true), nblog.LogLike(y, mn, w, 1, true), 1e-10) {
+		t.Fail()
+	}
+}
+
+func TestVcovScale(t *testing.T) {
+
+	// For weighted least squares, the covariance matrix is
+	// s^2 (X'WX)^{-1}, where s^2 is the Pearson estimate of the scale.
+	config := DefaultConfig().WithWeight("w")
+	model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	da := data4().Data()
+	x := mat.NewDense(7, 3, nil)
+	for i := 0; i < 7; i++ {
+		for j := 0; j < 3; j++ {
+			x.Set(i, j, float64(da[j+1][i])*math.Sqrt(float64(da[4][i])))
+		}
+	}
+	var xtx, xtxi mat.Dense
+	xtx.Mul(x.T(), x)
+	if err := xtxi.Inverse(&xtx); err != nil {
+		panic(err)
+	}
+	xtxi.Scale(result.Scale(), &xtxi)
+	if !floats.EqualApprox(result.VCov(), xtxi.RawMatrix().Data, 1e-8) {
+		t.Logf("%v %v\n", result.VCov(), xtxi.RawMatrix().Data)
+		t.Fail()
+	}
+
+	// GetVcovScaled multiplies the unscaled covariance by the scale
+	pa := &GLMParams{result.Params(), result.Scale()}
+	v1, _ := statmodel.GetVcov(model, pa)
+	v2, _ := statmodel.GetVcovScaled(model, pa, statmodel.ExpHess, 2.5)
+	floats.Scale(2.5, v1)
+	if !floats.EqualApprox(v1, v2, 1e-12) {
+		t.Fail()
+	}
+}
+
+func TestHasIntercept(t *testing.T) {
+
+	for _, tc := range []struct {
+		xnames []string
+		l1     map[string]float64
+		icept  bool
+	}{
+		{[]string{"x1", "x2", "x3"}, nil, true},
+		{[]string{"x2", "x3", "x1"}, nil, true},
+		{[]string{"x2", "x3"}, nil, false},
+		{[]string{"x1", "x2"}, map[string]float64{"x1": 100, "x2": 100}, true},
+	} {
+		config := DefaultConfig().WithWeight("w").WithL1Penalty(tc.l1)
+		model, err := NewGLM(data4(), "y", tc.xnames, config)
+		if err != nil {
+			panic(err)
+		}
+		result := model.Fit()
+		if result.HasIntercept() != tc.icept {
+			t.Logf("%v\n", tc.xnames)
+			t.Fail()
+		}
+
+		// The intercept coefficient is shrunk to zero, so it does not
+		// reduce the model degrees of freedom.
+		if tc.l1 != nil && (result.Params()[0] != 0 || result.ModelDF() != result.EffectiveDF()) {
+			t.Fail()
+		}
+	}
+}
+
+func TestIDVar(t *testing.T) {
+
+	da := data4().Data()
+	id := []statmodel.Dtype{101, 102, 103, 104, 105, 106, 107}
+	names := []string{"y", "x1", "x2", "x3", "w", "id"}
+	data := statmodel.NewDataset(append(da, id), names)
+
+	// Drop some rows, the IDs identify the remaining observations
+	data = statmodel.Subset(data, []int{0, 2, 3, 5, 6})
+	config := DefaultConfig().WithWeight("w").WithID("id")
+	model, err := NewGLM(data, "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+	ids := result.IDs()
+	if len(ids) != len(result.Resid(nil)) || ids[1] != 103 || ids[4] != 107 {
+		t.Logf("%v\n", ids)
+		t.Fail()
+	}
+
+	// The fitted data carry the IDs
+	fd := result.FittedData()
+	want := []string{"id", "fitted", "linpred", "resid", "pearson_resid", "deviance_resid"}
+	if strings.Join(fd.Names(), ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected fitted data names %v", fd.Names())
+	}
+	resid := result.Resid(nil)
+	for i, v := range fd.Data()[3] {
+		if fd.Data()[0][i] != ids[i] || !scalarClose(float64(v), resid[i], 1e-12) {
+			t.Fail()
+		}
+	}
+
+	config.IDVar = ""
+	model, err = NewGLM(data, "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result = model.Fit()
+	if result.IDs() != nil {
+		t.Fail()
+	}
+	if fd := result.FittedData(); len(fd.Names()) != 5 || fd.Names()[0] != "fitted" {
+		t.Fail()
+	}
+
+	for _, idv := range []string{"x2", "notfound"} {
+		config.IDVar = idv
+		if _, err := NewGLM(data, "y", []string{"x1", "x2"}, config); err == nil {
+			t.Fail()
+		}
+	}
+}
+
+func TestWeightOffsetVec(t *testing.T) {
+
+	da := data4().Data()
+	xnames := []string{"x1", "x2"}
+	w := []statmodel.Dtype{3, 3, 1, 3, 1, 3, 2}
+	off := []statmodel.Dtype{0, 1, 0, -1, 2, 0, 1}
+	names := []string{"y", "x1", "x2", "w", "off"}
+	data := statmodel.NewDataset([][]statmodel.Dtype{da[0], da[1], da[2], w, off}, names)
+
+	config := DefaultConfig().WithWeight("w").WithOffset("off")
+	model, err := NewGLM(data, "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	r1 := model.Fit()
+
+	// The standalone vectors take precedence over the variable names
+	vdata := statmodel.NewDataset([][]statmodel.Dtype{da[0], da[1], da[2]}, names[0:3])
+	config = DefaultConfig().WithWeightVec(w).WithOffsetVec(off)
+	model, err = NewGLM(vdata, "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	r2 := model.Fit()
+
+	if !floats.EqualApprox(r1.Params(), r2.Params(), 1e-8) ||
+		!floats.EqualApprox(r1.StdErr(), r2.StdErr(), 1e-8) ||
+		!scalarClose(r1.LogLike(), r2.LogLike(), 1e-8) {
+		t.Fail()
+	}
+	if len(vdata.Data()) != 3 || len(vdata.Names()) != 3 {
+		// The caller's data must not be modified
+		t.Fail()
+	}
+
+	// Predictions can use the caller's column layout, in which case the
+	// OffsetVec offset is not included.
+	lp := r2.FittedValues(vdata.Data())
+	if !floats.EqualApprox(lp, r1.FittedValues(nil), 1e-8) {
+		t.Fail()
+	}
+	if !floats.EqualApprox(r2.PredictMean(vdata.Data()), lp, 1e-8) {
+		t.Fail()
+	}
+	if !floats.EqualApprox(r2.PredictMeanBatch(vdata.Data(), nil, 3), lp, 1e-8) {
+		t.Fail()
+	}
+	if _, err := r2.PredictMeanCI(vdata.Data(), 0.95, MeanIntervalLink); err != nil {
+		t.Error(err)
+	}
+
+	// The vectors must have one value per observation
+	config = DefaultConfig().WithWeightVec(w[0:5])
+	if _, err := NewGLM(vdata, "y", xnames, config); err == nil {
+		t.Fail()
+	}
+	config = DefaultConfig().WithOffsetVec(off[0:5])
+	if _, err := NewGLM(vdata, "y", xnames, config); err == nil {
+		t.Fail()
+	}
+	config = DefaultConfig().WithWeightVec([]statmodel.Dtype{3, 3, -1, 3, 1, 3, 2})
+	if _, err := NewGLM(vdata, "y", xnames, config); err == nil {
+		t.Fail()
+	}
+}
+
+func TestCompareModelsGLM(t *testing.T) {
+
+	data := data4()
+	var rslts []statmodel.BaseResultser
+	for _, xnames := range [][]string{{"x1"}, {"x1", "x2", "x3"}} {
+		model, err := NewGLM(data, "y", xnames, DefaultConfig())
+		if err != nil {
+			panic(err)
+		}
+		rslts = append(rslts, model.Fit())
+	}
+
+	// The GLM results provide their own AIC, BIC, and deviance
+	tab := statmodel.CompareModels([]string{"m1", "m2"}, rslts)
+	for i, r := range rslts {
+		gr := r.(*GLMResults)
+		if !scalarClose(tab.Cols[2].([]float64)[i], gr.AIC(), 1e-12) ||
+			!scalarClose(tab.Cols[3].([]float64)[i], gr.BIC(), 1e-12) ||
+			!scalarClose(tab.Cols[4].([]float64)[i], gr.Deviance(), 1e-12) {
+			t.Fail()
+		}
+	}
+}
+
+func TestRidge(t *testing.T) {
+
+	// x4 is collinear with x2, so the unpenalized fit is not identified
+	da := data4().Data()
+	x4 := make([]statmodel.Dtype, len(da[2]))
+	copy(x4, da[2])
+	data := statmodel.NewDataset([][]statmodel.Dtype{da[0], da[1], da[2], da[3], x4},
+		[]string{"y", "x1", "x2", "x3", "x4"})
+	xnames := []string{"x1", "x2", "x3", "x4"}
+
+	lambda := 0.5
+	model, err := NewGLM(data, "y", xnames, DefaultConfig().WithRidge(lambda))
+	if err != nil {
+		panic(err)
+	}
+	rslt := model.Fit()
+
+	// For the Gaussian family, the solution is (X'X + nλD)^-1 X'y, with
+	// D a diagonal matrix with 0 for the intercept and 1 otherwise, and
+	// the covariance matrix is the scale times (X'X + nλD)^-1.
+	n, p := len(da[0]), len(xnames)
+	xm := mat.NewDense(n, p, nil)
+	for j, k := range []int{1, 2, 3, 4} {
+		for i := 0; i < n; i++ {
+			xm.Set(i, j, float64(data.Data()[k][i]))
+		}
+	}
+	var xtx mat.Dense
+	xtx.Mul(xm.T(), xm)
+	for j := 1; j < p; j++ {
+		xtx.Set(j, j, xtx.At(j, j)+float64(n)*lambda)
+	}
+	var xty, b mat.VecDense
+	xty.MulVec(xm.T(), mat.NewVecDense(n, da[0]))
+	if err := b.SolveVec(&xtx, &xty); err != nil {
+		panic(err)
+	}
+	if !floats.EqualApprox(rslt.Params(), b.RawVector().Data, 1e-8) {
+		t.Logf("%v %v\n", rslt.Params(), b.RawVector().Data)
+		t.Fail()
+	}
+	if !scalarClose(rslt.Params()[1], rslt.Params()[3], 1e-10) {
+		// The collinear variables share the effect equally
+		t.Fail()
+	}
+
+	var xtxi mat.Dense
+	if err := xtxi.Inverse(&xtx); err != nil {
+		panic(err)
+	}
+	for j := 0; j < p; j++ {
+		if !scalarClose(rslt.StdErr()[j], math.Sqrt(rslt.Scale()*xtxi.At(j, j)), 1e-6) {
+			t.Fail()
+		}
+	}
+
+	// The IRLS ridge fit agrees with the gradient-based L2 penalized fit
+	for _, fam := range []FamilyType{GaussianFamily, PoissonFamily} {
+		config := DefaultConfig().WithFamily(NewFamily(fam)).WithRidge(lambda)
+		model, err := NewGLM(data, "y", xnames, config)
+		if err != nil {
+			panic(err)
+		}
+		r1 := model.Fit()
+
+		l2 := map[string]float64{"x2": lambda, "x3": lambda, "x4": lambda}
+		config = DefaultConfig().WithFamily(NewFamily(fam)).WithL2Penalty(l2)
+		model, err = NewGLM(data, "y", xnames, config)
+		if err != nil {
+			panic(err)
+		}
+		r2 := model.Fit()
+		if !floats.EqualApprox(r1.Params(), r2.Params(), 1e-4) {
+			t.Logf("%v %v\n", r1.Params(), r2.Params())
+			t.Fail()
+		}
+	}
+
+	// Invalid configurations
+	for _, config := range []*Config{
+		DefaultConfig().WithRidge(-1),
+		DefaultConfig().WithRidge(1).WithL2Penalty(map[string]float64{"x2": 1}),
+		DefaultConfig().WithRidge(1).WithL1Penalty(map[string]float64{"x2": 1}),
+	} {
+		if _, err := NewGLM(data, "y", xnames, config); err == nil {
+			t.Fail()
+		}
+	}
+}
+
+func TestNonFinite(t *testing.T) {
+
+	// With the identity link, the fitted Gamma means are not positive
+	y := []statmodel.Dtype{0.1, 0.2, 5, 0.1, 8, 0.05}
+	x1 := []statmodel.Dtype{1, 1, 1, 1, 1, 1}
+	x2 := []statmodel.Dtype{0, 1, 2, 3, 4, 5}
+	ds := statmodel.NewDataset([][]statmodel.Dtype{y, x1, x2}, []string{"y", "x1", "x2"})
+
+	for _, fm := range []string{"irls", "gradient"} {
+		config := DefaultConfig().WithFamily(NewFamily(GammaFamily)).WithLink(NewLink(IdentityLink)).
+			WithLinkCheck(LinkCheckOff).WithFitMethod(fm)
+		model, err := NewGLM(ds, "y", []string{"x1", "x2"}, config)
+		if err != nil {
+			panic(err)
+		}
+		_, err = model.FitChecked()
+		if err == nil || !strings.Contains(err.Error(), "not valid for the Gamma family") {
+			t.Logf("%s: %v\n", fm, err)
+			t.Fail()
+		}
+
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fail()
+				}
+			}()
+			model.Fit()
+		}()
+	}
+
+	// Collinear covariates give a singular X'WX
+	x3 := []statmodel.Dtype{1, 2, 3, 4, 5, 6}
+	ds = statmodel.NewDataset([][]statmodel.Dtype{y, x1, x2, x3}, []string{"y", "x1", "x2", "x3"})
+	model, err := NewGLM(ds, "y", []string{"x1", "x2", "x3"}, DefaultConfig().WithFamily(NewFamily(GammaFamily)).WithLink(NewLink(LogLink)))
+	if err != nil {
+		panic(err)
+	}
+	if _, err := model.FitChecked(); err == nil || !strings.Contains(err.Error(), "singular") {
+		t.Logf("%v\n", err)
+		t.Fail()
+	}
+}
+
+func TestDefaultLink(t *testing.T) {
+
+	data := data4()
+
+	for fam, link := range map[FamilyType]LinkType{
+		GaussianFamily:    IdentityLink,
+		PoissonFamily:     LogLink,
+		GammaFamily:       RecipLink,
+		InvGaussianFamily: RecipSquaredLink,
+	} {
+		model, err := NewGLM(data, "y", []string{"x1", "x2"}, DefaultConfig().WithFamily(NewFamily(fam)))
+		if err != nil {
+			panic(err)
+		}
+		if model.link.TypeCode != link {
+			t.Logf("%v: %v\n", fam, model.link.Name)
+			t.Fail()
+		}
+	}
+
+	// The link provided to the negative binomial family is used
+	fam := NewNegBinomFamily(0.5, NewLink(IdentityLink))
+	model, err := NewGLM(data, "y", []string{"x1", "x2"}, DefaultConfig().WithFamily(fam))
+	if err != nil {
+		panic(err)
+	}
+	if model.link.TypeCode != IdentityLink {
+		t.Fail()
+	}
+
+	// A family with no links requires the link to be specified
+	fam = &Family{Name: "Nolink", TypeCode: CustomFamily}
+	if _, err := NewGLM(data, "y", []string{"x1", "x2"}, DefaultConfig().WithFamily(fam)); err == nil {
+		t.Fail()
+	}
+}
+
+func TestDispersionVar(t *testing.T) {
+
+	// Fixed effects meta-analysis, the estimate is the inverse variance
+	// weighted mean.
+	y := []statmodel.Dtype{0.5, 0.1, 0.9, 0.3, 0.4}
+	v := []statmodel.Dtype{0.1, 0.2, 0.4, 0.05, 0.3}
+	icept := []statmodel.Dtype{1, 1, 1, 1, 1}
+	data := statmodel.NewDataset([][]statmodel.Dtype{y, icept, v}, []string{"y", "icept", "v"})
+
+	var sw, swy float64
+	for i := range y {
+		sw += 1 / v[i]
+		swy += y[i] / v[i]
+	}
+
+	config := DefaultConfig().WithDispersion("v").WithDispersionForm(DispersionFixed)
+	model, err := NewGLM(data, "y", []string{"icept"}, config)
+	if err != nil {
+		panic(err)
+	}
+	r1 := model.Fit()
+	if !scalarClose(r1.Params()[0], swy/sw, 1e-10) || !scalarClose(r1.StdErr()[0], 1/math.Sqrt(sw), 1e-10) {
+		t.Logf("%v %v\n", r1.Params(), r1.StdErr())
+		t.Fail()
+	}
+	if r1.Scale() != 1 {
+		t.Fail()
+	}
+
+	// The dispersions do not change the effective sample size
+	if !scalarClose(r1.ResidDF(), 4, 1e-10) {
+		t.Fail()
+	}
+
+	// The log-likelihood is that of independent normal observations with
+	// variances v.
+	mu := swy / sw
+	var ll float64
+	for i := range y {
+		r := float64(y[i]) - mu
+		ll -= (r*r/float64(v[i]) + math.Log(2*math.Pi*float64(v[i]))) / 2
+	}
+	if !scalarClose(r1.LogLike(), ll, 1e-10) || !scalarClose(r1.AIC(), 2-2*ll, 1e-10) {
+		t.Errorf("log-likelihood %v, AIC %v, expected %v", r1.LogLike(), r1.AIC(), ll)
+	}
+
+	// With a free scale, the standard error is multiplied by the square
+	// root of the estimated scale.
+	config = DefaultConfig().WithDispersionForm(DispersionFree).WithDispersionVec(v)
+	model, err = NewGLM(statmodel.NewDataset([][]statmodel.Dtype{y, icept}, []string{"y", "icept"}), "y", []string{"icept"}, config)
+	if err != nil {
+		panic(err)
+	}
+	r2 := model.Fit()
+	if !scalarClose(r2.Params()[0], swy/sw, 1e-10) ||
+		!scalarClose(r2.StdErr()[0], math.Sqrt(r2.Scale()/sw), 1e-10) {
+		t.Fail()
+	}
+
+	// Dispersions must be positive
+	for _, d := range [][]statmodel.Dtype{{0.1, 0.2, 0, 0.05, 0.3}, {0.1, 0.2, -1, 0.05, 0.3}} {
+		config = DefaultConfig().WithDispersionVec(d)
+		if _, err := NewGLM(data, "y", []string{"icept"}, config); err == nil {
+			t.Fail()
+		}
+	}
+}
+
+func TestProfileLogLike(t *testing.T) {
+
+	config := DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithOffset("off").WithWeight("w")
+	model, err := NewGLM(data5(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	rslt := model.Fit()
+	b := rslt.Params()[1]
+	se := rslt.StdErr()[1]
+
+	h := 0.01 * se
+	ll := rslt.ProfileLogLike(1, []float64{b - h, b, b + h, b + 3*se})
+
+	// The profile is maximized at the MLE
+	if !scalarClose(ll[1], rslt.LogLike(), 1e-6) {
+		t.Logf("%v %v\n", ll[1], rslt.LogLike())
+		t.Fail()
+	}
+	if ll[0] > ll[1] || ll[2] > ll[1] || ll[3] > ll[2] {
+		t.Fail()
+	}
+
+	// Near the MLE, the profile is approximately quadratic with
+	// curvature determined by the standard error.
+	for _, k := range []int{0, 2} {
+		if !scalarClose(2*(ll[1]-ll[k]), (h/se)*(h/se), 1e-2*(h/se)*(h/se)) {
+			t.Logf("%v %v\n", 2*(ll[1]-ll[k]), (h/se)*(h/se))
+			t.Fail()
+		}
+	}
+
+	// Fixing the only covariate leaves nothing to estimate
+	model, err = NewGLM(data5(), "y", []string{"x1"}, config)
+	if err != nil {
+		panic(err)
+	}
+	rslt = model.Fit()
+	ll = rslt.ProfileLogLike(0, rslt.Params())
+	if !scalarClose(ll[0], rslt.LogLike(), 1e-8) {
+		t.Fail()
+	}
+}
+
+func TestDuplicatePredictors(t *testing.T) {
+
+	da := data4().Data()
+	x4 := make([]statmodel.Dtype, len(da[2]))
+	for i, v := range da[2] {
+		x4[i] = v + 1e-13
+	}
+	data := statmodel.NewDataset([][]statmodel.Dtype{da[0], da[1], da[2], da[3], x4},
+		[]string{"y", "x1", "x2", "x3", "x4"})
+
+	for _, xnames := range [][]string{{"x1", "x2", "x2"}, {"x1", "x2", "x3", "x4"}} {
+		_, err := NewGLM(data, "y", xnames, DefaultConfig())
+		if err == nil || !strings.Contains(err.Error(), "x2") {
+			t.Logf("%v: %v\n", xnames, err)
+			t.Fail()
+		}
+	}
+
+	// Columns that differ by more than the tolerance are not duplicates
+	x4[3] += 1e-3
+	if _, err := NewGLM(data, "y", []string{"x1", "x2", "x3", "x4"}, DefaultConfig()); err != nil {
+		t.Fail()
+	}
+}
+
+func TestConditionNumber(t *testing.T) {
+
+	// The columns are orthogonal, so the information matrix is diagonal
+	y := []statmodel.Dtype{1, 3, 2, 5, 4, 6}
+	x1 := []statmodel.Dtype{1, 1, 1, 1, 1, 1}
+	x2 := []statmodel.Dtype{-3, -2, -1, 1, 2, 3}
+	x3 := []statmodel.Dtype{-3, -2, -1, 1, 2, 3 + 2e-5}
+	data := statmodel.NewDataset([][]statmodel.Dtype{y, x1, x2, x3}, []string{"y", "x1", "x2", "x3"})
+
+	model, err := NewGLM(data, "y", []string{"x1", "x2"}, DefaultConfig())
+	if err != nil {
+		panic(err)
+	}
+	rslt := model.Fit()
+	if !scalarClose(rslt.ConditionNumber(), 28.0/6, 1e-10) {
+		t.Logf("%v\n", rslt.ConditionNumber())
+		t.Fail()
+	}
+	if strings.Contains(rslt.Summary().String(), "condition number is large") {
+		t.Fail()
+	}
+
+	// Nearly collinear covariates are flagged in the summary
+	model, err = NewGLM(data, "y", []string{"x1", "x2", "x3"}, DefaultConfig())
+	if err != nil {
+		panic(err)
+	}
+	rslt = model.Fit()
+	if rslt.ConditionNumber() < LargeConditionNumber {
+		t.Fail()
+	}
+	if !strings.Contains(rslt.Summary().String(), "condition number is large") {
+		t.Fail()
+	}
+}
+
+func TestIRLSMoments(t *testing.T) {
+
+	config := DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithOffset("off").WithWeight("w")
+	model, err := NewGLM(data5(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	rslt := model.Fit()
+
+	// The fitted parameters are a fixed point of the IRLS update
+	solve := func(xtx, xty []float64) []float64 {
+		var b mat.VecDense
+		if err := b.SolveVec(mat.NewDense(2, 2, xtx), mat.NewVecDense(2, xty)); err != nil {
+			panic(err)
+		}
+		return b.RawVector().Data
+	}
+	if !floats.EqualApprox(solve(rslt.IRLSMoments()), rslt.Params(), 1e-6) {
+		t.Fail()
+	}
+
+	// Distributed fitting, by summing the moments for two blocks of data
+	var models []*GLM
+	for _, ix := range [][]int{{0, 1, 2}, {3, 4, 5, 6}} {
+		m, err := NewGLM(statmodel.Subset(data5(), ix), "y", []string{"x1", "x2"}, config)
+		if err != nil {
+			panic(err)
+		}
+		models = append(models, m)
+	}
+	params := []float64{0, 0}
+	for iter := 0; iter < 50; iter++ {
+		xtx := make([]float64, 4)
+		xty := make([]float64, 2)
+		for _, m := range models {
+			a, b := m.IRLSMoments(params)
+			floats.Add(xtx, a)
+			floats.Add(xty, b)
+		}
+		params = solve(xtx, xty)
+	}
+	if !floats.EqualApprox(params, rslt.Params(), 1e-6) {
+		t.Logf("%v %v\n", params, rslt.Params())
+		t.Fail()
+	}
+}
+
+func TestBinomialTrials(t *testing.T) {
+
+	// Grouped data: the proportion of successes out of n trials
+	x := []statmodel.Dtype{-1, 0, 1, 2}
+	n := []statmodel.Dtype{4, 5, 3, 6}
+	k := []statmodel.Dtype{1, 2, 2, 5}
+
+	var yg, icept []statmodel.Dtype
+	for i := range x {
+		yg = append(yg, k[i]/n[i])
+		icept = append(icept, 1)
+	}
+	grouped := statmodel.NewDataset([][]statmodel.Dtype{yg, icept, x, n},
+		[]string{"y", "icept", "x", "n"})
+
+	// The same data with one binary response per trial
+	var ye, ie, xe []statmodel.Dtype
+	var lchoose float64
+	for i := range x {
+		for j := 0; j < int(n[i]); j++ {
+			if j < int(k[i]) {
+				ye = append(ye, 1)
+			} else {
+				ye = append(ye, 0)
+			}
+			ie = append(ie, 1)
+			xe = append(xe, x[i])
+		}
+		lchoose += lgamma(float64(n[i])+1) - lgamma(float64(k[i])+1) - lgamma(float64(n[i]-k[i])+1)
+	}
+	expanded := statmodel.NewDataset([][]statmodel.Dtype{ye, ie, xe},
+		[]string{"y", "icept", "x"})
+
+	config := DefaultConfig().WithFamily(NewFamily(BinomialFamily)).WithWeight("n").WithBinomialTrials(true)
+	model, err := NewGLM(grouped, "y", []string{"icept", "x"}, config)
+	if err != nil {
+		panic(err)
+	}
+	rg := model.Fit()
+
+	config = DefaultConfig().WithFamily(NewFamily(BinomialFamily))
+	model, err = NewGLM(expanded, "y", []string{"icept", "x"}, config)
+	if err != nil {
+		panic(err)
+	}
+	re := model.Fit()
+
+	if !floats.EqualApprox(rg.Params(), re.Params(), 1e-8) ||
+		!floats.EqualApprox(rg.StdErr(), re.StdErr(), 1e-6) {
+		t.Fail()
+	}
+	if !scalarClose(rg.LogLike(), re.LogLike()+lchoose, 1e-8) {
+		t.Logf("%f %f\n", rg.LogLike(), re.LogLike()+lchoose)
+		t.Fail()
+	}
+
+	// Invalid configurations and data
+	bad := []*Config{
+		DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithWeight("n").WithBinomialTrials(true),
+		DefaultConfig().WithFamily(NewFamily(BinomialFamily)).WithBinomialTrials(true),
+		DefaultConfig().WithFamily(NewFamily(BinomialFamily)).WithWeight("x").WithBinomialTrials(true),
+		DefaultConfig().WithFamily(NewFamily(BinomialFamily)).WithWeightVec([]float64{4, 5, 3, 6.5}).WithBinomialTrials(true),
+		DefaultConfig().WithFamily(NewFamily(BinomialFamily)).WithWeightVec([]float64{4, 5, 4, 6}).WithBinomialTrials(true),
+	}
+	for i, cfg := range bad {
+		if _, err := NewGLM(grouped, "y", []string{"icept", "x"}, cfg); err == nil {
+			t.Errorf("configuration %d should fail", i)
+		}
+	}
+}
+
+func TestPredictMeanCI(t *testing.T) {
+
+	config := DefaultConfig()
+	config.Family = NewFamily(BinomialFamily)
+	config.WeightVar = "w"
+	model, err := NewGLM(data2(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	lci, err := result.PredictMeanCI(nil, 0.95, MeanIntervalLink)
+	if err != nil {
+		panic(err)
+	}
+	dci, err := result.PredictMeanCI(nil, 0.95, MeanIntervalDelta)
+	if err != nil {
+		panic(err)
+	}
+
+	if !floats.EqualApprox(lci.Mean, result.PredictMean(nil), 1e-12) ||
+		!floats.EqualApprox(lci.StdErr, dci.StdErr, 1e-12) {
+		t.Fail()
+	}
+
+	x2 := data2().Data()[2]
+	vc := result.VCov()
+	lp := result.LinearPredictor(nil)
+	for i, mn := range lci.Mean {
+		x := []float64{1, float64(x2[i])}
+		var v float64
+		for j1 := range x {
+			for j2 := range x {
+				v += x[j1] * vc[2*j1+j2] * x[j2]
+			}
+		}
+		se := math.Sqrt(v)
+
+		// The derivative of the inverse logit link is mu*(1-mu)
+		if !scalarClose(dci.StdErr[i], mn*(1-mn)*se, 1e-10) {
+			t.Fail()
+		}
+		if !scalarClose(dci.LCB[i], mn-1.959964*dci.StdErr[i], 1e-6) ||
+			!scalarClose(dci.UCB[i], mn+1.959964*dci.StdErr[i], 1e-6) {
+			t.Fail()
+		}
+
+		expit := func(x float64) float64 { return 1 / (1 + math.Exp(-x)) }
+		if !scalarClose(lci.LCB[i], expit(lp[i]-1.959964*se), 1e-6) ||
+			!scalarClose(lci.UCB[i], expit(lp[i]+1.959964*se), 1e-6) {
+			t.Fail()
+		}
+		if !(lci.LCB[i] > 0 && lci.LCB[i] < mn && mn < lci.UCB[i] && lci.UCB[i] < 1) {
+			t.Fail()
+		}
+	}
+
+	// For the identity link the two intervals agree
+	config.Family = NewFamily(GaussianFamily)
+	model, err = NewGLM(data2(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result = model.Fit()
+	lci, _ = result.PredictMeanCI(nil, 0.9, MeanIntervalLink)
+	dci, _ = result.PredictMeanCI(nil, 0.9, MeanIntervalDelta)
+	if !floats.EqualApprox(lci.LCB, dci.LCB, 1e-10) || !floats.EqualApprox(lci.UCB, dci.UCB, 1e-10) {
+		t.Fail()
+	}
+}
+
+func TestPackedHessian(t *testing.T) {
+
+	for _, obs := range []bool{false, true} {
+		config := DefaultConfig().WithFamily(NewFamily(GammaFamily)).WithLink(NewLink(LogLink)).WithWeight("w").WithObservedInfo(obs)
+		model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+		if err != nil {
+			panic(err)
+		}
+		result := model.Fit()
+
+		pmodel, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config.WithPackedHessian(true))
+		if err != nil {
+			panic(err)
+		}
+		presult := pmodel.Fit()
+
+		if !floats.EqualApprox(result.VCov(), presult.VCov(), 1e-10) {
+			t.Errorf("observed=%v: %v != %v", obs, result.VCov(), presult.VCov())
+		}
+
+		par := &GLMParams{result.Params(), 1}
+		hess := make([]float64, 9)
+		model.Hessian(par, model.Information(), hess)
+		phess := make([]float64, 6)
+		pmodel.PackedHessian(par, model.Information(), phess)
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				if !scalarClose(hess[3*i+j], phess[statmodel.PackedIndex(i, j)], 1e-12) {
+					t.Fail()
+				}
+			}
+		}
+	}
+}
+
+func TestNumericalHessian(t *testing.T) {
+
+	xnames := []string{"x1", "x2", "x3"}
+
+	// A variance function that has no derivative
+	vari := &Variance{Name: "Squared", Var: squaredVar}
+
+	config := DefaultConfig().WithFamily(NewFamily(GammaFamily)).WithLink(NewLink(LogLink)).
+		WithVarFunc(vari).WithWeight("w").WithObservedInfo(true)
+	if _, err := NewGLM(data4(), "y", xnames, config); err == nil {
+		t.Fatal("expected an error for the missing variance derivative")
+	}
+
+	model, err := NewGLM(data4(), "y", xnames, config.WithNumericalHessian(true))
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	// The analytic observed information for the built-in gamma family
+	config = DefaultConfig().WithFamily(NewFamily(GammaFamily)).WithLink(NewLink(LogLink)).WithWeight("w").WithObservedInfo(true)
+	amodel, err := NewGLM(data4(), "y", xnames, config)
+	if err != nil {
+		panic(err)
+	}
+	aresult := amodel.Fit()
+
+	if !floats.EqualApprox(result.Params(), aresult.Params(), 1e-8) ||
+		!floats.EqualApprox(result.StdErr(), aresult.StdErr(), 1e-5) {
+		t.Logf("%v %v\n", result.StdErr(), aresult.StdErr())
+		t.Fail()
+	}
+}
+
+func TestDispersionStdErr(t *testing.T) {
+
+	// For the Gaussian family, the second derivative of the
+	// log-likelihood with respect to the scale, at the Pearson
+	// estimate, is (n/2 - (n - p)) / scale^2.
+	config := DefaultConfig()
+	model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+	se, err := result.DispersionStdErr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !scalarClose(se, result.Scale()*math.Sqrt(2), 1e-5) {
+		t.Errorf("got %v, expected %v", se, result.Scale()*math.Sqrt(2))
+	}
+	if !strings.Contains(result.Summary().String(), "Scale SE:") {
+		t.Fail()
+	}
+
+	// Overdispersed counts in two groups.  Since the means are
+	// saturated, the coefficients and alpha are orthogonal at the MLE,
+	// so the standard error from the profile likelihood agrees with
+	// the standard error at the estimated coefficients.
+	y := []statmodel.Dtype{0, 25, 5, 3, 0, 14, 1, 1, 8, 6}
+	x1 := []statmodel.Dtype{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+	x2 := []statmodel.Dtype{0, 1, 0, 1, 0, 1, 0, 1, 0, 1}
+	data := statmodel.NewDataset([][]statmodel.Dtype{y, x1, x2}, []string{"y", "x1", "x2"})
+	config = DefaultConfig().WithFamily(NewNegBinomFamily(1, NewLink(LogLink)))
+	model, err = NewGLM(data, "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	nb := NewNegBinomProfiler(model.Fit())
+	config = DefaultConfig().WithFamily(NewNegBinomFamily(nb.DispersionMLE(), NewLink(LogLink)))
+	model, err = NewGLM(data, "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result = model.Fit()
+	se, err = result.DispersionStdErr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !scalarClose(se, nb.DispersionStdErr(), 1e-3) {
+		t.Errorf("got %v, expected %v", se, nb.DispersionStdErr())
+	}
+	if !strings.Contains(result.Summary().String(), "Alpha:") {
+		t.Fail()
+	}
+
+	// The Poisson family has no dispersion parameter
+	config = DefaultConfig().WithFamily(NewFamily(PoissonFamily))
+	model, err = NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+	if err != nil {
+		panic(err)
+	}
+	if _, err := model.Fit().DispersionStdErr(); err == nil {
+		t.Fail()
+	}
+}
+
+func TestAdjustedPredictions(t *testing.T) {
+
+	config := DefaultConfig().WithFamily(NewFamily(PoissonFamily))
+	model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+	pa := result.Params()
+
+	// The mean of x3 in data4
+	x3 := 8.0 / 7
+
+	grid := []float64{-2, 0, 2}
+	ci, err := result.AdjustedPredictions("x2", grid, nil, 0.95, MeanIntervalLink)
+	if err != nil {
+		panic(err)
+	}
+	for i, x := range grid {
+		mn := math.Exp(pa[0] + pa[1]*x + pa[2]*x3)
+		if !scalarClose(ci.Mean[i], mn, 1e-8) {
+			t.Errorf("grid point %v: got %v, expected %v", x, ci.Mean[i], mn)
+		}
+		if !(ci.LCB[i] < ci.Mean[i] && ci.Mean[i] < ci.UCB[i]) {
+			t.Fail()
+		}
+	}
+
+	// Holding x3 at a given value
+	ci, err = result.AdjustedPredictions("x2", grid, map[string]float64{"x3": 1}, 0.95, MeanIntervalLink)
+	if err != nil {
+		panic(err)
+	}
+	for i, x := range grid {
+		if !scalarClose(ci.Mean[i], math.Exp(pa[0]+pa[1]*x+pa[2]), 1e-8) {
+			t.Fail()
+		}
+	}
+
+	if _, err := result.AdjustedPredictions("w", grid, nil, 0.95, MeanIntervalLink); err == nil {
+		t.Fail()
+	}
+	if _, err := result.AdjustedPredictions("x2", grid, map[string]float64{"y": 1}, 0.95, MeanIntervalLink); err == nil {
+		t.Fail()
+	}
+}
+
+func TestEventsPerParam(t *testing.T) {
+
+	// There are 7 cases with weights summing to 18, and 7 of the
+	// weighted cases have y = 1.
+	config := DefaultConfig().WithFamily(NewFamily(BinomialFamily)).WithWeight("w")
+	model, err := NewGLM(data2(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+	if !scalarClose(result.EventsPerParam(), 3.5, 1e-12) {
+		t.Errorf("got %v events per parameter, expected 3.5", result.EventsPerParam())
+	}
+	if !strings.Contains(result.Summary().String(), "3.5 events per parameter") {
+		t.Fail()
+	}
+
+	// Observations per parameter for other families
+	config = DefaultConfig().WithFamily(NewFamily(PoissonFamily))
+	model, err = NewGLM(data4(), "y", []string{"x1"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result = model.Fit()
+	if !scalarClose(result.EventsPerParam(), 7, 1e-12) {
+		t.Fail()
+	}
+	if !strings.Contains(result.Summary().String(), "7 observations per parameter") {
+		t.Fail()
+	}
+
+	// No warning with enough data
+	model, err = NewGLM(dataHet(100, false), "y", []string{"x1", "x2"}, nil)
+	if err != nil {
+		panic(err)
+	}
+	if strings.Contains(model.Fit().Summary().String(), "per parameter") {
+		t.Fail()
+	}
+}
+
+func TestNewtonRaphson(t *testing.T) {
+
+	xnames := []string{"x1", "x2", "x3"}
+	for _, q := range []struct {
+		fam  *Family
+		link *Link
+	}{
+		{NewFamily(GammaFamily), NewLink(LogLink)},
+		{NewFamily(PoissonFamily), NewLink(LogLink)},
+	} {
+		var results []*GLMResults
+		var iters []int
+		for _, newton := range []bool{false, true} {
+			config := DefaultConfig().WithFamily(q.fam).WithLink(q.link).WithWeight("w").
+				WithNewtonRaphson(newton)
+			model, err := NewGLM(data4(), "y", xnames, config)
+			if err != nil {
+				panic(err)
+			}
+			result := model.Fit()
+			fi, _ := result.FitInfo()
+			if !fi.Converged {
+				t.Errorf("%s/%s (newton=%v) did not converge", q.fam.Name, q.link.Name, newton)
+			}
+			results = append(results, result)
+			iters = append(iters, fi.Iterations)
+		}
+
+		// Fisher scoring converges linearly for the non-canonical
+		// link, so at the default tolerance the estimates agree to
+		// around five digits.
+		if !floats.EqualApprox(results[0].Params(), results[1].Params(), 1e-5) {
+			t.Errorf("%s/%s: Fisher scoring estimates %v differ from Newton-Raphson estimates %v",
+				q.fam.Name, q.link.Name, results[0].Params(), results[1].Params())
+		}
+
+		// The updates are the same for the canonical link, otherwise
+		// Newton-Raphson converges faster close to the MLE.
+		if q.fam.TypeCode == PoissonFamily && iters[0] != iters[1] {
+			t.Fail()
+		}
+		if q.fam.TypeCode == GammaFamily && iters[0] <= iters[1] {
+			t.Errorf("Fisher scoring took %d iterations, Newton-Raphson took %d", iters[0], iters[1])
+		}
+	}
+
+	// The observed information is not positive definite after the
+	// first update, so a Fisher scoring step is taken instead.
+	var results []*GLMResults
+	for _, newton := range []bool{false, true} {
+		config := DefaultConfig().WithFamily(NewFamily(GaussianFamily)).WithLink(NewLink(LogLink)).
+			WithNewtonRaphson(newton)
+		model, err := NewGLM(data4(), "y", []string{"x1", "x2"}, config)
+		if err != nil {
+			panic(err)
+		}
+		result, err := model.FitChecked()
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, result)
+	}
+	if !floats.EqualApprox(results[0].Params(), results[1].Params(), 1e-6) {
+		t.Errorf("%v != %v", results[0].Params(), results[1].Params())
+	}
+}
+
+func TestLinPredBound(t *testing.T) {
+
+	// The bound has no effect if the linear predictor is within it
+	xnames := []string{"x1", "x2", "x3"}
+	var results []*GLMResults
+	for _, b := range []float64{0, 30} {
+		config := DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithLinPredBound(b)
+		model, err := NewGLM(data4(), "y", xnames, config)
+		if err != nil {
+			panic(err)
+		}
+		results = append(results, model.Fit())
+	}
+	if !floats.Equal(results[0].Params(), results[1].Params()) {
+		t.Fail()
+	}
+
+	// Under perfect separation, the linear predictor and the fitted
+	// means remain bounded.
+	sep := statmodel.NewDataset([][]statmodel.Dtype{{0, 0, 0, 1, 1, 1}, {1, 1, 1, 1, 1, 1}, {1, 2, 3, 4, 5, 6}},
+		[]string{"y", "x1", "x2"})
+	config := DefaultConfig().WithFamily(NewFamily(BinomialFamily)).WithLinPredBound(3)
+	model, err := NewGLM(sep, "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result, err := model.FitChecked()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lb, ub := 1/(1+math.Exp(3)), 1/(1+math.Exp(-3))
+	for _, m := range result.Mean() {
+		if m < lb-1e-12 || m > ub+1e-12 {
+			t.Errorf("fitted mean %v is outside [%v, %v]", m, lb, ub)
+		}
+	}
+
+	// The log-likelihood is evaluated at the bounded linear predictor
+	ll := model.LogLike(&GLMParams{[]float64{-350, 100}, 1}, true)
+	if !scalarClose(ll, 6*math.Log(ub), 1e-10) {
+		t.Errorf("got log-likelihood %v, expected %v", ll, 6*math.Log(ub))
+	}
+
+	// The score and Hessian are the derivatives of the clamped
+	// log-likelihood, at parameters for which some of the linear
+	// predictors are clamped.
+	pa := []float64{-6.5, 2}
+	score := make([]float64, 2)
+	model.Score(&GLMParams{pa, 1}, score)
+	hess := make([]float64, 4)
+	model.Hessian(&GLMParams{pa, 1}, statmodel.ObsHess, hess)
+	nhess := make([]float64, 4)
+	model.numericalHessian(&GLMParams{pa, 1}, nhess)
+	for j := range pa {
+		h := 1e-6
+		pp := append([]float64(nil), pa...)
+		pm := append([]float64(nil), pa...)
+		pp[j] += h
+		pm[j] -= h
+		d := (model.LogLike(&GLMParams{pp, 1}, true) - model.LogLike(&GLMParams{pm, 1}, true)) / (2 * h)
+		if !scalarClose(score[j], d, 1e-5) {
+			t.Errorf("score %v, numerical derivative %v", score[j], d)
+		}
+	}
+	if !floats.EqualApprox(hess, nhess, 1e-5) {
+		t.Errorf("Hessian %v, numerical Hessian %v", hess, nhess)
+	}
+
+	// The linear predictor is clamped along with the means
+	for _, v := range result.LinearPredictor(nil) {
+		if math.Abs(v) > 3 {
+			t.Errorf("the linear predictor %v is not clamped", v)
+		}
+	}
+
+	if DefaultConfig().WithLinPredBound(-1).Validate() == nil {
+		t.Fail()
+	}
+}
+
+func TestPredictGroupTotals(t *testing.T) {
+
+	config := DefaultConfig().WithFamily(NewFamily(PoissonFamily))
+	model, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+	mn := result.Mean()
+	vc := result.VCov()
+	da := data4().Data()
+
+	groups := []statmodel.Dtype{3, 1, 1, 2, 2, 3, 3}
+	gt, err := result.PredictGroupTotals(nil, groups)
+	if err != nil {
+		panic(err)
+	}
+	if len(gt.Groups) != 3 || gt.Groups[0] != 1 || gt.Groups[2] != 3 {
+		t.Fatalf("unexpected groups %v", gt.Groups)
+	}
+
+	for k, g := range gt.Groups {
+
+		// For the log link, the gradient of each mean is mu * x
+		var tot float64
+		d := make([]float64, 3)
+		for i, h := range groups {
+			if h == g {
+				tot += mn[i]
+				for j := range d {
+					d[j] += mn[i] * float64(da[j+1][i])
+				}
+			}
+		}
+		var v float64
+		for j1 := range d {
+			for j2 := range d {
+				v += d[j1] * vc[3*j1+j2] * d[j2]
+			}
+		}
+		if !scalarClose(gt.Total[k], tot, 1e-10) || !scalarClose(gt.StdErr[k], math.Sqrt(v), 1e-10) {
+			t.Errorf("group %v: got %v (SE %v), expected %v (SE %v)", g, gt.Total[k], gt.StdErr[k], tot, math.Sqrt(v))
+		}
+	}
+
+	// With one observation per group, the standard errors are those of
+	// the predicted means.
+	gt, err = result.PredictGroupTotals(nil, []statmodel.Dtype{0, 1, 2, 3, 4, 5, 6})
+	if err != nil {
+		panic(err)
+	}
+	ci, err := result.PredictMeanCI(nil, 0.95, MeanIntervalDelta)
+	if err != nil {
+		panic(err)
+	}
+	if !floats.EqualApprox(gt.StdErr, ci.StdErr, 1e-10) {
+		t.Fail()
+	}
+
+	if _, err := result.PredictGroupTotals(nil, groups[0:3]); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,104 @@
+package glm
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/kshedden/statmodel/statmodel"
+)
+
+// PersonPeriod expands survival data with one row per subject into the
+// person-period format, with one row per subject for each period in
+// which the subject is at risk, for fitting a discrete-time hazard model.
+// The variable named by time contains the last period (a positive
+// integer) in which each subject is observed, and the variable named by
+// event is 1 if the event occurred in that period, and 0 if the subject
+// was censored at the end of that period.  Subject i contributes rows for
+// periods 1, ..., time[i].  In the returned dataset, the time variable is
+// replaced by a variable named "period" that contains the period of each
+// row, and the event variable is 1 only in the final period of a subject
+// whose event was observed.  The remaining variables are repeated on all
+// rows of a subject.
+//
+// A binomial GLM fit to the person-period data, with the event variable
+// as the response, models the discrete hazard: the probability that the
+// event occurs in a period, given that it has not occurred earlier.
+// With the cloglog link, the fit is the grouped-data version of the
+// proportional hazards model, so the coefficients of the covariates are
+// log hazard ratios, while the logit link gives a proportional odds
+// model.  The baseline hazard is usually modeled with indicators for the
+// periods, e.g. using statmodel.ExpandFactor on the period variable, or
+// with a smooth function of the period.
+func PersonPeriod(data statmodel.Dataset, time, event string) (statmodel.Dataset, error) {
+
+	names := data.Names()
+	da := data.Data()
+
+	tpos, epos := -1, -1
+	for j, na := range names {
+		switch na {
+		case time:
+			tpos = j
+		case event:
+			epos = j
+		case "period":
+			return nil, fmt.Errorf("PersonPeriod: the data already contain a variable named 'period'")
+		}
+	}
+	if tpos == -1 {
+		msg := fmt.Sprintf("PersonPeriod: time variable '%s' not found in dataset\n", time)
+		return nil, fmt.Errorf(msg)
+	}
+	if epos == -1 {
+		msg := fmt.Sprintf("PersonPeriod: event variable '%s' not found in dataset\n", event)
+		return nil, fmt.Errorf(msg)
+	}
+	if tpos == epos {
+		return nil, fmt.Errorf("PersonPeriod: the time and event variables must differ")
+	}
+
+	// Check the data and count the rows of the expanded data
+	var n int
+	for i, t := range da[tpos] {
+		if !(t >= 1) || t != math.Floor(t) || math.IsInf(float64(t), 0) {
+			msg := fmt.Sprintf("PersonPeriod: the time for subject %d is %v, times must be positive integers\n", i, t)
+			return nil, fmt.Errorf(msg)
+		}
+		if e := da[epos][i]; e != 0 && e != 1 {
+			msg := fmt.Sprintf("PersonPeriod: the event indicator for subject %d is %v, it must be 0 or 1\n", i, e)
+			return nil, fmt.Errorf(msg)
+		}
+		n += int(t)
+	}
+
+	pnames := make([]string, len(names))
+	copy(pnames, names)
+	pnames[tpos] = "period"
+
+	pdat := make([][]statmodel.Dtype, len(da))
+	for j := range pdat {
+		pdat[j] = make([]statmodel.Dtype, 0, n)
+	}
+
+	for i, t := range da[tpos] {
+		nt := int(t)
+		for k := 1; k <= nt; k++ {
+			for j := range da {
+				switch j {
+				case tpos:
+					pdat[j] = append(pdat[j], statmodel.Dtype(k))
+				case epos:
+					var y statmodel.Dtype
+					if k == nt {
+						y = da[epos][i]
+					}
+					pdat[j] = append(pdat[j], y)
+				default:
+					pdat[j] = append(pdat[j], da[j][i])
+				}
+			}
+		}
+	}
+
+	return statmodel.NewDataset(pdat, pnames), nil
+}
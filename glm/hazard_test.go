@@ -0,0 +1,77 @@
+package glm
+
+import (
+	"testing"
+
+	"github.com/kshedden/statmodel/statmodel"
+)
+
+func TestPersonPeriod(t *testing.T) {
+
+	time := []statmodel.Dtype{1, 3, 2, 3, 2, 1, 3, 2}
+	event := []statmodel.Dtype{1, 0, 1, 1, 0, 0, 1, 1}
+	x := []statmodel.Dtype{5, 6, 7, 8, 9, 10, 11, 12}
+	data := statmodel.NewDataset([][]statmodel.Dtype{time, event, x}, []string{"time", "event", "x"})
+
+	pp, err := PersonPeriod(data, "time", "event")
+	if err != nil {
+		panic(err)
+	}
+
+	if pp.Names()[0] != "period" || pp.Names()[1] != "event" || pp.Names()[2] != "x" {
+		t.Fail()
+	}
+
+	// The second subject is censored after three periods, the third
+	// has the event in the second period.
+	pd := pp.Data()
+	if len(pd[0]) != 17 {
+		t.Fatalf("expected 17 person-periods, got %d", len(pd[0]))
+	}
+	want := [][]statmodel.Dtype{
+		{1, 1, 2, 3, 1, 2},
+		{1, 0, 0, 0, 0, 1},
+		{5, 6, 6, 6, 7, 7},
+	}
+	for j := range want {
+		for i, v := range want[j] {
+			if pd[j][i] != v {
+				t.Errorf("column %d, row %d: got %v, expected %v", j, i, pd[j][i], v)
+			}
+		}
+	}
+
+	// With an indicator for each period, the fitted hazards are the
+	// life table hazards, the number of events divided by the number at
+	// risk in each period.
+	cols, dnames, err := statmodel.ExpandFactor(pd[0], "period", statmodel.TreatmentContrast)
+	if err != nil {
+		panic(err)
+	}
+	icept := make([]statmodel.Dtype, len(pd[0]))
+	for i := range icept {
+		icept[i] = 1
+	}
+	hdata := statmodel.NewDataset(append([][]statmodel.Dtype{pd[1], icept}, cols...),
+		append([]string{"event", "icept"}, dnames...))
+
+	config := DefaultConfig().WithFamily(NewFamily(BinomialFamily)).WithLink(NewLink(CloglogLink))
+	model, err := NewGLM(hdata, "event", append([]string{"icept"}, dnames...), config)
+	if err != nil {
+		panic(err)
+	}
+	mn := model.Fit().Mean()
+
+	hazard := map[statmodel.Dtype]float64{1: 1.0 / 8, 2: 2.0 / 6, 3: 2.0 / 3}
+	for i, p := range pd[0] {
+		if !scalarClose(mn[i], hazard[p], 1e-6) {
+			t.Errorf("period %v: hazard %v, expected %v", p, mn[i], hazard[p])
+		}
+	}
+
+	// Invalid times
+	data = statmodel.NewDataset([][]statmodel.Dtype{{1, 1.5}, {0, 1}}, []string{"time", "event"})
+	if _, err := PersonPeriod(data, "time", "event"); err == nil {
+		t.Fail()
+	}
+}
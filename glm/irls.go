@@ -10,10 +10,20 @@ import (
 	"gonum.org/v1/gonum/mat"
 )
 
-func (glm *GLM) fitIRLS(start []float64, maxiter int) []float64 {
-
-	// TODO make this configurable
-	dtol := 1e-8
+// fitIRLS fits the model using iteratively reweighted least squares,
+// returning the parameter estimates and information about convergence.
+// An error is returned if the deviance or the parameters become
+// non-finite.
+func (glm *GLM) fitIRLS(start []float64, maxiter int) ([]float64, FitInfo, error) {
+
+	crit := glm.convergence
+	if crit == 0 {
+		crit = ConvergeDeviance
+	}
+	tol := glm.convtol
+	if tol == 0 {
+		tol = DefaultConvergenceTol
+	}
 
 	linpred := glm.getNslice()
 	mn := glm.getNslice()
@@ -22,6 +32,14 @@ func (glm *GLM) fitIRLS(start []float64, maxiter int) []float64 {
 	irlsw := glm.getNslice()
 	adjy := glm.getNslice()
 
+	// The observed information factors for Newton-Raphson updates
+	var obsfac, lderiv2, vad []float64
+	if glm.newton {
+		obsfac = glm.getNslice()
+		lderiv2 = glm.getNslice()
+		vad = glm.getNslice()
+	}
+
 	var nparam mat.VecDense
 
 	nvar := glm.NumParams()
@@ -29,38 +47,34 @@ func (glm *GLM) fitIRLS(start []float64, maxiter int) []float64 {
 	xty := make([]float64, nvar)
 	xtx := make([]float64, nvar*nvar)
 
-	var params []float64
-	if start == nil {
-		params = make([]float64, nvar)
-	} else {
-		params = start
+	params := make([]float64, nvar)
+	if start != nil {
+		copy(params, start)
 	}
+	oldparams := make([]float64, nvar)
 
 	var dev []float64
+	var info FitInfo
 
 	xdat := make([][]statmodel.Dtype, len(glm.xpos))
 	for j, k := range glm.xpos {
 		xdat[j] = glm.data[k]
 	}
 
+	var wgt, off []statmodel.Dtype
+	if glm.weightpos != -1 {
+		wgt = glm.data[glm.weightpos]
+	}
+	if glm.offsetpos != -1 {
+		off = glm.data[glm.offsetpos]
+	}
+	yda := glm.data[glm.ypos]
+
 	// IRLS iterations
-	for iter := 0; iter < maxiter; iter++ {
+	for iter := 0; ; iter++ {
 
 		zero(xtx)
 		zero(xty)
-		var devi float64
-
-		// Loop over data chunks
-		var wgt, off []statmodel.Dtype
-
-		yda := glm.data[glm.ypos]
-
-		if glm.weightpos != -1 {
-			wgt = glm.data[glm.weightpos]
-		}
-		if glm.offsetpos != -1 {
-			off = glm.data[glm.offsetpos]
-		}
 
 		zero(linpred)
 		for j := range glm.xpos {
@@ -74,77 +88,100 @@ func (glm *GLM) fitIRLS(start []float64, maxiter int) []float64 {
 				linpred[i] += float64(off[i])
 			}
 		}
+		glm.boundLinpred(linpred)
 
 		if iter == 0 {
 			glm.startingMu(yda, mn)
 		} else {
 			glm.link.InvLink(linpred, mn)
+			glm.clampMean(mn)
 		}
 
 		glm.link.Deriv(mn, lderiv)
 		glm.vari.Var(mn, va)
 
-		devi += glm.fam.Deviance(yda, mn, wgt, 1)
+		devi := glm.fam.Deviance(yda, mn, wgt, 1)
+		if math.IsNaN(devi) || math.IsInf(devi, 0) || !allFinite(params) {
+			return nil, info, glm.nonFiniteError(fmt.Sprintf("IRLS iteration %d", iter), params)
+		}
 
-		// Create weights for WLS
-		if wgt != nil {
-			for i := range yda {
-				irlsw[i] = float64(wgt[i]) / (lderiv[i] * lderiv[i] * va[i])
-			}
-		} else {
-			for i := range yda {
-				irlsw[i] = 1 / (lderiv[i] * lderiv[i] * va[i])
-			}
+		if glm.log != nil && iter > 0 {
+			msg := fmt.Sprintf("Iteration %d: deviance=%.10f\n", iter, devi)
+			glm.log.Print(msg)
 		}
 
-		// Create an adjusted response for WLS
-		if off == nil {
-			for i := range yda {
-				adjy[i] = linpred[i] + lderiv[i]*(float64(yda[i])-mn[i])
-			}
-		} else {
-			for i := range yda {
-				adjy[i] = linpred[i] + lderiv[i]*(float64(yda[i])-mn[i]) - float64(off[i])
+		if iter == maxiter {
+			break
+		}
+		dev = append(dev, devi)
+
+		// The first update starts from the initial means rather than
+		// the means at the starting parameters, so it uses Fisher
+		// scoring.
+		var fac []float64
+		if glm.newton && iter > 0 {
+			glm.link.Deriv2(mn, lderiv2)
+			glm.vari.Deriv(mn, vad)
+			scoreFactor(yda, mn, lderiv, va, obsfac)
+			for i := range obsfac {
+				obsfac[i] = 1 + (va[i]*lderiv2[i]+lderiv[i]*vad[i])*obsfac[i]
 			}
+			fac = obsfac
 		}
 
-		// Update the weighted moment matrices.  For large data sets, this is by far the
-		// most expensive step.
-		glm.irlsXprod(xdat, adjy, irlsw, xty, xtx)
+		for {
+			glm.irlsMoments(xdat, yda, wgt, off, linpred, mn, lderiv, va, fac, irlsw, adjy, xty, xtx)
 
-		// Fill in the unfilled triangle of xtx
-		for j1 := range glm.xpos {
-			for j2 := j1 + 1; j2 < nvar; j2++ {
-				xtx[j1*nvar+j2] = xtx[j2*nvar+j1]
+			// Account for the ridge penalty
+			if glm.l2wgt != nil {
+				nobs := float64(len(yda))
+				for j, v := range glm.l2wgt {
+					xtx[j*nvar+j] += nobs * v
+				}
 			}
+
+			// Far from the MLE, the observed information may not be
+			// positive definite, in which case a Fisher scoring step
+			// is taken instead.
+			var chol mat.Cholesky
+			if fac == nil || chol.Factorize(mat.NewSymDense(nvar, xtx)) {
+				break
+			}
+			fac = nil
+			zero(xtx)
+			zero(xty)
 		}
 
 		// Update the parameters
 		xtxm := mat.NewDense(nvar, nvar, xtx)
 		xtyv := mat.NewVecDense(nvar, xty)
-		err := nparam.SolveVec(xtxm, xtyv)
-		if err != nil {
-			for j := 0; j < nvar; j++ {
-				fmt.Printf("%8d %12.4f %12.4f\n", j, xty[j], xtx[j*nvar+j])
-			}
-			panic(err)
+		if err := nparam.SolveVec(xtxm, xtyv); err != nil {
+			msg := fmt.Sprintf("IRLS iteration %d: the weighted cross-product matrix X'WX is singular or nearly singular, "+
+				"the covariates may be collinear: %v\n", iter+1, err)
+			return nil, info, fmt.Errorf(msg)
 		}
-		params = nparam.RawVector().Data
-
-		// Check convergence
-		dev = append(dev, devi)
-		if len(dev) > 3 && math.Abs(dev[len(dev)-1]-dev[len(dev)-2]) < dtol {
+		copy(oldparams, params)
+		copy(params, nparam.RawVector().Data)
+		info.Iterations++
+
+		// Check convergence after the update, so that the updated
+		// parameters are returned
+		if c := glm.converged(crit, tol, dev, params, oldparams, xdat, yda, mn, lderiv, va, wgt); c != 0 {
+			if !allFinite(params) {
+				return nil, info, glm.nonFiniteError(fmt.Sprintf("IRLS iteration %d", iter+1), params)
+			}
+			info.Converged = true
+			info.Criterion = c
 			break
 		}
-
-		if glm.log != nil {
-			msg := fmt.Sprintf("Iteration %d: deviance=%.10f\n", iter+1, devi)
-			glm.log.Print(msg)
-		}
 	}
 
 	if glm.log != nil {
-		glm.log.Print("IRLS converged\n")
+		if info.Converged {
+			glm.log.Printf("IRLS converged (%s)\n", info.Criterion)
+		} else {
+			glm.log.Print("IRLS did not converge\n")
+		}
 	}
 
 	glm.putNslice(linpred)
@@ -153,8 +190,192 @@ func (glm *GLM) fitIRLS(start []float64, maxiter int) []float64 {
 	glm.putNslice(lderiv)
 	glm.putNslice(irlsw)
 	glm.putNslice(adjy)
+	if glm.newton {
+		glm.putNslice(obsfac)
+		glm.putNslice(lderiv2)
+		glm.putNslice(vad)
+	}
+
+	return params, info, nil
+}
+
+// irlsMoments calculates the weighted moment matrices X'WX and X'Wz for
+// a weighted least squares step of IRLS, given the linear predictor,
+// mean, link derivative, and variance at the current parameters.  The
+// weights and adjusted response are written to irlsw and adjy, and the
+// moments are added to xty and xtx, which must be zeroed by the caller.
+// If obsfac is not nil, the weights are multiplied by obsfac, so that
+// the update is a Newton-Raphson step using the observed information.
+// In this case the adjusted response excludes the residuals, and the
+// score is added to xty directly, since obsfac may be zero.
+func (glm *GLM) irlsMoments(xdat [][]statmodel.Dtype, yda, wgt, off []statmodel.Dtype,
+	linpred, mn, lderiv, va, obsfac, irlsw, adjy, xty, xtx []float64) {
+
+	nvar := len(xdat)
+
+	// Create weights for WLS
+	if wgt != nil {
+		for i := range yda {
+			irlsw[i] = float64(wgt[i]) / (lderiv[i] * lderiv[i] * va[i])
+		}
+	} else {
+		for i := range yda {
+			irlsw[i] = 1 / (lderiv[i] * lderiv[i] * va[i])
+		}
+	}
+
+	// Create an adjusted response for WLS
+	for i := range yda {
+		adjy[i] = linpred[i]
+		if obsfac == nil {
+			adjy[i] += lderiv[i] * (float64(yda[i]) - mn[i])
+		}
+		if off != nil {
+			adjy[i] -= float64(off[i])
+		}
+	}
 
-	return params
+	// Add the score, X' W G (y - mu), where G contains the link
+	// derivatives, before the weights are modified.
+	if obsfac != nil {
+		for j := range xdat {
+			var u float64
+			for i, x := range xdat[j] {
+				u += float64(x) * irlsw[i] * lderiv[i] * (float64(yda[i]) - mn[i])
+			}
+			xty[j] += u
+		}
+		for i := range irlsw {
+			irlsw[i] *= obsfac[i]
+		}
+	}
+
+	// Update the weighted moment matrices.  For large data sets, this is by far the
+	// most expensive step.
+	glm.irlsXprod(xdat, adjy, irlsw, xty, xtx)
+
+	// Fill in the unfilled triangle of xtx
+	for j1 := range glm.xpos {
+		for j2 := j1 + 1; j2 < nvar; j2++ {
+			xtx[j1*nvar+j2] = xtx[j2*nvar+j1]
+		}
+	}
+}
+
+// IRLSMoments returns the weighted moment matrices X'WX (stored by row
+// as a p x p matrix) and X'Wz (a vector of length p) for an iteration
+// of IRLS at the given parameters, where W contains the IRLS weights
+// and z is the adjusted response, excluding any offset.  The IRLS update
+// of the parameters is the solution b of (X'WX) b = X'Wz.  The moments
+// are sums over the observations, so in distributed fitting the moments
+// for several blocks of data evaluated at common parameters can be
+// summed and then solved for the updated parameters.  The moments do not
+// include any penalty.
+func (glm *GLM) IRLSMoments(params []float64) ([]float64, []float64) {
+
+	nvar := glm.NumParams()
+	if len(params) != nvar {
+		msg := fmt.Sprintf("IRLSMoments: params has length %d, but the model has %d parameters\n", len(params), nvar)
+		panic(msg)
+	}
+
+	xdat := make([][]statmodel.Dtype, len(glm.xpos))
+	for j, k := range glm.xpos {
+		xdat[j] = glm.data[k]
+	}
+
+	var wgt, off []statmodel.Dtype
+	if glm.weightpos != -1 {
+		wgt = glm.data[glm.weightpos]
+	}
+	if glm.offsetpos != -1 {
+		off = glm.data[glm.offsetpos]
+	}
+	yda := glm.data[glm.ypos]
+
+	linpred := glm.LinearPredictor(&GLMParams{params, 1}, nil)
+	glm.boundLinpred(linpred)
+	mn := make([]float64, len(linpred))
+	glm.link.InvLink(linpred, mn)
+	glm.clampMean(mn)
+
+	lderiv := make([]float64, len(mn))
+	va := make([]float64, len(mn))
+	glm.link.Deriv(mn, lderiv)
+	glm.vari.Var(mn, va)
+
+	irlsw := make([]float64, len(mn))
+	adjy := make([]float64, len(mn))
+	xty := make([]float64, nvar)
+	xtx := make([]float64, nvar*nvar)
+	glm.irlsMoments(xdat, yda, wgt, off, linpred, mn, lderiv, va, nil, irlsw, adjy, xty, xtx)
+
+	return xtx, xty
+}
+
+// converged returns the first of the given convergence criteria that is
+// satisfied after an IRLS update from oldparams to params, or zero if
+// none of the criteria are satisfied.  The deviance values at the
+// parameters before each update are in dev, and the mean, link
+// derivative, and variance at oldparams are in mn, lderiv, and va.
+func (glm *GLM) converged(crit ConvergenceCriterion, tol float64, dev, params, oldparams []float64,
+	xdat [][]statmodel.Dtype, yda []statmodel.Dtype, mn, lderiv, va []float64, wgt []statmodel.Dtype) ConvergenceCriterion {
+
+	if crit&ConvergeDeviance != 0 && len(dev) > 3 {
+		d1 := dev[len(dev)-1]
+		d0 := dev[len(dev)-2]
+		if math.Abs(d1-d0) < tol {
+			return ConvergeDeviance
+		}
+	}
+
+	if crit&ConvergeRelDeviance != 0 && len(dev) > 3 {
+		d1 := dev[len(dev)-1]
+		d0 := dev[len(dev)-2]
+		if math.Abs(d1-d0)/(math.Abs(d1)+0.1) < tol {
+			return ConvergeRelDeviance
+		}
+	}
+
+	if crit&ConvergeParams != 0 {
+		var mx float64
+		for j := range params {
+			mx = math.Max(mx, math.Abs(params[j]-oldparams[j]))
+		}
+		if mx < tol {
+			return ConvergeParams
+		}
+	}
+
+	if crit&ConvergeGradient != 0 {
+		var mx, ws float64
+		for j := range xdat {
+			var g float64
+			for i, y := range yda {
+				r := (float64(y) - mn[i]) / (lderiv[i] * va[i])
+				if wgt != nil {
+					r *= float64(wgt[i])
+				}
+				g += r * float64(xdat[j][i])
+			}
+			if glm.l2wgt != nil {
+				g -= float64(len(yda)) * glm.l2wgt[j] * params[j]
+			}
+			mx = math.Max(mx, math.Abs(g))
+		}
+		if wgt == nil {
+			ws = float64(len(yda))
+		} else {
+			for _, w := range wgt {
+				ws += float64(w)
+			}
+		}
+		if mx/ws < tol {
+			return ConvergeGradient
+		}
+	}
+
+	return 0
 }
 
 func (glm *GLM) irlsXprod(xdat [][]statmodel.Dtype, adjy, irlsw, xty, xtx []float64) {
@@ -246,3 +467,10 @@ func (glm *GLM) startingMu(y []statmodel.Dtype, mn []float64) {
 		}
 	}
 }
+
+// IRLSMoments returns the weighted moment matrices X'WX and X'Wz at the
+// fitted parameters, see GLM.IRLSMoments.
+func (rslt *GLMResults) IRLSMoments() ([]float64, []float64) {
+	model := rslt.Model().(*GLM)
+	return model.IRLSMoments(rslt.Params())
+}
@@ -41,6 +41,7 @@ const (
 	RecipLink
 	RecipSquaredLink
 	PowerLink
+	CustomLink
 )
 
 // NewLink returns a link function object corresponding to the given
@@ -122,7 +123,11 @@ var recipSquaredLink = Link{
 }
 
 // NewPowerLink returns the power link eta = mu^pw.  If
-// pw = 0 returns the log link.
+// pw = 0 returns the log link.  The inverse link is mu = eta^(1/pw),
+// and the derivative is pw*mu^(pw-1).  The power link can be used
+// with the gamma and inverse Gaussian families to model the mean
+// flexibly, e.g. pw = -1 and pw = -2 give the canonical links for
+// these two families.
 func NewPowerLink(pw float64) *Link {
 
 	if pw == 0 {
@@ -139,6 +144,63 @@ func NewPowerLink(pw float64) *Link {
 	}
 }
 
+// NewCustomLink returns a user-defined link function, which is specified
+// through scalar functions computing the link, its inverse, and its
+// derivative.  The second derivative of the link function, which is
+// needed for the observed Hessian, is obtained by numerically
+// differentiating deriv.  The range of the mean is not constrained for
+// custom links, and custom links are not checked for compatibility with
+// the family.
+func NewCustomLink(name string, link, invlink, deriv func(float64) float64) *Link {
+
+	if link == nil || invlink == nil || deriv == nil {
+		panic("NewCustomLink: the link, inverse link, and derivative functions must not be nil")
+	}
+	if name == "" {
+		panic("NewCustomLink: the link must have a name")
+	}
+
+	vec := func(f func(float64) float64) VecFunc {
+		return func(x []float64, y []float64) {
+			for i := range x {
+				y[i] = f(x[i])
+			}
+		}
+	}
+
+	// Central difference approximation to the derivative of deriv,
+	// with a step size that is relative to the magnitude of the
+	// argument.
+	deriv2 := func(x float64) float64 {
+		h := 1e-5 * math.Max(1, math.Abs(x))
+		return (deriv(x+h) - deriv(x-h)) / (2 * h)
+	}
+
+	return &Link{
+		Name:     name,
+		TypeCode: CustomLink,
+		Link:     vec(link),
+		InvLink:  vec(invlink),
+		Deriv:    vec(deriv),
+		Deriv2:   vec(deriv2),
+	}
+}
+
+// meanBounds returns the lower and upper limits of the values that
+// can be produced by the inverse link function.
+func (link *Link) meanBounds() (float64, float64) {
+
+	switch link.TypeCode {
+	case LogLink, PowerLink:
+		// The power link is only defined for positive means.
+		return 0, math.Inf(1)
+	case LogitLink, CloglogLink:
+		return 0, 1
+	default:
+		return math.Inf(-1), math.Inf(1)
+	}
+}
+
 func logFunc(x []float64, y []float64) {
 	for i := range x {
 		y[i] = math.Log(x[i])
@@ -204,7 +266,7 @@ func idDeriv2Func(x []float64, y []float64) {
 
 func cloglogFunc(x []float64, y []float64) {
 	for i, v := range x {
-		y[i] = math.Log(-math.Log(1 - v))
+		y[i] = math.Log(-math.Log1p(-v))
 	}
 }
 
@@ -224,7 +286,8 @@ func cloglogDeriv2Func(x []float64, y []float64) {
 
 func cloglogInvFunc(x []float64, y []float64) {
 	for i, v := range x {
-		y[i] = 1 - math.Exp(-math.Exp(v))
+		// Expm1 retains precision when exp(v) is small
+		y[i] = -math.Expm1(-math.Exp(v))
 	}
 }
 
@@ -2,6 +2,7 @@ package glm
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/kshedden/statmodel/statmodel"
@@ -74,9 +75,9 @@ var pq = []ptlsh{
 		exphess: []float64{-40.50897618, -144.25622765, -47.39149341,
 			-144.25622765, -678.14114997, -178.31768404,
 			-47.39149341, -178.31768404, -115.39745549},
-		obshess: []float64{45.05775111, 654.03495551, 518.19721783,
-			654.03495551, 4621.88794831, 2715.89536808,
-			518.19721783, 2715.89536808, 941.69106211},
+		obshess: []float64{-62.98977043, -195.76521145, -25.52822721,
+			-195.76521145, -719.47178018, -54.69005023,
+			-25.52822721, -54.69005023, -92.59543894},
 	},
 	{
 		title:  "Binomial unweighted 1",
@@ -150,9 +151,9 @@ var pq = []ptlsh{
 		exphess: []float64{-6.54801803, -14.02138681, -0.8840382,
 			-14.02138681, -50.90492947, -3.13023238,
 			-0.8840382, -3.13023238, -8.54267285},
-		obshess: []float64{-9.57814454, -24.11165106, -9.90658666,
-			-24.11165106, -100.95443538, -20.30041455,
-			-9.90658666, -20.30041455, -12.13755286},
+		obshess: []float64{-11.00897222, -22.95985132, -9.98178171,
+			-22.95985132, -107.12157279, -13.45815514,
+			-9.98178171, -13.45815514, -19.71245509},
 	},
 	{
 		title:  "Poisson unweighted 3",
@@ -245,3 +246,119 @@ func TestLLScoreHess(t *testing.T) {
 		}
 	}
 }
+
+func TestExtremeBinomial(t *testing.T) {
+
+	y := []statmodel.Dtype{0, 1, 0, 1, 1, 0, 1}
+	icept := []statmodel.Dtype{1, 1, 1, 1, 1, 1, 1}
+	x := []statmodel.Dtype{-80, -3, -1, 0, 0.5, 0.55, 3}
+	data := statmodel.NewDataset([][]statmodel.Dtype{y, icept, x}, []string{"y", "icept", "x"})
+
+	for _, lt := range []LinkType{LogitLink, CloglogLink} {
+
+		config := DefaultConfig().WithFamily(NewFamily(BinomialFamily)).WithLink(NewLink(lt))
+		model, err := NewGLM(data, "y", []string{"icept", "x"}, config)
+		if err != nil {
+			panic(err)
+		}
+
+		// The linear predictors range from -800 to 30, so that some of
+		// the means are exactly 0 or 1 in floating point.
+		params := []float64{0, 10}
+		ll := model.LogLike(&GLMParams{params, 1}, false)
+		score := make([]float64, 2)
+		model.Score(&GLMParams{params, 1}, score)
+		if math.IsNaN(ll) || math.IsInf(ll, 0) || math.IsNaN(score[0]) || math.IsInf(score[0], 0) ||
+			math.IsNaN(score[1]) || math.IsInf(score[1], 0) {
+			t.Errorf("link %v: log-likelihood %v, score %v", lt, ll, score)
+		}
+
+		// The observations in the tails contribute the limiting values
+		var want float64
+		for i, v := range x {
+			lp := 10 * float64(v)
+			switch lt {
+			case LogitLink:
+				want += float64(y[i])*lp - math.Max(lp, 0) - math.Log1p(math.Exp(-math.Abs(lp)))
+			case CloglogLink:
+				e := math.Exp(lp)
+				if y[i] == 1 {
+					want += math.Log(-math.Expm1(-e))
+				} else {
+					want -= e
+				}
+			}
+		}
+		if !scalarClose(ll, want, 1e-8) {
+			t.Errorf("link %v: log-likelihood %v, expected %v", lt, ll, want)
+		}
+
+		// The score agrees with numerical differentiation
+		params = []float64{0.5, 2}
+		model.Score(&GLMParams{params, 1}, score)
+		for j := range params {
+			pp := []float64{params[0], params[1]}
+			pm := []float64{params[0], params[1]}
+			pp[j] += 1e-6
+			pm[j] -= 1e-6
+			d := (model.LogLike(&GLMParams{pp, 1}, false) - model.LogLike(&GLMParams{pm, 1}, false)) / 2e-6
+			if !scalarClose(score[j], d, 1e-4) {
+				t.Errorf("link %v: score %v, numerical derivative %v", lt, score[j], d)
+			}
+		}
+	}
+
+	// Means that are exactly 0 or 1 and agree with the responses
+	// contribute zero.
+	ll := binomialLogLike([]statmodel.Dtype{0, 1}, []float64{0, 1}, nil, 1, false)
+	if ll != 0 {
+		t.Fail()
+	}
+}
+
+// For a canonical link, the link derivative is 1/V(mu), so the term of
+// the observed information that involves the residuals, which is
+// proportional to V*g2 + g1*dV for link derivatives g1, g2 and variance
+// derivative dV, vanishes and the observed and expected Hessians are
+// equal at all parameter values.
+func TestCanonicalInformation(t *testing.T) {
+
+	for _, q := range []struct {
+		title  string
+		family *Family
+		link   *Link
+		data   statmodel.Dataset
+		xnames []string
+		params []float64
+	}{
+		{"Binomial", NewFamily(BinomialFamily), NewLink(LogitLink), data2(), []string{"x1", "x2", "x3"}, []float64{0.2, -0.3, 0.1}},
+		{"Poisson", NewFamily(PoissonFamily), NewLink(LogLink), data4(), []string{"x1", "x2", "x3"}, []float64{0.5, 0.1, -0.2}},
+		{"Gaussian", NewFamily(GaussianFamily), NewLink(IdentityLink), data4(), []string{"x1", "x2", "x3"}, []float64{1, 0.2, -0.1}},
+		{"Gamma", NewFamily(GammaFamily), NewLink(RecipLink), data4(), []string{"x1", "x2"}, []float64{0.5, 0.01}},
+		{"Gamma power", NewFamily(GammaFamily), NewPowerLink(-1), data4(), []string{"x1", "x2"}, []float64{0.5, 0.01}},
+		{"InvGaussian", NewFamily(InvGaussianFamily), NewLink(RecipSquaredLink), data4(), []string{"x1", "x2"}, []float64{0.5, 0.01}},
+		{"Tweedie", NewTweedieFamily(1.5, NewPowerLink(-0.5)), NewPowerLink(-0.5), data4(), []string{"x1", "x2"}, []float64{0.5, 0.01}},
+	} {
+		for _, weight := range []bool{false, true} {
+			config := DefaultConfig().WithFamily(q.family).WithLink(q.link)
+			if weight {
+				config = config.WithWeight("w")
+			}
+			model, err := NewGLM(q.data, "y", q.xnames, config)
+			if err != nil {
+				panic(err)
+			}
+
+			p := len(q.params)
+			par := &GLMParams{q.params, 1}
+			exphess := make([]float64, p*p)
+			obshess := make([]float64, p*p)
+			model.Hessian(par, statmodel.ExpHess, exphess)
+			model.Hessian(par, statmodel.ObsHess, obshess)
+			if !floats.EqualApprox(exphess, obshess, 1e-8) {
+				t.Errorf("%s (weighted=%v): observed Hessian %v differs from expected Hessian %v",
+					q.title, weight, obshess, exphess)
+			}
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package glm
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/kshedden/statmodel/statmodel"
+)
+
+// PathConfig defines configuration parameters for fitting a
+// regularization path.
+type PathConfig struct {
+
+	// Alpha is the elastic net mixing parameter, which must lie in
+	// (0, 1].  For penalty strength lambda, the L1 penalty weight is
+	// alpha*lambda and the L2 penalty weight is (1-alpha)*lambda.  If
+	// zero, alpha is set to 1 (the lasso).
+	Alpha float64
+
+	// Lambda is a decreasing sequence of penalty strengths.  If not
+	// provided, a sequence is generated that decreases geometrically
+	// from the smallest penalty at which all penalized coefficients
+	// are zero.
+	Lambda []float64
+
+	// NumLambda is the number of penalty strengths in the generated
+	// sequence, 50 by default.
+	NumLambda int
+
+	// LambdaRatio is the ratio of the smallest to the largest penalty
+	// strength in the generated sequence, 0.001 by default.
+	LambdaRatio float64
+}
+
+// PathResults contains the fitted coefficients and deviances along a
+// regularization path.
+type PathResults struct {
+
+	// The covariate names, in the order of the coefficients.
+	Names []string
+
+	// The penalty strengths
+	Lambda []float64
+
+	// Params[i] contains the coefficients for penalty strength
+	// Lambda[i].
+	Params [][]float64
+
+	// Deviance[i] is the (unscaled) deviance for penalty strength
+	// Lambda[i].
+	Deviance []float64
+}
+
+// NumNonzero returns the number of nonzero coefficients at each point
+// on the path.
+func (pr *PathResults) NumNonzero() []int {
+
+	nz := make([]int, len(pr.Params))
+	for i, pa := range pr.Params {
+		for _, v := range pa {
+			if v != 0 {
+				nz[i]++
+			}
+		}
+	}
+
+	return nz
+}
+
+// FitPath fits a sequence of elastic net regularized GLMs, using the
+// data and model specification in config, at a decreasing sequence of
+// penalty strengths.  Each fit is started at the solution of the
+// previous fit.  All predictors are penalized equally, except for the
+// variables named in config.PenaltyExempt, which are not penalized.
+// Any L1 and L2 penalties in config are ignored.  The penalties are
+// scaled as for the L1Penalty and L2Penalty configuration values.
+func FitPath(data statmodel.Dataset, outcome string, predictors []string, config *Config, pconfig *PathConfig) (*PathResults, error) {
+
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if pconfig == nil {
+		pconfig = &PathConfig{}
+	}
+
+	alpha := pconfig.Alpha
+	if alpha == 0 {
+		alpha = 1
+	}
+	if alpha < 0 || alpha > 1 {
+		msg := fmt.Sprintf("FitPath: alpha must lie in (0, 1], got %f\n", alpha)
+		return nil, fmt.Errorf(msg)
+	}
+
+	exempt := make(map[string]bool)
+	for _, na := range config.PenaltyExempt {
+		exempt[na] = true
+	}
+
+	lambda := pconfig.Lambda
+	if lambda == nil {
+		lmax, err := lambdaMax(data, outcome, predictors, config, exempt, alpha)
+		if err != nil {
+			return nil, err
+		}
+		lambda = lambdaSeq(lmax, pconfig.NumLambda, pconfig.LambdaRatio)
+	}
+	for i := 1; i < len(lambda); i++ {
+		if lambda[i] > lambda[i-1] {
+			msg := "FitPath: the lambda values must be decreasing\n"
+			return nil, fmt.Errorf(msg)
+		}
+	}
+
+	pr := &PathResults{
+		Names:  predictors,
+		Lambda: lambda,
+	}
+
+	start := make([]float64, len(predictors))
+	cfg := *config
+	for _, lam := range lambda {
+
+		l1 := make(map[string]float64)
+		l2 := make(map[string]float64)
+		for _, na := range predictors {
+			if !exempt[na] {
+				l1[na] = alpha * lam
+				if alpha < 1 {
+					l2[na] = (1 - alpha) * lam
+				}
+			}
+		}
+		cfg.L1Penalty = l1
+		cfg.L2Penalty = l2
+
+		// The fit modifies the starting values in-place
+		cfg.Start = make([]float64, len(start))
+		copy(cfg.Start, start)
+
+		model, err := NewGLM(data, outcome, predictors, &cfg)
+		if err != nil {
+			return nil, err
+		}
+		rslt, err := model.FitChecked()
+		if err != nil {
+			return nil, err
+		}
+
+		params := make([]float64, len(rslt.Params()))
+		copy(params, rslt.Params())
+		pr.Params = append(pr.Params, params)
+		pr.Deviance = append(pr.Deviance, rslt.Deviance())
+		copy(start, params)
+	}
+
+	return pr, nil
+}
+
+// lambdaMax returns the smallest penalty strength for which all of the
+// penalized coefficients are zero.  This is determined by the score of
+// the full model, evaluated at the fit of the model containing only the
+// unpenalized variables.
+func lambdaMax(data statmodel.Dataset, outcome string, predictors []string, config *Config,
+	exempt map[string]bool, alpha float64) (float64, error) {
+
+	cfg := *config
+	cfg.L1Penalty = nil
+	cfg.L2Penalty = nil
+	cfg.PenaltyExempt = nil
+	cfg.Start = nil
+
+	var unpen []string
+	for _, na := range predictors {
+		if exempt[na] {
+			unpen = append(unpen, na)
+		}
+	}
+
+	nmodel, err := NewGLM(data, outcome, unpen, &cfg)
+	if err != nil {
+		return 0, err
+	}
+	nrslt, err := nmodel.FitChecked()
+	if err != nil {
+		return 0, err
+	}
+	nparams := nrslt.Params()
+
+	model, err := NewGLM(data, outcome, predictors, &cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	params := make([]float64, len(predictors))
+	var k int
+	for j, na := range predictors {
+		if exempt[na] {
+			params[j] = nparams[k]
+			k++
+		}
+	}
+
+	score := make([]float64, len(predictors))
+	model.Score(&GLMParams{params, 1}, score)
+
+	var lmax float64
+	for j, na := range predictors {
+		if !exempt[na] {
+			lmax = math.Max(lmax, math.Abs(score[j]))
+		}
+	}
+
+	return lmax / (float64(model.NumObs()) * alpha), nil
+}
+
+// lambdaSeq returns a geometric sequence of n values, decreasing from
+// lmax to ratio*lmax.
+func lambdaSeq(lmax float64, n int, ratio float64) []float64 {
+
+	if n <= 0 {
+		n = 50
+	}
+	if ratio <= 0 {
+		ratio = 0.001
+	}
+
+	lambda := make([]float64, n)
+	for i := range lambda {
+		if n == 1 {
+			lambda[i] = lmax
+		} else {
+			lambda[i] = lmax * math.Pow(ratio, float64(i)/float64(n-1))
+		}
+	}
+
+	return lambda
+}
@@ -0,0 +1,99 @@
+package glm
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+
+	"github.com/kshedden/statmodel/statmodel"
+)
+
+func dataPath(n int, binary bool) statmodel.Dataset {
+
+	rng := rand.New(rand.NewSource(139))
+	coeff := []float64{0.5, 1, 0, -0.5, 0}
+	da := make([][]statmodel.Dtype, 6)
+	for i := 0; i < n; i++ {
+		lp := coeff[0]
+		da[1] = append(da[1], 1)
+		for j := 2; j < 6; j++ {
+			x := rng.NormFloat64()
+			da[j] = append(da[j], statmodel.Dtype(x))
+			lp += coeff[j-1] * x
+		}
+		var y float64
+		if binary {
+			if rng.Float64() < 1/(1+math.Exp(-lp)) {
+				y = 1
+			}
+		} else {
+			y = lp + rng.NormFloat64()
+		}
+		da[0] = append(da[0], statmodel.Dtype(y))
+	}
+
+	return statmodel.NewDataset(da, []string{"y", "icept", "x1", "x2", "x3", "x4"})
+}
+
+func TestFitPath(t *testing.T) {
+
+	xnames := []string{"icept", "x1", "x2", "x3", "x4"}
+	for _, binary := range []bool{false, true} {
+		for _, alpha := range []float64{1, 0.5} {
+
+			data := dataPath(200, binary)
+			config := DefaultConfig().WithPenaltyExempt("icept")
+			if binary {
+				config = config.WithFamily(NewFamily(BinomialFamily))
+			}
+
+			pr, err := FitPath(data, "y", xnames, config, &PathConfig{Alpha: alpha, NumLambda: 20})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pr.Lambda) != 20 || len(pr.Params) != 20 || len(pr.Deviance) != 20 {
+				t.Fail()
+			}
+
+			// Only the intercept is nonzero at the first penalty
+			nz := pr.NumNonzero()
+			if nz[0] != 1 || nz[len(nz)-1] != 5 {
+				t.Logf("%v\n", nz)
+				t.Fail()
+			}
+
+			// The deviance decreases along the path
+			for i := 1; i < len(pr.Deviance); i++ {
+				if pr.Deviance[i] > pr.Deviance[i-1]+1e-6 {
+					t.Logf("%v\n", pr.Deviance)
+					t.Fail()
+					break
+				}
+			}
+
+			// The warm started fit agrees with a fit started at zero
+			k := 8
+			l1 := make(map[string]float64)
+			l2 := make(map[string]float64)
+			for _, na := range xnames[1:] {
+				l1[na] = alpha * pr.Lambda[k]
+				l2[na] = (1 - alpha) * pr.Lambda[k]
+			}
+			cfg := DefaultConfig().WithL1Penalty(l1).WithL2Penalty(l2)
+			if binary {
+				cfg = cfg.WithFamily(NewFamily(BinomialFamily))
+			}
+			model, err := NewGLM(data, "y", xnames, cfg)
+			if err != nil {
+				panic(err)
+			}
+			result := model.Fit()
+			if !floats.EqualApprox(result.Params(), pr.Params[k], 1e-4) {
+				t.Logf("%v %v\n", result.Params(), pr.Params[k])
+				t.Fail()
+			}
+		}
+	}
+}
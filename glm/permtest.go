@@ -0,0 +1,65 @@
+package glm
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/kshedden/statmodel/statmodel"
+)
+
+// PermutationPValue returns a permutation p-value for the null hypothesis
+// that the coefficient of the named covariate is zero.  The values of the
+// covariate are randomly permuted among the observations, the model is
+// refit, and the null distribution of the coefficient is formed from nperm
+// such permutations.  The p-value is the proportion of permutations, with
+// the observed data counted as one of them, in which the absolute value of
+// the coefficient is at least as large as its observed absolute value.
+// This does not depend on large sample approximations, but permuting the
+// covariate also removes its association with the other covariates, so
+// the test is exact only if the named covariate is independent of the
+// other covariates.  The results are reproducible for a given seed.
+func (rslt *GLMResults) PermutationPValue(vname string, nperm int, seed int64) (float64, error) {
+
+	model := rslt.Model().(*GLM)
+
+	if nperm < 1 {
+		msg := fmt.Sprintf("PermutationPValue: the number of permutations must be positive, got %d\n", nperm)
+		return 0, fmt.Errorf(msg)
+	}
+
+	j, err := statmodel.ParamIndex(model, vname)
+	if err != nil {
+		return 0, err
+	}
+
+	pmodel := *model
+	pmodel.nslices = new(nslicePool)
+	pmodel.data = append([][]statmodel.Dtype(nil), model.data...)
+
+	x := model.data[model.xpos[j]]
+	xp := make([]statmodel.Dtype, len(x))
+	pmodel.data[model.xpos[j]] = xp
+
+	obs := math.Abs(rslt.Params()[j])
+	rs := statmodel.NewResampler(seed)
+	n := 1
+	for k := 0; k < nperm; k++ {
+		for i, ii := range rs.Permutation(len(x)) {
+			xp[i] = x[ii]
+		}
+
+		// The starting values are copied, since L1 regularized fits
+		// update them in place.
+		pmodel.start = append([]float64(nil), model.start...)
+		pr, err := pmodel.FitChecked()
+		if err != nil {
+			msg := fmt.Sprintf("PermutationPValue: the fit failed for permutation %d: %v\n", k, err)
+			return 0, fmt.Errorf(msg)
+		}
+		if math.Abs(pr.Params()[j]) >= obs {
+			n++
+		}
+	}
+
+	return float64(n) / float64(nperm+1), nil
+}
@@ -0,0 +1,107 @@
+package glm
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/kshedden/statmodel/statmodel"
+)
+
+func TestPermutationPValue(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(8))
+	n := 60
+	y := make([]statmodel.Dtype, n)
+	icept := make([]statmodel.Dtype, n)
+	x1 := make([]statmodel.Dtype, n)
+	x2 := make([]statmodel.Dtype, n)
+	for i := range y {
+		icept[i] = 1
+		x1[i] = statmodel.Dtype(rng.NormFloat64())
+		x2[i] = statmodel.Dtype(rng.NormFloat64())
+		y[i] = x1[i] + statmodel.Dtype(rng.NormFloat64())
+	}
+	data := statmodel.NewDataset([][]statmodel.Dtype{y, icept, x1, x2}, []string{"y", "icept", "x1", "x2"})
+
+	model, err := NewGLM(data, "y", []string{"icept", "x1", "x2"}, nil)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+
+	// x1 has a strong effect, so no permutation produces a coefficient
+	// as large as the observed one.
+	nperm := 99
+	p1, err := result.PermutationPValue("x1", nperm, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !scalarClose(p1, 1/float64(nperm+1), 1e-12) {
+		t.Errorf("p-value for x1 is %f", p1)
+	}
+
+	// x2 has no effect, the p-value agrees roughly with the Wald p-value
+	p2, err := result.PermutationPValue("x2", nperm, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p2 < 1/float64(nperm+1) || p2 > 1 || !scalarClose(p2, result.PValues()[2], 0.15) {
+		t.Errorf("p-value for x2 is %f, Wald p-value is %f", p2, result.PValues()[2])
+	}
+
+	// Reproducible, and the data are not modified
+	p3, _ := result.PermutationPValue("x2", nperm, 1)
+	if p3 != p2 {
+		t.Fail()
+	}
+	if model.data[2][0] != x1[0] || model.data[3][0] != x2[0] {
+		t.Fail()
+	}
+
+	if _, err := result.PermutationPValue("x3", nperm, 1); err == nil {
+		t.Fail()
+	}
+	if _, err := result.PermutationPValue("x1", 0, 1); err == nil {
+		t.Fail()
+	}
+}
+
+// TestPermutationPValueL1 checks that the permutation test does not
+// modify the estimates of an L1 regularized fit.
+func TestPermutationPValueL1(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(9))
+	n := 100
+	y := make([]statmodel.Dtype, n)
+	icept := make([]statmodel.Dtype, n)
+	x := make([]statmodel.Dtype, n)
+	for i := range y {
+		icept[i] = 1
+		x[i] = statmodel.Dtype(rng.NormFloat64())
+		y[i] = x[i] + statmodel.Dtype(rng.NormFloat64())
+	}
+	data := statmodel.NewDataset([][]statmodel.Dtype{y, icept, x}, []string{"y", "icept", "x"})
+
+	config := DefaultConfig().WithL1Penalty(map[string]float64{"x": 0.01})
+	model, err := NewGLM(data, "y", []string{"icept", "x"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+	params := append([]float64(nil), result.Params()...)
+
+	pv, err := result.PermutationPValue("x", 20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for j, v := range result.Params() {
+		if v != params[j] {
+			t.Errorf("the estimates changed from %v to %v", params, result.Params())
+			break
+		}
+	}
+	if !scalarClose(pv, 1/float64(21), 1e-12) {
+		t.Errorf("p-value for x is %f", pv)
+	}
+}
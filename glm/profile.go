@@ -1,8 +1,12 @@
 package glm
 
 import (
+	"fmt"
+	"math"
 	"sort"
 
+	"github.com/kshedden/statmodel/statmodel"
+
 	"gonum.org/v1/gonum/optimize"
 	"gonum.org/v1/gonum/stat/distuv"
 )
@@ -336,6 +340,19 @@ func (nb *NegBinomProfiler) DispersionMLE() float64 {
 	return nb.dispersionMLE
 }
 
+// DispersionStdErr returns the standard error of the maximum
+// likelihood estimate of the dispersion parameter, based on the second
+// derivative of the profile log-likelihood at the MLE.
+func (nb *NegBinomProfiler) DispersionStdErr() float64 {
+
+	h := 1e-4 * nb.dispersionMLE
+	ll1 := nb.LogLike(nb.dispersionMLE - h)
+	ll2 := nb.LogLike(nb.dispersionMLE + h)
+	d2 := (ll1 - 2*nb.maxLogLike + ll2) / (h * h)
+
+	return 1 / math.Sqrt(-d2)
+}
+
 func (nb *NegBinomProfiler) getMLE() {
 
 	model := nb.results.Model().(*GLM)
@@ -402,3 +419,78 @@ func (nb *NegBinomProfiler) ConfInt(prob float64) (float64, float64) {
 
 	return disp0, disp1
 }
+
+// fixCoeff returns a copy of the model in which the coefficient at
+// position j is held fixed at the value b, by moving the covariate
+// into the offset.  The remaining coefficients are started at the
+// values in start, which excludes position j.
+func (model *GLM) fixCoeff(j int, b float64, start []float64) *GLM {
+
+	fmodel := *model
+	fmodel.nslices = new(nslicePool)
+	fmodel.data = append([][]statmodel.Dtype(nil), model.data...)
+	fmodel.varnames = append([]string(nil), model.varnames...)
+
+	off := make([]statmodel.Dtype, model.NumObs())
+	x := model.data[model.xpos[j]]
+	for i := range off {
+		off[i] = statmodel.Dtype(b * float64(x[i]))
+	}
+	if model.offsetpos != -1 {
+		for i, v := range model.data[model.offsetpos] {
+			off[i] += v
+		}
+	}
+	fmodel.data = append(fmodel.data, off)
+	fmodel.varnames = append(fmodel.varnames, "__fixed")
+	fmodel.offsetpos = len(fmodel.data) - 1
+
+	drop := func(x []float64) []float64 {
+		if x == nil {
+			return nil
+		}
+		return append(append([]float64(nil), x[:j]...), x[j+1:]...)
+	}
+
+	fmodel.xpos = append(append([]int(nil), model.xpos[:j]...), model.xpos[j+1:]...)
+	fmodel.l2wgt = drop(model.l2wgt)
+	fmodel.start = append([]float64(nil), start...)
+
+	return &fmodel
+}
+
+// ProfileLogLike returns the profile log-likelihood for the coefficient
+// at the given position, at each value in grid.  At each grid value, the
+// coefficient is held fixed and the model is refit to obtain the
+// maximized log-likelihood over the other coefficients.  The scale
+// parameter is estimated in the same way as in the original fit.  If
+// the fit fails at a grid value, the profile log-likelihood is NaN.
+// ProfileLogLike can not be used with L1 regularized fits.
+func (rslt *GLMResults) ProfileLogLike(index int, grid []float64) []float64 {
+
+	model := rslt.Model().(*GLM)
+
+	if model.l1wgt != nil {
+		panic("ProfileLogLike can not be used with L1 regularized fits\n")
+	}
+	if index < 0 || index >= len(model.xpos) {
+		msg := fmt.Sprintf("ProfileLogLike: invalid coefficient position %d\n", index)
+		panic(msg)
+	}
+
+	pa := rslt.Params()
+	start := append(append([]float64(nil), pa[:index]...), pa[index+1:]...)
+
+	ll := make([]float64, len(grid))
+	for i, b := range grid {
+		fmodel := model.fixCoeff(index, b, start)
+		fr, err := fmodel.FitChecked()
+		if err != nil {
+			ll[i] = math.NaN()
+			continue
+		}
+		ll[i] = fr.LogLike()
+	}
+
+	return ll
+}
@@ -0,0 +1,101 @@
+package glm
+
+import (
+	"math"
+)
+
+// ResultsRow contains the results for one coefficient of a fitted GLM.
+type ResultsRow struct {
+
+	// The name of the covariate
+	Name string
+
+	// The estimated coefficient
+	Estimate float64
+
+	// The standard error, Z-score, two-sided p-value, and confidence
+	// limits.  These are NaN for L1 regularized fits.
+	StdErr float64
+	ZScore float64
+	PValue float64
+	LCB    float64
+	UCB    float64
+}
+
+// ResultsTable is a flat representation of the results of a fitted GLM,
+// with all fields exported so that it can be used directly with the
+// text/template and html/template packages.
+type ResultsTable struct {
+	Family   string
+	Link     string
+	Variance string
+
+	// The number of observations, and the sum of the case weights
+	NumObs     int
+	SumWeights float64
+
+	Scale      float64
+	LogLike    float64
+	AIC        float64
+	BIC        float64
+	Deviance   float64
+	DevianceR2 float64
+
+	// The coverage probability of the confidence intervals
+	Level float64
+
+	// One row per coefficient, in the canonical coefficient order
+	Rows []ResultsRow
+}
+
+// Table returns the results of the fitted model as a ResultsTable, with
+// 95% confidence intervals.
+func (rslt *GLMResults) Table() ResultsTable {
+
+	model := rslt.Model().(*GLM)
+
+	level := 0.95
+	tab := ResultsTable{
+		Family:     model.fam.Name,
+		Link:       model.link.Name,
+		Variance:   model.vari.Name,
+		NumObs:     model.NumObs(),
+		SumWeights: model.sumWeights(),
+		Scale:      rslt.scale,
+		LogLike:    rslt.LogLike(),
+		AIC:        rslt.AIC(),
+		BIC:        rslt.BIC(),
+		Deviance:   rslt.Deviance(),
+		DevianceR2: rslt.DevianceR2(),
+		Level:      level,
+	}
+
+	names := rslt.Names()
+	params := rslt.Params()
+	se := rslt.StdErr()
+	zs := rslt.ZScores()
+	pv := rslt.PValues()
+	lcb, ucb := rslt.ConfInt(level)
+
+	for j := range params {
+		row := ResultsRow{
+			Name:     names[j],
+			Estimate: params[j],
+			StdErr:   math.NaN(),
+			ZScore:   math.NaN(),
+			PValue:   math.NaN(),
+			LCB:      math.NaN(),
+			UCB:      math.NaN(),
+		}
+		if se != nil {
+			row.StdErr = se[j]
+			row.ZScore = zs[j]
+			row.PValue = pv[j]
+			row.LCB = lcb[j]
+			row.UCB = ucb[j]
+		}
+		tab.Rows = append(tab.Rows, row)
+	}
+
+	return tab
+}
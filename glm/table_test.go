@@ -0,0 +1,57 @@
+package glm
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestTable(t *testing.T) {
+
+	config := DefaultConfig().WithFamily(NewFamily(PoissonFamily)).WithWeight("w")
+	model, err := NewGLM(data5(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result := model.Fit()
+	tab := result.Table()
+
+	if tab.Family != "Poisson" || tab.NumObs != 7 || tab.SumWeights != 14 || len(tab.Rows) != 2 {
+		t.Fail()
+	}
+	lcb, ucb := result.ConfInt(0.95)
+	for j, row := range tab.Rows {
+		if row.Name != result.Names()[j] || row.Estimate != result.Params()[j] ||
+			row.StdErr != result.StdErr()[j] || row.PValue != result.PValues()[j] ||
+			row.LCB != lcb[j] || row.UCB != ucb[j] {
+			t.Fail()
+		}
+	}
+	if tab.AIC != result.AIC() || tab.Deviance != result.Deviance() {
+		t.Fail()
+	}
+
+	tmpl := template.Must(template.New("t").Parse(
+		"{{.Family}}{{range .Rows}}\n{{.Name}} {{printf \"%.4f\" .Estimate}}{{end}}"))
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, tab); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "Poisson\nx1 ") || strings.Count(buf.String(), "\n") != 2 {
+		t.Logf("%s\n", buf.String())
+		t.Fail()
+	}
+
+	// There are no standard errors for L1 regularized fits
+	config = DefaultConfig().WithWeight("w").WithL1Penalty(map[string]float64{"x2": 0.1})
+	model, err = NewGLM(data4(), "y", []string{"x1", "x2"}, config)
+	if err != nil {
+		panic(err)
+	}
+	tab = model.Fit().Table()
+	if !math.IsNaN(tab.Rows[0].StdErr) || math.IsNaN(tab.Rows[0].Estimate) {
+		t.Fail()
+	}
+}
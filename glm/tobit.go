@@ -0,0 +1,528 @@
+package glm
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/optimize"
+
+	"github.com/kshedden/statmodel/statmodel"
+)
+
+// Tobit is a censored Gaussian linear regression model.  The response
+// is a latent Gaussian variable y* = x'b + e, with e ~ N(0, sigma^2).  For
+// uncensored observations y* is observed, for left censored observations
+// it is only known that y* <= y, and for right censored observations it
+// is only known that y* >= y, where y is the recorded response value.
+//
+// The model parameters are the regression coefficients b followed by
+// log(sigma).
+type Tobit struct {
+
+	// The data to which the model is fit
+	data [][]statmodel.Dtype
+
+	// The names of the variables.  The order agrees with the order of 'data'.
+	varnames []string
+
+	// Position of the response variable
+	ypos int
+
+	// Positions of the covariates
+	xpos []int
+
+	// Positions of the left and right censoring indicators, or -1
+	leftpos  int
+	rightpos int
+
+	// Position of the case weights, or -1
+	weightpos int
+
+	// Starting values, optional
+	start []float64
+
+	// Optimization settings
+	optsettings *optimize.Settings
+
+	// Optimization method
+	optmethod optimize.Method
+}
+
+// TobitConfig defines configuration parameters for a Tobit model.
+type TobitConfig struct {
+
+	// LeftCensorVar is the name of a variable that is 1 for left
+	// censored observations and 0 otherwise.
+	LeftCensorVar string
+
+	// RightCensorVar is the name of a variable that is 1 for right
+	// censored observations and 0 otherwise.
+	RightCensorVar string
+
+	// WeightVar is the name of the variable for frequency-weighting the cases, if an empty
+	// string, all weights are equal to 1.
+	WeightVar string
+
+	// Start contains starting values for the regression coefficients
+	// followed by log(sigma).  If not provided, the starting values are
+	// obtained from a least squares fit that ignores the censoring.
+	Start []float64
+
+	// OptMethod is the Gonum optimization used to fit the model.
+	OptMethod optimize.Method
+
+	// OptSettings configures the Gonum optimization routine.
+	OptSettings *optimize.Settings
+}
+
+// TobitParams represents the parameters of a Tobit model, which are the
+// regression coefficients followed by log(sigma).
+type TobitParams struct {
+	coeff []float64
+}
+
+// GetCoeff returns the regression coefficients followed by log(sigma).
+func (p *TobitParams) GetCoeff() []float64 {
+	return p.coeff
+}
+
+// SetCoeff sets the regression coefficients and log(sigma).
+func (p *TobitParams) SetCoeff(coeff []float64) {
+	p.coeff = coeff
+}
+
+// Clone produces a deep copy of the parameter value.
+func (p *TobitParams) Clone() statmodel.Parameter {
+	coeff := make([]float64, len(p.coeff))
+	copy(coeff, p.coeff)
+	return &TobitParams{coeff: coeff}
+}
+
+// NewTobit returns a Tobit model for the given response and covariates.
+// At least one of the censoring indicators should be specified in config,
+// otherwise the model is equivalent to Gaussian linear regression.
+func NewTobit(data statmodel.Dataset, outcome string, predictors []string, config *TobitConfig) (*Tobit, error) {
+
+	if config == nil {
+		config = &TobitConfig{}
+	}
+
+	if err := checkValid(data); err != nil {
+		return nil, err
+	}
+
+	pos := make(map[string]int)
+	for i, v := range data.Names() {
+		pos[v] = i
+	}
+
+	ypos, ok := pos[outcome]
+	if !ok {
+		msg := fmt.Sprintf("Outcome variable '%s' not found in dataset\n", outcome)
+		return nil, fmt.Errorf(msg)
+	}
+
+	var xpos []int
+	for _, xna := range predictors {
+		xp, ok := pos[xna]
+		if !ok {
+			msg := fmt.Sprintf("Predictor '%s' not found in dataset\n", xna)
+			return nil, fmt.Errorf(msg)
+		}
+		xpos = append(xpos, xp)
+	}
+
+	getpos := func(vn string) (int, error) {
+		if vn == "" {
+			return -1, nil
+		}
+		loc, ok := pos[vn]
+		if !ok {
+			msg := fmt.Sprintf("'%s' not found\n", vn)
+			return -1, fmt.Errorf(msg)
+		}
+		return loc, nil
+	}
+
+	leftpos, err := getpos(config.LeftCensorVar)
+	if err != nil {
+		return nil, err
+	}
+	rightpos, err := getpos(config.RightCensorVar)
+	if err != nil {
+		return nil, err
+	}
+	weightpos, err := getpos(config.WeightVar)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Start != nil && len(config.Start) != len(xpos)+1 {
+		msg := fmt.Sprintf("Start has length %d, expected %d\n", len(config.Start), len(xpos)+1)
+		return nil, fmt.Errorf(msg)
+	}
+
+	model := &Tobit{
+		data:        data.Data(),
+		varnames:    data.Names(),
+		ypos:        ypos,
+		xpos:        xpos,
+		leftpos:     leftpos,
+		rightpos:    rightpos,
+		weightpos:   weightpos,
+		start:       config.Start,
+		optsettings: config.OptSettings,
+		optmethod:   config.OptMethod,
+	}
+
+	if err := model.checkCensoring(); err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+// checkCensoring confirms that the censoring indicators are binary, and
+// that no observation is both left and right censored.
+func (model *Tobit) checkCensoring() error {
+
+	for _, k := range []int{model.leftpos, model.rightpos} {
+		if k == -1 {
+			continue
+		}
+		for _, v := range model.data[k] {
+			if v != 0 && v != 1 {
+				msg := fmt.Sprintf("Censoring indicator '%s' has values other than 0 and 1\n", model.varnames[k])
+				return fmt.Errorf(msg)
+			}
+		}
+	}
+
+	if model.leftpos != -1 && model.rightpos != -1 {
+		left := model.data[model.leftpos]
+		right := model.data[model.rightpos]
+		for i := range left {
+			if left[i] == 1 && right[i] == 1 {
+				msg := fmt.Sprintf("Observation %d is both left and right censored\n", i)
+				return fmt.Errorf(msg)
+			}
+		}
+	}
+
+	return nil
+}
+
+// NumParams returns the number of model parameters, which is the number
+// of covariates plus one for the scale parameter.
+func (model *Tobit) NumParams() int {
+	return len(model.xpos) + 1
+}
+
+// NumObs returns the number of observations in the data set.
+func (model *Tobit) NumObs() int {
+	return len(model.data[0])
+}
+
+// Xpos returns the positions of the covariates in the data set.
+func (model *Tobit) Xpos() []int {
+	return model.xpos
+}
+
+// Dataset returns the data columns that are used to fit the model.
+func (model *Tobit) Dataset() [][]statmodel.Dtype {
+	return model.data
+}
+
+// VarNames returns the names of the data columns that are used to fit
+// the model.
+func (model *Tobit) VarNames() []string {
+	return model.varnames
+}
+
+// censType returns -1 if observation i is left censored, 1 if it is
+// right censored, and 0 if it is not censored.
+func (model *Tobit) censType(i int) int {
+	if model.leftpos != -1 && model.data[model.leftpos][i] == 1 {
+		return -1
+	}
+	if model.rightpos != -1 && model.data[model.rightpos][i] == 1 {
+		return 1
+	}
+	return 0
+}
+
+// eval calculates the log-likelihood at the given parameters, and if
+// score or hess are not nil, the score vector and the (observed) Hessian
+// matrix.
+func (model *Tobit) eval(params, score, hess []float64) float64 {
+
+	p := len(model.xpos)
+	q := p + 1
+	coeff := params[0:p]
+	ls := params[p]
+	isig := math.Exp(-ls)
+
+	if score != nil {
+		zero(score)
+	}
+	if hess != nil {
+		zero(hess)
+	}
+
+	yda := model.data[model.ypos]
+	var wgt []statmodel.Dtype
+	if model.weightpos != -1 {
+		wgt = model.data[model.weightpos]
+	}
+
+	x := make([]float64, p)
+	var ll float64
+	for i := range yda {
+
+		w := 1.0
+		if wgt != nil {
+			w = float64(wgt[i])
+		}
+
+		for j, k := range model.xpos {
+			x[j] = float64(model.data[k][i])
+		}
+		z := (float64(yda[i]) - floats.Dot(x, coeff)) * isig
+
+		// g1 and g2 are the first and second derivatives of the
+		// log-likelihood contribution with respect to z, and gs
+		// is the additional term in the derivative with respect
+		// to log(sigma) that arises from the density's
+		// normalizing constant.
+		var g1, g2, gs float64
+		switch model.censType(i) {
+		case 0:
+			ll += w * (-ls - math.Log(2*math.Pi)/2 - z*z/2)
+			g1, g2, gs = -z, -1, -1
+		default:
+			// Left censored cases contribute log Phi(z), right
+			// censored cases contribute log Phi(-z).
+			a := -float64(model.censType(i))
+			cdf := 0.5 * math.Erfc(-a*z/math.Sqrt2)
+			pdf := math.Exp(-z*z/2) / math.Sqrt(2*math.Pi)
+			lam := pdf / cdf
+			ll += w * math.Log(cdf)
+			g1 = a * lam
+			g2 = -a * lam * (z + a*lam)
+		}
+
+		// The derivatives of z are -x/sigma (coefficients) and -z
+		// (log sigma).
+		if score != nil {
+			for j := range x {
+				score[j] -= w * g1 * x[j] * isig
+			}
+			score[p] += w * (-g1*z + gs)
+		}
+
+		if hess != nil {
+			for j1 := range x {
+				for j2 := range x {
+					hess[j1*q+j2] += w * g2 * x[j1] * x[j2] * isig * isig
+				}
+				h := w * (g2*z + g1) * x[j1] * isig
+				hess[j1*q+p] += h
+				hess[p*q+j1] += h
+			}
+			hess[p*q+p] += w * (g2*z*z + g1*z)
+		}
+	}
+
+	return ll
+}
+
+// LogLike returns the log-likelihood at the given parameter value.  The
+// 'exact' parameter is ignored, all constants are included.
+func (model *Tobit) LogLike(param statmodel.Parameter, exact bool) float64 {
+	return model.eval(param.GetCoeff(), nil, nil)
+}
+
+// Score computes the score vector at the given parameter value.
+func (model *Tobit) Score(param statmodel.Parameter, score []float64) {
+	model.eval(param.GetCoeff(), score, nil)
+}
+
+// Hessian computes the Hessian matrix at the given parameter value.  The
+// Hessian type parameter is not used, the observed Hessian is always
+// returned.
+func (model *Tobit) Hessian(param statmodel.Parameter, ht statmodel.HessType, hess []float64) {
+	model.eval(param.GetCoeff(), nil, hess)
+}
+
+// startValues returns starting values obtained from a least squares fit
+// that ignores the censoring.
+func (model *Tobit) startValues() ([]float64, error) {
+
+	p := len(model.xpos)
+	names := make([]string, p)
+	for j, k := range model.xpos {
+		names[j] = model.varnames[k]
+	}
+
+	config := DefaultConfig()
+	if model.weightpos != -1 {
+		config.WeightVar = model.varnames[model.weightpos]
+	}
+	ols, err := NewGLM(statmodel.NewDataset(model.data, model.varnames), model.varnames[model.ypos], names, config)
+	if err != nil {
+		return nil, err
+	}
+	rslt, err := ols.FitChecked()
+	if err != nil {
+		return nil, err
+	}
+
+	start := make([]float64, p+1)
+	copy(start, rslt.Params())
+	start[p] = math.Log(rslt.Scale()) / 2
+
+	return start, nil
+}
+
+// TobitResults describes the results of a fitted Tobit model.
+type TobitResults struct {
+	statmodel.BaseResults
+}
+
+// Fit estimates the parameters of the Tobit model using maximum
+// likelihood.
+func (model *Tobit) Fit() (*TobitResults, error) {
+
+	start := model.start
+	if start == nil {
+		var err error
+		start, err = model.startValues()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	prob := optimize.Problem{
+		Func: func(x []float64) float64 {
+			return -model.eval(x, nil, nil)
+		},
+		Grad: func(grad, x []float64) {
+			model.eval(x, grad, nil)
+			floats.Scale(-1, grad)
+		},
+	}
+
+	settings := model.optsettings
+	if settings == nil {
+		settings = &optimize.Settings{
+			GradientThreshold: 1e-6,
+		}
+	}
+
+	method := model.optmethod
+	if method == nil {
+		method = &optimize.BFGS{
+			Linesearcher: &optimize.MoreThuente{},
+		}
+	}
+
+	optrslt, err := optimize.Minimize(prob, start, settings, method)
+	if err != nil {
+		return nil, err
+	}
+	if err = optrslt.Status.Err(); err != nil {
+		return nil, err
+	}
+
+	params := make([]float64, len(optrslt.X))
+	copy(params, optrslt.X)
+
+	var xna []string
+	for _, k := range model.xpos {
+		xna = append(xna, model.varnames[k])
+	}
+	xna = append(xna, "log(sigma)")
+
+	vcov, _ := statmodel.GetVcov(model, &TobitParams{params})
+
+	return &TobitResults{
+		BaseResults: statmodel.NewBaseResults(model, -optrslt.F, params, xna, vcov),
+	}, nil
+}
+
+// Coeff returns the estimated regression coefficients for the latent
+// variable, excluding the scale parameter.
+func (rslt *TobitResults) Coeff() []float64 {
+	pa := rslt.Params()
+	return pa[0 : len(pa)-1]
+}
+
+// Scale returns the estimated standard deviation (sigma) of the latent
+// variable's error term.
+func (rslt *TobitResults) Scale() float64 {
+	pa := rslt.Params()
+	return math.Exp(pa[len(pa)-1])
+}
+
+// Summary returns a summary table of the model results.
+func (rslt *TobitResults) Summary() string {
+
+	model := rslt.Model().(*Tobit)
+
+	var nleft, nright int
+	for i := 0; i < model.NumObs(); i++ {
+		switch model.censType(i) {
+		case -1:
+			nleft++
+		case 1:
+			nright++
+		}
+	}
+
+	fs := func(x interface{}, h string) []string {
+		y := x.([]string)
+		m := len(h)
+		for i := range y {
+			if len(y[i]) > m {
+				m = len(y[i])
+			}
+		}
+		var z []string
+		for i := range y {
+			c := fmt.Sprintf("%%-%ds", m)
+			z = append(z, fmt.Sprintf(c, y[i]))
+		}
+		return z
+	}
+
+	fn := func(x interface{}, h string) []string {
+		y := x.([]float64)
+		var s []string
+		for i := range y {
+			s = append(s, fmt.Sprintf("%10.4f", y[i]))
+		}
+		return s
+	}
+
+	sum := &statmodel.SummaryTable{
+		Title: "Tobit regression analysis",
+		Top: []string{
+			fmt.Sprintf("Num obs:        %d", model.NumObs()),
+			fmt.Sprintf("Left censored:  %d", nleft),
+			fmt.Sprintf("Right censored: %d", nright),
+			fmt.Sprintf("Sigma:          %f", rslt.Scale()),
+		},
+		ColNames: []string{"Variable   ", "Parameter", "SE", "Z-score", "P-value"},
+		ColFmt:   []statmodel.Fmter{fs, fn, fn, fn, fn},
+		Cols: []interface{}{
+			rslt.Names(),
+			rslt.Params(),
+			rslt.StdErr(),
+			rslt.ZScores(),
+			rslt.PValues(),
+		},
+	}
+
+	return sum.String()
+}
@@ -0,0 +1,143 @@
+package glm
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/diff/fd"
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/kshedden/statmodel/statmodel"
+)
+
+// dataTobit simulates data from a Tobit model with coefficients (1, 2) for
+// an intercept and a standard normal covariate, and sigma = 1.5.  The
+// latent responses are left censored at 0 and right censored at 4.
+func dataTobit(n int) statmodel.Dataset {
+
+	rng := rand.New(rand.NewSource(42))
+	var y, icept, x, left, right []statmodel.Dtype
+	for i := 0; i < n; i++ {
+		xv := rng.NormFloat64()
+		ys := 1 + 2*xv + 1.5*rng.NormFloat64()
+		var l, r statmodel.Dtype
+		if ys < 0 {
+			ys, l = 0, 1
+		} else if ys > 4 {
+			ys, r = 4, 1
+		}
+		y = append(y, statmodel.Dtype(ys))
+		icept = append(icept, 1)
+		x = append(x, statmodel.Dtype(xv))
+		left = append(left, l)
+		right = append(right, r)
+	}
+
+	return statmodel.NewDataset([][]statmodel.Dtype{y, icept, x, left, right},
+		[]string{"y", "icept", "x", "left", "right"})
+}
+
+func TestTobitDerivatives(t *testing.T) {
+
+	config := &TobitConfig{LeftCensorVar: "left", RightCensorVar: "right"}
+	model, err := NewTobit(dataTobit(50), "y", []string{"icept", "x"}, config)
+	if err != nil {
+		panic(err)
+	}
+
+	loglike := func(x []float64) float64 {
+		return model.LogLike(&TobitParams{x}, true)
+	}
+	scoref := func(y, x []float64) {
+		model.Score(&TobitParams{x}, y)
+	}
+
+	score := make([]float64, 3)
+	ngrad := make([]float64, 3)
+	hess := make([]float64, 9)
+	nhess := mat.NewDense(3, 3, nil)
+	for _, pa := range [][]float64{{0, 0, 0}, {1, 2, 0.4}, {-0.5, 1, -0.3}} {
+		fd.Gradient(ngrad, loglike, pa, &fd.Settings{Formula: fd.Central})
+		model.Score(&TobitParams{pa}, score)
+		if !floats.EqualApprox(score, ngrad, 1e-5) {
+			t.Logf("%v %v\n", score, ngrad)
+			t.Fail()
+		}
+
+		fd.Jacobian(nhess, scoref, pa, &fd.JacobianSettings{Formula: fd.Central})
+		model.Hessian(&TobitParams{pa}, statmodel.ObsHess, hess)
+		if !floats.EqualApprox(hess, nhess.RawMatrix().Data, 1e-5) {
+			t.Logf("%v %v\n", hess, nhess.RawMatrix().Data)
+			t.Fail()
+		}
+	}
+}
+
+func TestTobitFit(t *testing.T) {
+
+	config := &TobitConfig{LeftCensorVar: "left", RightCensorVar: "right"}
+	model, err := NewTobit(dataTobit(2000), "y", []string{"icept", "x"}, config)
+	if err != nil {
+		panic(err)
+	}
+	result, err := model.Fit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The estimates should be close to the population values
+	coeff := result.Coeff()
+	se := result.StdErr()
+	if math.Abs(coeff[0]-1) > 4*se[0] || math.Abs(coeff[1]-2) > 4*se[1] {
+		t.Logf("%v %v\n", coeff, se)
+		t.Fail()
+	}
+	if math.Abs(result.Scale()-1.5) > 0.1 {
+		t.Fail()
+	}
+	if len(result.Names()) != 3 || result.Names()[2] != "log(sigma)" {
+		t.Fail()
+	}
+	_ = result.Summary()
+
+	// Without censoring, the model is Gaussian linear regression with the
+	// MLE of the variance.
+	model, err = NewTobit(data4(), "y", []string{"x1", "x2", "x3"}, nil)
+	if err != nil {
+		panic(err)
+	}
+	result, err = model.Fit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ols, err := NewGLM(data4(), "y", []string{"x1", "x2", "x3"}, nil)
+	if err != nil {
+		panic(err)
+	}
+	orslt := ols.Fit()
+	if !floats.EqualApprox(result.Coeff(), orslt.Params(), 1e-6) {
+		t.Fail()
+	}
+	n := float64(ols.NumObs())
+	if !scalarClose(result.Scale()*result.Scale(), orslt.Scale()*(n-3)/n, 1e-6) {
+		t.Fail()
+	}
+}
+
+func TestTobitErrors(t *testing.T) {
+
+	da := dataTobit(10).Data()
+	da[3][0], da[4][0] = 1, 1
+	d := statmodel.NewDataset(da, []string{"y", "icept", "x", "left", "right"})
+	config := &TobitConfig{LeftCensorVar: "left", RightCensorVar: "right"}
+	if _, err := NewTobit(d, "y", []string{"icept", "x"}, config); err == nil {
+		t.Fail()
+	}
+
+	config = &TobitConfig{LeftCensorVar: "nothere"}
+	if _, err := NewTobit(d, "y", []string{"icept", "x"}, config); err == nil {
+		t.Fail()
+	}
+}
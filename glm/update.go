@@ -0,0 +1,185 @@
+package glm
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/kshedden/statmodel/statmodel"
+)
+
+// onlineState contains the accumulated statistics for all batches of
+// data that have been incorporated into a fitted GLM.
+type onlineState struct {
+
+	// The accumulated (unscaled) information matrix, evaluated at
+	// the parameter estimates that were current when each batch
+	// was added.
+	info []float64
+
+	// The accumulated Pearson chi-square statistic
+	chi2 float64
+
+	// The accumulated deviance
+	dev float64
+
+	// The accumulated sum of case weights
+	wsum float64
+}
+
+// batch returns a copy of the model that uses the given data, which must
+// have the same columns as the data used to construct the model.
+func (model *GLM) batch(data [][]statmodel.Dtype) *GLM {
+	bmodel := *model
+	bmodel.data = data
+	bmodel.nslices = new(nslicePool)
+	return &bmodel
+}
+
+// Update incorporates a new batch of observations into a fitted GLM
+// without revisiting the data used in earlier fits.  The columns of
+// newData must agree with the columns of the data used to construct the
+// model.  The contribution of the earlier data to the log-likelihood is
+// represented by a quadratic approximation centered at the current
+// estimates, based on the accumulated information matrix.  A few Newton
+// steps are then taken from the current estimates.  The result is exact
+// for Gaussian models with the identity link, but is otherwise an
+// approximation to the fit that would be obtained by refitting the
+// model to all of the data, unless the updates are iterated to
+// convergence.  After calling Update, the parameter estimates, standard
+// errors, and scale reflect all batches, and the log-likelihood is
+// approximated in the same way.  The log-likelihood is evaluated at the
+// scale parameter from before the update, since the contribution of the
+// earlier data can not be re-evaluated at a new scale, so for families
+// with an estimated scale it is approximate.  The model returned by
+// Model continues to refer to the original data.  Update cannot be used
+// with regularized fits or with the beta family.
+func (rslt *GLMResults) Update(newData [][]statmodel.Dtype) error {
+
+	model := rslt.Model().(*GLM)
+
+	if model.l1wgt != nil || model.l2wgt != nil {
+		return fmt.Errorf("Update can not be used with regularized fits")
+	}
+
+	if model.casewpos != model.weightpos {
+		return fmt.Errorf("Update can not be used with per-observation dispersions")
+	}
+
+	if model.fam.TypeCode == BetaFamily {
+		return fmt.Errorf("Update can not be used with the beta family")
+	}
+
+	if len(newData) != len(model.data) {
+		msg := fmt.Sprintf("Data has incorrect number of columns, %d != %d\n", len(newData), len(model.data))
+		return fmt.Errorf(msg)
+	}
+
+	for j := range newData {
+		if len(newData[j]) != len(newData[0]) {
+			msg := fmt.Sprintf("Column %d of the new data has length %d, expected %d\n", j, len(newData[j]), len(newData[0]))
+			return fmt.Errorf(msg)
+		}
+	}
+
+	if len(newData[0]) == 0 {
+		return fmt.Errorf("Update requires at least one new observation")
+	}
+
+	p := model.NumParams()
+	if rslt.online == nil {
+		info := make([]float64, p*p)
+		model.Hessian(&GLMParams{rslt.Params(), 1}, model.Information(), info)
+		floats.Scale(-1, info)
+		chi2, wsum := model.pearsonChi2(rslt.Params())
+		rslt.online = &onlineState{
+			info: info,
+			chi2: chi2,
+			dev:  model.deviance(rslt.Params()),
+			wsum: wsum,
+		}
+	}
+
+	bmodel := model.batch(newData)
+
+	params0 := rslt.Params()
+	params := make([]float64, p)
+	copy(params, params0)
+
+	score := make([]float64, p)
+	hess := make([]float64, p*p)
+	grad := make([]float64, p)
+	diff := make([]float64, p)
+	var step mat.VecDense
+
+	// Newton steps, using the quadratic approximation for the
+	// earlier data, and the exact log-likelihood for the new data.
+	maxiter := 5
+	for iter := 0; iter < maxiter; iter++ {
+
+		bmodel.Score(&GLMParams{params, 1}, score)
+		bmodel.Hessian(&GLMParams{params, 1}, model.Information(), hess)
+
+		floats.SubTo(diff, params, params0)
+		for j := 0; j < p; j++ {
+			grad[j] = score[j] - floats.Dot(rslt.online.info[j*p:(j+1)*p], diff)
+		}
+		floats.Scale(-1, hess)
+		floats.Add(hess, rslt.online.info)
+
+		err := step.SolveVec(mat.NewDense(p, p, hess), mat.NewVecDense(p, grad))
+		if err != nil {
+			return err
+		}
+		floats.Add(params, step.RawVector().Data)
+
+		if floats.Norm(step.RawVector().Data, math.Inf(1)) < 1e-8 {
+			break
+		}
+	}
+
+	// Add the statistics for the new batch.  The statistics for
+	// the earlier batches are moved to the new estimates using the
+	// quadratic approximation.
+	floats.SubTo(diff, params, params0)
+	qf := 0.0
+	for j := 0; j < p; j++ {
+		qf += diff[j] * floats.Dot(rslt.online.info[j*p:(j+1)*p], diff)
+	}
+	bmodel.Hessian(&GLMParams{params, 1}, model.Information(), hess)
+	floats.Scale(-1, hess)
+	floats.Add(rslt.online.info, hess)
+	chi2, wsum := bmodel.pearsonChi2(params)
+	rslt.online.chi2 += qf + chi2
+	rslt.online.dev += qf + bmodel.deviance(params)
+	rslt.online.wsum += wsum
+
+	scale := model.dispersionValue
+	if model.dispersionMethod != DispersionFixed {
+		scale = rslt.online.chi2
+		if model.scaleEstimator == ScaleDeviance {
+			scale = rslt.online.dev
+		}
+		scale /= rslt.online.wsum - float64(p)
+	}
+
+	// The log-likelihood of the earlier batches is approximated
+	// using the quadratic expansion, at the scale of the earlier
+	// fit, so the new batch uses the same scale.
+	ll := rslt.LogLike() - qf/(2*rslt.scale)
+	ll += bmodel.LogLike(&GLMParams{params, rslt.scale}, true)
+
+	vcov := make([]float64, p*p)
+	vmat := mat.NewDense(p, p, vcov)
+	if err := vmat.Inverse(mat.NewDense(p, p, rslt.online.info)); err != nil {
+		return err
+	}
+	floats.Scale(scale, vcov)
+
+	rslt.BaseResults = statmodel.NewBaseResults(model, ll, params, rslt.Names(), vcov)
+	rslt.scale = scale
+
+	return nil
+}
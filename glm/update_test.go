@@ -0,0 +1,89 @@
+package glm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kshedden/statmodel/statmodel"
+	"gonum.org/v1/gonum/floats"
+)
+
+// Simulate data in a deterministic way, rows [i0, i1) are returned.
+func dataUpdate(i0, i1 int) [][]statmodel.Dtype {
+
+	var y, x1, x2 []statmodel.Dtype
+	for i := i0; i < i1; i++ {
+		u := math.Sin(float64(i))
+		v := math.Cos(float64(3 * i))
+		x1 = append(x1, 1)
+		x2 = append(x2, statmodel.Dtype(u))
+		y = append(y, statmodel.Dtype(math.Floor(3+u+v)))
+	}
+
+	return [][]statmodel.Dtype{y, x1, x2}
+}
+
+func TestUpdate(t *testing.T) {
+
+	names := []string{"y", "x1", "x2"}
+
+	for _, ft := range []FamilyType{GaussianFamily, PoissonFamily} {
+
+		config := DefaultConfig()
+		config.Family = NewFamily(ft)
+
+		// Fit to all the data at once
+		full, err := NewGLM(statmodel.NewDataset(dataUpdate(0, 100), names), "y", []string{"x1", "x2"}, config)
+		if err != nil {
+			panic(err)
+		}
+		fullResult := full.Fit()
+
+		// Fit to the first batch, then add two more batches
+		part, err := NewGLM(statmodel.NewDataset(dataUpdate(0, 40), names), "y", []string{"x1", "x2"}, config)
+		if err != nil {
+			panic(err)
+		}
+		partResult := part.Fit()
+		if err := partResult.Update(dataUpdate(40, 70)); err != nil {
+			t.Fatal(err)
+		}
+		if err := partResult.Update(dataUpdate(70, 100)); err != nil {
+			t.Fatal(err)
+		}
+
+		// Exact for the Gaussian case
+		tol := 1e-8
+		if ft != GaussianFamily {
+			tol = 1e-2
+		}
+
+		if !floats.EqualApprox(fullResult.Params(), partResult.Params(), tol) {
+			t.Logf("%v: %v != %v\n", ft, fullResult.Params(), partResult.Params())
+			t.Fail()
+		}
+		if !floats.EqualApprox(fullResult.StdErr(), partResult.StdErr(), tol) {
+			t.Logf("%v: %v != %v\n", ft, fullResult.StdErr(), partResult.StdErr())
+			t.Fail()
+		}
+		if math.Abs(fullResult.Scale()-partResult.Scale()) > tol {
+			t.Logf("%v: %v != %v\n", ft, fullResult.Scale(), partResult.Scale())
+			t.Fail()
+		}
+
+		// The log-likelihood is evaluated at the scale of the earlier
+		// fit, which is fixed for the Poisson family.
+		if ft == PoissonFamily && math.Abs(fullResult.LogLike()-partResult.LogLike()) > tol {
+			t.Logf("%v: %v != %v\n", ft, fullResult.LogLike(), partResult.LogLike())
+			t.Fail()
+		}
+	}
+
+	// Mismatched columns
+	config := DefaultConfig()
+	model, _ := NewGLM(statmodel.NewDataset(dataUpdate(0, 40), names), "y", []string{"x1", "x2"}, config)
+	result := model.Fit()
+	if result.Update(dataUpdate(40, 50)[0:2]) == nil {
+		t.Fail()
+	}
+}
@@ -3,11 +3,13 @@ package statmodel
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"strings"
 
 	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/distuv"
 )
 
 // Dtype is a type alias that is used to define the datatype of all data
@@ -63,6 +65,18 @@ const (
 	ExpHess
 )
 
+// String returns the name of the Hessian type.
+func (ht HessType) String() string {
+	switch ht {
+	case ObsHess:
+		return "Observed"
+	case ExpHess:
+		return "Expected"
+	default:
+		return fmt.Sprintf("HessType(%d)", int(ht))
+	}
+}
+
 // Parameter is the parameter of a model.
 type Parameter interface {
 
@@ -104,6 +118,43 @@ type RegFitter interface {
 	Hessian(Parameter, HessType, []float64)
 }
 
+// VarNamer is a model that can report the names of the variables in its
+// data set, which allows its coefficients to be looked up by name, see
+// ParamIndex.
+type VarNamer interface {
+	RegFitter
+
+	// Names of the variables in the data set, in the same order
+	// as the columns returned by Dataset.
+	VarNames() []string
+}
+
+// PackedHessianer is a model that can compute its Hessian matrix in packed
+// form, storing only the lower triangle, which uses about half of the
+// memory of the full matrix.  Element (i, j) of the Hessian, for j <= i,
+// is stored in position PackedIndex(i, j).  If UsePackedHessian returns
+// true, GetVcovHess and the functions based on it use the packed form.
+type PackedHessianer interface {
+	RegFitter
+
+	// UsePackedHessian returns true if the packed form should be used
+	UsePackedHessian() bool
+
+	// PackedHessian computes the Hessian in packed form, in a slice
+	// of length p*(p+1)/2 for p parameters.
+	PackedHessian(Parameter, HessType, []float64)
+}
+
+// PackedIndex returns the position of element (i, j) of a symmetric matrix
+// in its packed representation, in which the lower triangle is stored by
+// rows.  The indices may be given in either order.
+func PackedIndex(i, j int) int {
+	if j > i {
+		i, j = j, i
+	}
+	return i*(i+1)/2 + j
+}
+
 // BaseResultser is a fitted model that can produce results (parameter estimates, etc.).
 type BaseResultser interface {
 	Model() RegFitter
@@ -116,7 +167,23 @@ type BaseResultser interface {
 	PValues() []float64
 }
 
-// BaseResults contains the results after fitting a model to data.
+// Predictor is a fitted model that can produce predictions for new data.
+// Code that evaluates predictions, e.g. cross-validation or prediction
+// metrics, can be written against this interface rather than a specific
+// model type.
+type Predictor interface {
+
+	// Predict returns the predictions for the given data, which are
+	// stored column-wise and must have the same columns as the data used
+	// to fit the model.  If da is nil, the predictions are for the data
+	// used to fit the model.
+	Predict(da [][]Dtype) []float64
+}
+
+// BaseResults contains the results after fitting a model to data.  The
+// standard errors, Z-scores, and p-values are computed when the results
+// are constructed, so a BaseResults value is not modified after
+// construction and can safely be read from multiple goroutines.
 type BaseResults struct {
 	model   RegFitter
 	loglike float64
@@ -130,16 +197,38 @@ type BaseResults struct {
 
 // NewBaseResults returns a BaseResults corresponding to the given fitted model.
 func NewBaseResults(model RegFitter, loglike float64, params []float64, xnames []string, vcov []float64) BaseResults {
-	return BaseResults{
+
+	rslt := BaseResults{
 		model:   model,
 		loglike: loglike,
 		params:  params,
 		xnames:  xnames,
 		vcov:    vcov,
 	}
+
+	// No vcov, no standard errors, Z-scores, or p-values
+	if vcov == nil {
+		return rslt
+	}
+
+	p := len(params)
+	rslt.stderr = make([]float64, p)
+	rslt.zscores = make([]float64, p)
+	rslt.pvalues = make([]float64, p)
+	for i := range params {
+		rslt.stderr[i] = math.Sqrt(vcov[i*p+i])
+		rslt.zscores[i] = params[i] / rslt.stderr[i]
+		rslt.pvalues[i] = 2 * normcdf(-math.Abs(rslt.zscores[i]))
+	}
+
+	return rslt
 }
 
-// Model produces the model value used to produce the results.
+// Model produces the model value used to produce the results.  The
+// returned value can be used to evaluate the log-likelihood, score,
+// and Hessian at arbitrary parameter values after fitting, e.g. to
+// plot a likelihood surface.  A type assertion to the concrete model
+// type (e.g. *glm.GLM) gives access to model-specific methods.
 func (rslt *BaseResults) Model() RegFitter {
 	return rslt.model
 }
@@ -164,7 +253,12 @@ func (rslt *BaseResults) FittedValues(da [][]Dtype) []float64 {
 		panic(msg)
 	}
 
-	fv := make([]float64, rslt.model.NumObs())
+	nobs := rslt.model.NumObs()
+	if len(da) > 0 {
+		nobs = len(da[0])
+	}
+
+	fv := make([]float64, nobs)
 	for k, j := range xpos {
 		z := da[j]
 		for i := range z {
@@ -175,7 +269,11 @@ func (rslt *BaseResults) FittedValues(da [][]Dtype) []float64 {
 	return fv
 }
 
-// Names returns the covariate names for the variables in the model.
+// Names returns the covariate names for the variables in the model.  The
+// names are in the order in which the covariates were given when the model
+// was specified.  This is the canonical order of the coefficients, and all
+// slices of per-coefficient values (Params, StdErr, ZScores, PValues,
+// ConfInt) are parallel to Names.
 func (rslt *BaseResults) Names() []string {
 	return rslt.xnames
 }
@@ -185,6 +283,20 @@ func (rslt *BaseResults) Params() []float64 {
 	return rslt.params
 }
 
+// ParamsMap returns the point estimates for the parameters in the model,
+// keyed by the covariate names.  Since the iteration order of a map is
+// not deterministic, Names and Params should be used when the canonical
+// order of the coefficients is needed, e.g. for reports.
+func (rslt *BaseResults) ParamsMap() map[string]float64 {
+
+	mp := make(map[string]float64, len(rslt.params))
+	for j, na := range rslt.xnames {
+		mp[na] = rslt.params[j]
+	}
+
+	return mp
+}
+
 // VCov returns the sampling variance/covariance model for the parameters in the model.
 // The matrix is vetorized to one dimension.
 func (rslt *BaseResults) VCov() []float64 {
@@ -198,82 +310,168 @@ func (rslt *BaseResults) LogLike() float64 {
 
 // StdErr returns the standard errors for the parameters in the model.
 func (rslt *BaseResults) StdErr() []float64 {
+	return rslt.stderr
+}
 
-	// No vcov, no standard error
+// ZScores returns the Z-scores (the parameter estimates divided by the standard errors).
+func (rslt *BaseResults) ZScores() []float64 {
+	return rslt.zscores
+}
+
+func normcdf(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt(2))
+}
+
+// PValues returns the p-values for the null hypothesis that each parameter's population
+// value is equal to zero.
+func (rslt *BaseResults) PValues() []float64 {
+	return rslt.pvalues
+}
+
+// ConfInt returns the lower and upper limits of Wald-type confidence
+// intervals for the parameters in the model, with the given coverage
+// probability (e.g. 0.95).
+func (rslt *BaseResults) ConfInt(level float64) ([]float64, []float64) {
+
+	// No vcov, no confidence intervals
 	if rslt.vcov == nil {
-		return nil
+		return nil, nil
 	}
 
-	p := rslt.model.NumParams()
-	if rslt.stderr == nil {
-		rslt.stderr = make([]float64, p)
-	} else {
-		return rslt.stderr
+	q := distuv.UnitNormal.Quantile((1 + level) / 2)
+	std := rslt.StdErr()
+	lcb := make([]float64, len(rslt.params))
+	ucb := make([]float64, len(rslt.params))
+	for i, p := range rslt.params {
+		lcb[i] = p - q*std[i]
+		ucb[i] = p + q*std[i]
 	}
 
-	for i := range rslt.stderr {
-		rslt.stderr[i] = math.Sqrt(rslt.vcov[i*p+i])
+	return lcb, ucb
+}
+
+// WaldTest returns the Wald statistic (b - b0)' V^{-1} (b - b0) for testing
+// the null hypothesis that the full coefficient vector b is equal to b0,
+// where V is the estimated covariance matrix of the coefficients.  This is
+// the squared Mahalanobis distance of the estimates from b0.  The degrees
+// of freedom (the number of parameters) and the p-value based on the
+// chi-square distribution are also returned.  An error is returned if b0
+// has the wrong length, or if the covariance matrix is not available or is
+// singular.
+func (rslt *BaseResults) WaldTest(b0 []float64) (float64, float64, float64, error) {
+
+	p := len(rslt.params)
+	if len(b0) != p {
+		msg := fmt.Sprintf("WaldTest: b0 has length %d, but there are %d parameters\n", len(b0), p)
+		return 0, 0, 0, fmt.Errorf(msg)
 	}
 
-	return rslt.stderr
+	ix := make([]int, p)
+	for j := range ix {
+		ix[j] = j
+	}
+
+	return rslt.waldTest("WaldTest", ix, b0)
 }
 
-// ZScores returns the Z-scores (the parameter estimates divided by the standard errors).
-func (rslt *BaseResults) ZScores() []float64 {
+// waldTest returns the Wald test of the null hypothesis that the
+// coefficients at the positions in ix are equal to b0, using the
+// corresponding block of the covariance matrix.  The name of the calling
+// function is used in the error messages.
+func (rslt *BaseResults) waldTest(fname string, ix []int, b0 []float64) (float64, float64, float64, error) {
 
-	// No vcov, no z-scores
-	if rslt.vcov == nil {
-		return nil
+	q := len(ix)
+	if rslt.vcov == nil || q == 0 {
+		msg := fmt.Sprintf("%s: the covariance matrix is not available\n", fname)
+		return 0, 0, 0, fmt.Errorf(msg)
 	}
 
-	p := rslt.model.NumParams()
-	if rslt.zscores == nil {
-		rslt.zscores = make([]float64, p)
-	} else {
-		return rslt.zscores
+	p := len(rslt.params)
+	d := make([]float64, q)
+	v := make([]float64, q*q)
+	for k1, j1 := range ix {
+		d[k1] = rslt.params[j1] - b0[k1]
+		for k2, j2 := range ix {
+			v[k1*q+k2] = rslt.vcov[j1*p+j2]
+		}
 	}
 
-	std := rslt.StdErr()
-	for i := range std {
-		rslt.zscores[i] = rslt.params[i] / std[i]
+	var u mat.VecDense
+	if err := u.SolveVec(mat.NewDense(q, q, v), mat.NewVecDense(q, d)); err != nil {
+		return 0, 0, 0, err
 	}
+	stat := mat.Dot(&u, mat.NewVecDense(q, d))
 
-	return rslt.zscores
-}
+	df := float64(q)
+	pv := distuv.ChiSquared{K: df}.Survival(stat)
 
-func normcdf(x float64) float64 {
-	return 0.5 * math.Erfc(-x/math.Sqrt(2))
+	return stat, df, pv, nil
 }
 
-// PValues returns the p-values for the null hypothesis that each parameter's population
-// value is equal to zero.
-func (rslt *BaseResults) PValues() []float64 {
-
-	// No vcov, no p-values
-	if rslt.vcov == nil {
-		return nil
+// TermWaldTest returns the Wald statistic for testing the null hypothesis
+// that the coefficients with the given names are jointly zero, e.g. the
+// coefficients of the columns that represent a spline or a factor.  The
+// statistic is b' V^{-1} b, where b contains the named coefficients and V
+// is their estimated covariance matrix.  This is the Wald test for the
+// contrast matrix that selects the named coefficients.  The degrees of
+// freedom (the number of names) and the p-value based on the chi-square
+// distribution are also returned.  An error is returned if a name is not
+// a coefficient in the model or is repeated, or if the covariance matrix
+// is not available or is singular.
+func (rslt *BaseResults) TermWaldTest(names []string) (float64, float64, float64, error) {
+
+	if len(names) == 0 {
+		return 0, 0, 0, fmt.Errorf("TermWaldTest: no coefficients were given\n")
 	}
 
-	p := rslt.model.NumParams()
-	if rslt.pvalues == nil {
-		rslt.pvalues = make([]float64, p)
-	} else {
-		return rslt.pvalues
+	ix, err := namePositions("TermWaldTest", rslt.xnames, names)
+	if err != nil {
+		return 0, 0, 0, err
 	}
 
-	for i, z := range rslt.zscores {
-		rslt.pvalues[i] = 2 * normcdf(-math.Abs(z))
+	return rslt.waldTest("TermWaldTest", ix, make([]float64, len(ix)))
+}
+
+// GetVcov returns the sampling variance/covariance matrix for the parameter estimates,
+// based on the expected information.  The result is the negative inverse Hessian, with
+// no scaling; see GetVcovScaled for models with a dispersion parameter.
+func GetVcov(model RegFitter, params Parameter) ([]float64, error) {
+	return GetVcovHess(model, params, ExpHess)
+}
+
+// GetVcovScaled returns the sampling variance/covariance matrix for the parameter
+// estimates, multiplied by the given scale parameter.  Models whose Hessian does not
+// include the dispersion, such as generalized linear models with an estimated scale,
+// should pass the estimated scale here, otherwise the standard errors are off by a
+// factor of the square root of the scale.
+func GetVcovScaled(model RegFitter, params Parameter, ht HessType, scale float64) ([]float64, error) {
+
+	vcov, err := GetVcovHess(model, params, ht)
+	if err != nil {
+		return nil, err
+	}
+	for i := range vcov {
+		vcov[i] *= scale
 	}
 
-	return rslt.pvalues
+	return vcov, nil
 }
 
-// GetVcov returns the sampling variance/covariance matrix for the parameter estimates.
-func GetVcov(model RegFitter, params Parameter) ([]float64, error) {
+// GetVcovHess returns the sampling variance/covariance matrix for the parameter estimates,
+// based on either the observed or the expected information as specified by ht.
+func GetVcovHess(model RegFitter, params Parameter, ht HessType) ([]float64, error) {
 	nvar := model.NumParams()
+	if nvar == 0 {
+		// A model with no free parameters, e.g. an offset-only model
+		return []float64{}, nil
+	}
+	if pm, ok := model.(PackedHessianer); ok && pm.UsePackedHessian() {
+		return getVcovPacked(pm, params, ht)
+	}
+
 	n2 := nvar * nvar
 	hess := make([]float64, n2)
-	model.Hessian(params, ExpHess, hess)
+	model.Hessian(params, ht, hess)
 	hmat := mat.NewDense(nvar, nvar, hess)
 	hessi := make([]float64, n2)
 	himat := mat.NewDense(nvar, nvar, hessi)
@@ -287,6 +485,162 @@ func GetVcov(model RegFitter, params Parameter) ([]float64, error) {
 	return hessi, nil
 }
 
+// getVcovPacked returns the negative inverse of the Hessian, which is
+// computed in packed form.  The Cholesky factorization A = LL' of the
+// negative Hessian A, which must be positive definite, and the inverse of
+// L are computed in place in the packed storage, so the only p x p array
+// is the returned covariance matrix, which is (L^{-1})' L^{-1}.
+func getVcovPacked(model PackedHessianer, params Parameter, ht HessType) ([]float64, error) {
+
+	nvar := model.NumParams()
+	a := make([]float64, nvar*(nvar+1)/2)
+	model.PackedHessian(params, ht, a)
+	for i := range a {
+		a[i] = -a[i]
+	}
+
+	// Overwrite the lower triangle of A with L.
+	for j := 0; j < nvar; j++ {
+		d := a[PackedIndex(j, j)]
+		for k := 0; k < j; k++ {
+			u := a[PackedIndex(j, k)]
+			d -= u * u
+		}
+		if !(d > 0) {
+			os.Stderr.Write([]byte("Can't invert Hessian\n"))
+			return nil, fmt.Errorf("The negative Hessian is not positive definite")
+		}
+		d = math.Sqrt(d)
+		a[PackedIndex(j, j)] = d
+		for i := j + 1; i < nvar; i++ {
+			u := a[PackedIndex(i, j)]
+			for k := 0; k < j; k++ {
+				u -= a[PackedIndex(i, k)] * a[PackedIndex(j, k)]
+			}
+			a[PackedIndex(i, j)] = u / d
+		}
+	}
+
+	// Overwrite L with its inverse M, one column at a time.  Column j
+	// of M depends only on column j of M and columns j and later of L.
+	for j := 0; j < nvar; j++ {
+		a[PackedIndex(j, j)] = 1 / a[PackedIndex(j, j)]
+		for i := j + 1; i < nvar; i++ {
+			var u float64
+			for k := j; k < i; k++ {
+				u -= a[PackedIndex(i, k)] * a[PackedIndex(k, j)]
+			}
+			a[PackedIndex(i, j)] = u / a[PackedIndex(i, i)]
+		}
+	}
+
+	vcov := make([]float64, nvar*nvar)
+	for i := 0; i < nvar; i++ {
+		for j := 0; j <= i; j++ {
+			var u float64
+			for k := i; k < nvar; k++ {
+				u += a[PackedIndex(k, i)] * a[PackedIndex(k, j)]
+			}
+			vcov[i*nvar+j] = u
+			vcov[j*nvar+i] = u
+		}
+	}
+
+	return vcov, nil
+}
+
+// DesignMatrix returns the covariates of the model as a NumObs x NumParams
+// matrix.  The columns are taken from the dataset at the positions given
+// by Xpos, so they are in the same order as the model coefficients.  Any
+// intercept that is included among the covariates is included in the
+// returned matrix.
+func DesignMatrix(model RegFitter) *mat.Dense {
+
+	nobs := model.NumObs()
+	xpos := model.Xpos()
+	data := model.Dataset()
+
+	x := mat.NewDense(nobs, len(xpos), nil)
+	for j, k := range xpos {
+		z := data[k]
+		for i := 0; i < nobs; i++ {
+			x.Set(i, j, float64(z[i]))
+		}
+	}
+
+	return x
+}
+
+// ParamIndex returns the position in the coefficient vector of the
+// covariate with the given name, so that contrasts and hypothesis tests
+// can be specified using the variable names.  An error is returned if
+// the model does not include a covariate with the given name, or if the
+// model does not implement VarNamer.
+func ParamIndex(model RegFitter, name string) (int, error) {
+
+	ix, err := paramPositions("ParamIndex", model, []string{name})
+	if err != nil {
+		return -1, err
+	}
+
+	return ix[0], nil
+}
+
+// ParamPositions returns the positions in the coefficient vector of the
+// covariates with the given names, in the given order.  An error is
+// returned if a name is not a covariate in the model or is repeated, or
+// if the model does not implement VarNamer.
+func ParamPositions(model RegFitter, names []string) ([]int, error) {
+	return paramPositions("ParamPositions", model, names)
+}
+
+func paramPositions(fname string, model RegFitter, names []string) ([]int, error) {
+
+	vn, ok := model.(VarNamer)
+	if !ok {
+		msg := fmt.Sprintf("%s: the model does not provide variable names\n", fname)
+		return nil, fmt.Errorf(msg)
+	}
+
+	vnames := vn.VarNames()
+	xpos := model.Xpos()
+	coefnames := make([]string, len(xpos))
+	for j, k := range xpos {
+		coefnames[j] = vnames[k]
+	}
+
+	return namePositions(fname, coefnames, names)
+}
+
+// namePositions returns the positions of names within coefnames, which
+// are the names of the coefficients of a model.  The name of the calling
+// function is used in the error messages.
+func namePositions(fname string, coefnames, names []string) ([]int, error) {
+
+	pos := make(map[string]int)
+	for j, na := range coefnames {
+		pos[na] = j
+	}
+
+	ix := make([]int, len(names))
+	seen := make(map[string]bool)
+	for k, na := range names {
+		j, ok := pos[na]
+		if !ok {
+			msg := fmt.Sprintf("%s: '%s' is not a covariate in the model\n", fname, na)
+			return nil, fmt.Errorf(msg)
+		}
+		if seen[na] {
+			msg := fmt.Sprintf("%s: '%s' is given more than once\n", fname, na)
+			return nil, fmt.Errorf(msg)
+		}
+		seen[na] = true
+		ix[k] = j
+	}
+
+	return ix, nil
+}
+
 // SummaryTable holds the summary values for a fitted model.
 type SummaryTable struct {
 
@@ -309,6 +663,15 @@ type SummaryTable struct {
 	// Messages displayed below the table
 	Msg []string
 
+	// If Color is true, the rows of the table for which Highlight is
+	// true are displayed in color using ANSI escape codes.  Color
+	// should only be used when writing to a terminal.
+	Color bool
+
+	// Highlight[i] indicates whether row i of the table is
+	// highlighted, it is only used if Color is true.
+	Highlight []bool
+
 	// Total width of the table
 	tw int
 }
@@ -323,6 +686,10 @@ func (s *SummaryTable) line(c string) string {
 // the same width.
 func (s *SummaryTable) cleanTop() {
 
+	if len(s.Top) == 0 {
+		return
+	}
+
 	w := len(s.Top[0])
 	for _, x := range s.Top {
 		if len(x) > w {
@@ -373,6 +740,40 @@ type Fmter func(interface{}, string) []string
 
 // String returns the table as a string.
 func (s *SummaryTable) String() string {
+	var buf bytes.Buffer
+	s.WriteTo(&buf)
+	return buf.String()
+}
+
+// ANSI escape codes for highlighting rows of a summary table.
+const (
+	ansiGreen = "\x1b[32m"
+	ansiReset = "\x1b[0m"
+)
+
+// countWriter writes to an io.Writer, tracking the number of bytes
+// written and the first error that occurs.  Once an error occurs, no
+// further writes are made.
+type countWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countWriter) write(x string) {
+	if cw.err != nil {
+		return
+	}
+	n, err := io.WriteString(cw.w, x)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+// WriteTo writes the table to w, one line at a time, so that the table
+// does not need to be constructed in memory as a single string.  It
+// returns the number of bytes written and any error that occurred,
+// implementing io.WriterTo.
+func (s *SummaryTable) WriteTo(w io.Writer) (int64, error) {
 
 	s.cleanTop()
 
@@ -381,7 +782,7 @@ func (s *SummaryTable) String() string {
 	for j, c := range s.Cols {
 		u := s.ColFmt[j](c, s.ColNames[j])
 		tab = append(tab, u)
-		if len(u[0]) > len(s.ColNames[j]) {
+		if len(u) > 0 && len(u[0]) > len(s.ColNames[j]) {
 			wx = append(wx, len(u[0]))
 		} else {
 			wx = append(wx, len(s.ColNames[j]))
@@ -392,17 +793,17 @@ func (s *SummaryTable) String() string {
 
 	// Get the total width of the table
 	s.tw = 0
-	for _, w := range wx {
-		s.tw += w
+	for _, x := range wx {
+		s.tw += x
 	}
 	if s.tw < len(s.Title) {
 		s.tw = len(s.Title)
 	}
-	if s.tw < gap+2*len(s.Top[0]) {
+	if len(s.Top) > 0 && s.tw < gap+2*len(s.Top[0]) {
 		s.tw = gap + 2*len(s.Top[0])
 	}
 
-	var buf bytes.Buffer
+	cw := &countWriter{w: w}
 
 	// Center the title
 	k := len(s.Title)
@@ -410,35 +811,207 @@ func (s *SummaryTable) String() string {
 	if kr < 0 {
 		kr = 0
 	}
-	buf.Write([]byte(strings.Repeat(" ", kr)))
-	buf.Write([]byte(s.Title))
-	buf.Write([]byte("\n"))
-
-	buf.Write([]byte(s.line("=")))
-	buf.Write([]byte(s.top(gap)))
-	buf.Write([]byte(s.line("-")))
+	cw.write(strings.Repeat(" ", kr))
+	cw.write(s.Title)
+	cw.write("\n")
+
+	cw.write(s.line("="))
+	if len(s.Top) > 0 {
+		cw.write(s.top(gap))
+		cw.write(s.line("-"))
+	}
 
+	var b strings.Builder
 	for j, c := range s.ColNames {
 		f := fmt.Sprintf("%%%ds", wx[j])
-		buf.Write([]byte(fmt.Sprintf(f, c)))
+		b.WriteString(fmt.Sprintf(f, c))
 	}
-	buf.Write([]byte("\n"))
-	buf.Write([]byte(s.line("-")))
+	b.WriteString("\n")
+	cw.write(b.String())
+	cw.write(s.line("-"))
 
 	for i := 0; i < len(tab[0]); i++ {
+		b.Reset()
+
+		// The escape codes are added outside the padded cells, so
+		// they do not affect the column widths.
+		hl := s.Color && i < len(s.Highlight) && s.Highlight[i]
+		if hl {
+			b.WriteString(ansiGreen)
+		}
 		for j := 0; j < len(tab); j++ {
 			f := fmt.Sprintf("%%%ds", wx[j])
-			buf.Write([]byte(fmt.Sprintf(f, tab[j][i])))
+			b.WriteString(fmt.Sprintf(f, tab[j][i]))
+		}
+		if hl {
+			b.WriteString(ansiReset)
 		}
-		buf.Write([]byte("\n"))
+		b.WriteString("\n")
+		cw.write(b.String())
 	}
-	buf.Write([]byte(s.line("-")))
+	cw.write(s.line("-"))
 
-	if len(s.Msg) > 0 {
-		for _, msg := range s.Msg {
-			buf.Write([]byte(msg + "\n"))
+	for _, msg := range s.Msg {
+		cw.write(msg + "\n")
+	}
+
+	return cw.n, cw.err
+}
+
+// Transpose returns a copy of the table in which the rows and columns of
+// the body of the table are exchanged, which is convenient when there are
+// few rows and many columns.  The values in the first column, e.g. the
+// variable names, become the column headers of the transposed table, and
+// the column headers become the labels in its first column.  The values
+// are formatted using the formatters of the original table, so the
+// columns of the transposed table contain strings.  The title, the top
+// part, and the messages are retained, but rows are not highlighted.
+func (s *SummaryTable) Transpose() *SummaryTable {
+
+	var tab [][]string
+	for j, c := range s.Cols {
+		u := s.ColFmt[j](c, s.ColNames[j])
+		for i := range u {
+			u[i] = strings.TrimSpace(u[i])
 		}
+		tab = append(tab, u)
 	}
 
-	return buf.String()
+	// Pad the cells with leading spaces to the width of the column,
+	// keeping a gap between columns.  The labels are left-aligned.
+	pad := func(left bool) Fmter {
+		return func(x interface{}, h string) []string {
+			y := x.([]string)
+			m := len(h)
+			for _, v := range y {
+				if len(v) > m {
+					m = len(v)
+				}
+			}
+			c := fmt.Sprintf("%%%ds", m+2)
+			if left {
+				c = fmt.Sprintf("%%-%ds", m+2)
+			}
+			var z []string
+			for _, v := range y {
+				z = append(z, fmt.Sprintf(c, v))
+			}
+			return z
+		}
+	}
+
+	ts := &SummaryTable{
+		Title: s.Title,
+		Top:   append([]string(nil), s.Top...),
+		Msg:   append([]string(nil), s.Msg...),
+	}
+
+	if len(tab) == 0 {
+		return ts
+	}
+
+	var labels []string
+	for _, na := range s.ColNames[1:] {
+		labels = append(labels, strings.TrimSpace(na))
+	}
+	ts.ColNames = []string{""}
+	ts.Cols = []interface{}{labels}
+	ts.ColFmt = []Fmter{pad(true)}
+
+	for i, na := range tab[0] {
+		col := make([]string, len(tab)-1)
+		for j := 1; j < len(tab); j++ {
+			col[j-1] = tab[j][i]
+		}
+		ts.ColNames = append(ts.ColNames, na)
+		ts.Cols = append(ts.Cols, col)
+		ts.ColFmt = append(ts.ColFmt, pad(false))
+	}
+
+	return ts
+}
+
+// CompareModels returns a table comparing several fitted models, with
+// one row per model showing the log-likelihood, AIC, BIC, deviance,
+// and number of parameters.  If a fitted model has AIC, BIC, or
+// Deviance methods they are used, otherwise the AIC and BIC are
+// calculated from the log-likelihood and the number of parameters, and
+// the deviance is reported as NaN.
+func CompareModels(labels []string, models []BaseResultser) SummaryTable {
+
+	if len(labels) != len(models) {
+		panic("CompareModels: labels and models must have the same length\n")
+	}
+
+	var ll, aic, bic, dev, np []float64
+	for _, rslt := range models {
+
+		k := float64(len(rslt.Params()))
+		l := rslt.LogLike()
+		ll = append(ll, l)
+		np = append(np, k)
+
+		if r, ok := rslt.(interface{ AIC() float64 }); ok {
+			aic = append(aic, r.AIC())
+		} else {
+			aic = append(aic, -2*l+2*k)
+		}
+
+		if r, ok := rslt.(interface{ BIC() float64 }); ok {
+			bic = append(bic, r.BIC())
+		} else {
+			n := float64(rslt.Model().NumObs())
+			bic = append(bic, -2*l+math.Log(n)*k)
+		}
+
+		if r, ok := rslt.(interface{ Deviance() float64 }); ok {
+			dev = append(dev, r.Deviance())
+		} else {
+			dev = append(dev, math.NaN())
+		}
+	}
+
+	// String formatter
+	fs := func(x interface{}, h string) []string {
+		y := x.([]string)
+		m := len(h)
+		for i := range y {
+			if len(y[i]) > m {
+				m = len(y[i])
+			}
+		}
+		var z []string
+		for i := range y {
+			c := fmt.Sprintf("%%-%ds", m)
+			z = append(z, fmt.Sprintf(c, y[i]))
+		}
+		return z
+	}
+
+	// Number formatter
+	fn := func(x interface{}, h string) []string {
+		y := x.([]float64)
+		var s []string
+		for i := range y {
+			s = append(s, fmt.Sprintf("%12.4f", y[i]))
+		}
+		return s
+	}
+
+	// Integer formatter
+	fi := func(x interface{}, h string) []string {
+		y := x.([]float64)
+		var s []string
+		for i := range y {
+			s = append(s, fmt.Sprintf("%6.0f", y[i]))
+		}
+		return s
+	}
+
+	return SummaryTable{
+		Title:    "Model comparison",
+		ColNames: []string{"Model   ", "Log-likelihood", "AIC", "BIC", "Deviance", "Params"},
+		ColFmt:   []Fmter{fs, fn, fn, fn, fn, fi},
+		Cols:     []interface{}{labels, ll, aic, bic, dev, np},
+	}
 }
@@ -1,6 +1,9 @@
 package statmodel
 
 import (
+	"fmt"
+	"math"
+	"strings"
 	"testing"
 
 	"gonum.org/v1/gonum/floats"
@@ -36,14 +39,19 @@ func data2() ([]string, [][]Dtype) {
 
 // A mock model for testing
 type Mock struct {
-	data [][]Dtype
-	xpos []int
+	data  [][]Dtype
+	names []string
+	xpos  []int
 }
 
 func (m *Mock) Dataset() [][]Dtype {
 	return m.data
 }
 
+func (m *Mock) VarNames() []string {
+	return m.names
+}
+
 func (m *Mock) LogLike(params Parameter, exact bool) float64 {
 	return 0
 }
@@ -93,3 +101,448 @@ func TestResult1(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestDesignMatrix(t *testing.T) {
+
+	_, da := data2()
+	model := &Mock{
+		data: da,
+		xpos: []int{3, 1},
+	}
+
+	x := DesignMatrix(model)
+	r, c := x.Dims()
+	if r != 7 || c != 2 {
+		t.Fail()
+	}
+
+	for i := 0; i < r; i++ {
+		if x.At(i, 0) != float64(da[3][i]) || x.At(i, 1) != float64(da[1][i]) {
+			t.Fail()
+		}
+	}
+}
+
+func TestSummaryEmptyTop(t *testing.T) {
+
+	fn := func(x interface{}, h string) []string {
+		var s []string
+		for _, v := range x.([]float64) {
+			s = append(s, fmt.Sprintf("%10.4f", v))
+		}
+		return s
+	}
+
+	sum := &SummaryTable{
+		Title:    "Empty top",
+		ColNames: []string{"A", "B"},
+		ColFmt:   []Fmter{fn, fn},
+		Cols:     []interface{}{[]float64{1, 2}, []float64{3, 4}},
+	}
+
+	s := sum.String()
+	if !strings.Contains(s, "Empty top") || !strings.Contains(s, "4.0000") {
+		t.Fail()
+	}
+}
+
+func TestParamsMap(t *testing.T) {
+
+	_, da := data1()
+	model := &Mock{
+		data: da,
+		xpos: []int{1, 2},
+	}
+
+	r := NewBaseResults(model, 0, []float64{1, 2}, []string{"x1", "x2"}, nil)
+	mp := r.ParamsMap()
+	if len(mp) != 2 || mp["x1"] != 1 || mp["x2"] != 2 {
+		t.Fail()
+	}
+}
+
+func TestConfInt(t *testing.T) {
+
+	_, da := data1()
+	model := &Mock{
+		data: da,
+		xpos: []int{1, 2},
+	}
+
+	r := NewBaseResults(model, 0, []float64{1, 2}, []string{"x1", "x2"}, []float64{4, 0, 0, 1})
+	lcb, ucb := r.ConfInt(0.95)
+	if !floats.EqualApprox(lcb, []float64{1 - 2*1.959964, 2 - 1.959964}, 1e-5) {
+		t.Fail()
+	}
+	if !floats.EqualApprox(ucb, []float64{1 + 2*1.959964, 2 + 1.959964}, 1e-5) {
+		t.Fail()
+	}
+}
+
+// failWriter accepts a limited number of bytes and then fails.
+type failWriter struct {
+	n int
+}
+
+func (fw *failWriter) Write(p []byte) (int, error) {
+	if len(p) > fw.n {
+		n := fw.n
+		fw.n = 0
+		return n, fmt.Errorf("write failed")
+	}
+	fw.n -= len(p)
+	return len(p), nil
+}
+
+func TestSummaryWriteTo(t *testing.T) {
+
+	fn := func(x interface{}, h string) []string {
+		var s []string
+		for _, v := range x.([]float64) {
+			s = append(s, fmt.Sprintf("%10.4f", v))
+		}
+		return s
+	}
+
+	sum := &SummaryTable{
+		Title:    "WriteTo",
+		ColNames: []string{"A", "B"},
+		ColFmt:   []Fmter{fn, fn},
+		Cols:     []interface{}{[]float64{1, 2}, []float64{3, 4}},
+		Top:      []string{"Top 1", "Top 2", "Top 3"},
+		Msg:      []string{"A message"},
+	}
+
+	var b strings.Builder
+	n, err := sum.WriteTo(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := sum.String()
+	if b.String() != s || int(n) != len(s) {
+		t.Fail()
+	}
+
+	// Errors are returned, along with the number of bytes written
+	n, err = sum.WriteTo(&failWriter{n: 20})
+	if err == nil || n != 20 {
+		t.Fail()
+	}
+}
+
+func TestSummaryColor(t *testing.T) {
+
+	fn := func(x interface{}, h string) []string {
+		var s []string
+		for _, v := range x.([]float64) {
+			s = append(s, fmt.Sprintf("%10.4f", v))
+		}
+		return s
+	}
+
+	sum := &SummaryTable{
+		Title:     "Color",
+		ColNames:  []string{"A", "B"},
+		ColFmt:    []Fmter{fn, fn},
+		Cols:      []interface{}{[]float64{1, 2, 3}, []float64{4, 5, 6}},
+		Highlight: []bool{false, true, false},
+	}
+
+	// Highlight is ignored unless Color is set
+	plain := sum.String()
+	if strings.Contains(plain, "\x1b") {
+		t.Fail()
+	}
+
+	sum.Color = true
+	color := sum.String()
+	if strings.Count(color, ansiGreen) != 1 || strings.Count(color, ansiReset) != 1 {
+		t.Fail()
+	}
+	if !strings.Contains(color, ansiGreen+"    2.0000    5.0000"+ansiReset+"\n") {
+		t.Fail()
+	}
+
+	// Apart from the escape codes, the tables are identical
+	color = strings.Replace(color, ansiGreen, "", -1)
+	color = strings.Replace(color, ansiReset, "", -1)
+	if color != plain {
+		t.Fail()
+	}
+}
+
+func TestWaldTest(t *testing.T) {
+
+	_, da := data1()
+	model := &Mock{
+		data: da,
+		xpos: []int{1, 2},
+	}
+
+	params := []float64{1, 2}
+	vcov := []float64{2, 1, 1, 2}
+	r := NewBaseResults(model, 0, params, []string{"x1", "x2"}, vcov)
+
+	// (b - b0) = (1, 1), V^{-1} = [2 -1; -1 2]/3
+	stat, df, pv, err := r.WaldTest([]float64{0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(stat-2.0/3) > 1e-12 || df != 2 || math.Abs(pv-math.Exp(-stat/2)) > 1e-12 {
+		t.Logf("%v %v %v\n", stat, df, pv)
+		t.Fail()
+	}
+
+	if _, _, _, err := r.WaldTest([]float64{0}); err == nil {
+		t.Fail()
+	}
+	r = NewBaseResults(model, 0, params, []string{"x1", "x2"}, nil)
+	if _, _, _, err := r.WaldTest([]float64{0, 1}); err == nil {
+		t.Fail()
+	}
+}
+
+func TestTermWaldTest(t *testing.T) {
+
+	_, da := data1()
+	model := &Mock{
+		data: da,
+		xpos: []int{1, 2},
+	}
+
+	params := []float64{1, 2, 3}
+	vcov := []float64{2, 1, 0, 1, 2, 1, 0, 1, 4}
+	r := NewBaseResults(model, 0, params, []string{"x1", "x2", "x3"}, vcov)
+
+	// A single coefficient gives the squared Z-score
+	stat, df, pv, err := r.TermWaldTest([]string{"x3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(stat-9.0/4) > 1e-12 || df != 1 || math.Abs(pv-2*normcdf(-1.5)) > 1e-12 {
+		t.Errorf("%v %v %v", stat, df, pv)
+	}
+
+	// b = (1, 2), V^{-1} = [2 -1; -1 2]/3, so b' V^{-1} b = 6/3
+	stat, df, pv, err = r.TermWaldTest([]string{"x1", "x2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(stat-2) > 1e-12 || df != 2 || math.Abs(pv-math.Exp(-1)) > 1e-12 {
+		t.Errorf("%v %v %v", stat, df, pv)
+	}
+
+	// All coefficients agree with WaldTest
+	s1, _, _, _ := r.TermWaldTest([]string{"x3", "x1", "x2"})
+	s2, _, _, _ := r.WaldTest([]float64{0, 0, 0})
+	if math.Abs(s1-s2) > 1e-10 {
+		t.Fail()
+	}
+
+	for _, na := range [][]string{nil, {"x4"}, {"x1", "x1"}} {
+		if _, _, _, err := r.TermWaldTest(na); err == nil {
+			t.Fail()
+		}
+	}
+	r = NewBaseResults(model, 0, params, []string{"x1", "x2", "x3"}, nil)
+	if _, _, _, err := r.TermWaldTest([]string{"x1"}); err == nil {
+		t.Fail()
+	}
+}
+
+func TestCompareModels(t *testing.T) {
+
+	_, da := data1()
+	model := &Mock{
+		data: da,
+		xpos: []int{1, 2},
+	}
+
+	r1 := NewBaseResults(model, -10, []float64{1, 2}, []string{"x1", "x2"}, nil)
+	r2 := NewBaseResults(model, -8, []float64{1, 2, 3}, []string{"x1", "x2", "x3"}, nil)
+	tab := CompareModels([]string{"small", "large"}, []BaseResultser{&r1, &r2})
+
+	aic := tab.Cols[2].([]float64)
+	bic := tab.Cols[3].([]float64)
+	dev := tab.Cols[4].([]float64)
+	if !floats.EqualApprox(aic, []float64{24, 22}, 1e-12) {
+		t.Fail()
+	}
+	if !floats.EqualApprox(bic, []float64{20 + 2*math.Log(7), 16 + 3*math.Log(7)}, 1e-12) {
+		t.Fail()
+	}
+	if !math.IsNaN(dev[0]) || !math.IsNaN(dev[1]) {
+		t.Fail()
+	}
+
+	s := tab.String()
+	if !strings.Contains(s, "small") || !strings.Contains(s, "large") || !strings.Contains(s, "24.0000") {
+		t.Logf("%s\n", s)
+		t.Fail()
+	}
+}
+
+func TestParamIndex(t *testing.T) {
+
+	names, da := data2()
+	model := &Mock{
+		data:  da,
+		names: names,
+		xpos:  []int{3, 1},
+	}
+
+	for na, want := range map[string]int{"x3": 0, "x1": 1} {
+		j, err := ParamIndex(model, na)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if j != want {
+			t.Errorf("%s: got position %d, expected %d", na, j, want)
+		}
+	}
+
+	// x2 is in the data but is not a covariate, and y is the response
+	for _, na := range []string{"x2", "y", "z"} {
+		if _, err := ParamIndex(model, na); err == nil {
+			t.Errorf("%s: expected an error", na)
+		}
+	}
+
+	ix, err := ParamPositions(model, []string{"x1", "x3"})
+	if err != nil || len(ix) != 2 || ix[0] != 1 || ix[1] != 0 {
+		t.Fail()
+	}
+	if _, err := ParamPositions(model, []string{"x1", "x1"}); err == nil {
+		t.Fail()
+	}
+
+	// A model that does not implement VarNamer can not be indexed by
+	// name.
+	var rf RegFitter = struct{ RegFitter }{model}
+	if _, err := ParamIndex(rf, "x1"); err == nil {
+		t.Fail()
+	}
+}
+
+func TestSummaryTranspose(t *testing.T) {
+
+	fs := func(x interface{}, h string) []string {
+		return x.([]string)
+	}
+	fn := func(x interface{}, h string) []string {
+		var s []string
+		for _, v := range x.([]float64) {
+			s = append(s, fmt.Sprintf("%10.4f", v))
+		}
+		return s
+	}
+
+	sum := &SummaryTable{
+		Title:    "Transpose",
+		ColNames: []string{"Variable", "A", "B"},
+		ColFmt:   []Fmter{fs, fn, fn},
+		Cols:     []interface{}{[]string{"x1", "x2"}, []float64{1, 2}, []float64{3, 4}},
+		Top:      []string{"Top 1"},
+		Msg:      []string{"A message"},
+	}
+
+	ts := sum.Transpose()
+	if len(ts.Cols) != 3 || ts.ColNames[1] != "x1" || ts.ColNames[2] != "x2" {
+		t.Fatal("unexpected transposed columns")
+	}
+	want := [][]string{{"A", "B"}, {"1.0000", "3.0000"}, {"2.0000", "4.0000"}}
+	for j, w := range want {
+		if strings.Join(ts.Cols[j].([]string), ",") != strings.Join(w, ",") {
+			t.Errorf("column %d: got %v, expected %v", j, ts.Cols[j], w)
+		}
+	}
+
+	s := ts.String()
+	if !strings.Contains(s, "Top 1") || !strings.Contains(s, "A message") {
+		t.Fail()
+	}
+}
+
+// A mock model with a fixed Hessian that can be computed in packed form
+type packedMock struct {
+	Mock
+	hess []float64
+}
+
+func (m *packedMock) Hessian(params Parameter, ht HessType, hess []float64) {
+	copy(hess, m.hess)
+}
+
+func (m *packedMock) PackedHessian(params Parameter, ht HessType, hess []float64) {
+	p := m.NumParams()
+	for i := 0; i < p; i++ {
+		for j := 0; j <= i; j++ {
+			hess[PackedIndex(i, j)] = m.hess[i*p+j]
+		}
+	}
+}
+
+func (m *packedMock) UsePackedHessian() bool {
+	return true
+}
+
+func TestGetVcovPacked(t *testing.T) {
+
+	// The Hessian is -(B'B + I)
+	p := 5
+	b := []float64{1, 2, 0, -1, 3, 0, 1, 4, 2, -2, 1, 1, 1, 0, 5, -3, 2, 0, 1, 1, 2, 0, -1, 3, 1}
+	hess := make([]float64, p*p)
+	for i := 0; i < p; i++ {
+		for j := 0; j < p; j++ {
+			for k := 0; k < p; k++ {
+				hess[i*p+j] -= b[k*p+i] * b[k*p+j]
+			}
+			if i == j {
+				hess[i*p+j]--
+			}
+		}
+	}
+
+	model := &packedMock{Mock: Mock{xpos: []int{0, 1, 2, 3, 4}}, hess: hess}
+	vcov, err := GetVcovHess(model, nil, ExpHess)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The dense path is used if the model is not a PackedHessianer
+	dvcov, err := GetVcovHess(struct{ RegFitter }{model}, nil, ExpHess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !floats.EqualApprox(vcov, dvcov, 1e-10) {
+		t.Errorf("%v != %v", vcov, dvcov)
+	}
+
+	// The negative Hessian must be positive definite
+	hess[0] = 1
+	if _, err := GetVcovHess(model, nil, ExpHess); err == nil {
+		t.Fail()
+	}
+}
+
+func TestPValues(t *testing.T) {
+
+	_, da := data1()
+	model := &Mock{
+		data: da,
+		xpos: []int{1, 2},
+	}
+
+	// The p-values are available without first calling ZScores
+	params := []float64{1, -3}
+	vcov := []float64{0.25, 0, 0, 4}
+	r := NewBaseResults(model, 0, params, []string{"x1", "x2"}, vcov)
+	pv := r.PValues()
+	want := []float64{2 * normcdf(-2), 2 * normcdf(-1.5)}
+	if !floats.EqualApprox(pv, want, 1e-12) {
+		t.Errorf("%v != %v", pv, want)
+	}
+	if !floats.EqualApprox(r.ZScores(), []float64{2, -1.5}, 1e-12) {
+		t.Fail()
+	}
+}
@@ -0,0 +1,127 @@
+package statmodel
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ContrastType defines how a categorical variable (factor) is coded
+// into numeric columns.
+type ContrastType int
+
+// TreatmentContrast, SumContrast and HelmertContrast are the supported
+// codings of a factor with K levels into K-1 columns, corresponding to
+// contr.treatment, contr.sum and contr.helmert in R.
+//
+// With treatment (dummy) coding, the first level is the reference, and
+// each coefficient contrasts one of the other levels with the reference.
+//
+// With sum (deviation) coding, the last level is omitted, and each
+// coefficient contrasts one of the other levels with the average over
+// all levels.
+//
+// With Helmert coding, the j^th coefficient contrasts level j+1 with the
+// average of the first j levels (up to a scaling factor).
+const (
+	TreatmentContrast ContrastType = iota
+	SumContrast
+	HelmertContrast
+)
+
+// String returns the name of the contrast type.
+func (ct ContrastType) String() string {
+	switch ct {
+	case TreatmentContrast:
+		return "Treatment"
+	case SumContrast:
+		return "Sum"
+	case HelmertContrast:
+		return "Helmert"
+	default:
+		return fmt.Sprintf("ContrastType(%d)", int(ct))
+	}
+}
+
+// ExpandFactor codes the categorical variable x, which has the given name,
+// into numeric columns using the given contrast type.  The levels of the
+// factor are the distinct values of x in increasing order.  A factor with
+// K levels is coded into K-1 columns, which are returned along with their
+// names.  The names have the form name[T.level], name[S.level] or
+// name[H.level] for treatment, sum and Helmert coding respectively.  The
+// returned columns and names can be appended to the columns and names of
+// a Dataset.
+func ExpandFactor(x []Dtype, name string, contrast ContrastType) ([][]Dtype, []string, error) {
+
+	// Get the sorted levels
+	lm := make(map[Dtype]int)
+	for _, v := range x {
+		lm[v] = 0
+	}
+	var levels []float64
+	for v := range lm {
+		levels = append(levels, float64(v))
+	}
+	sort.Float64s(levels)
+	for j, v := range levels {
+		lm[Dtype(v)] = j
+	}
+
+	nlev := len(levels)
+	if nlev < 2 {
+		msg := fmt.Sprintf("Factor '%s' has fewer than two levels\n", name)
+		return nil, nil, fmt.Errorf(msg)
+	}
+
+	// The contrast matrix, cmat[k][j] is the value in column j for
+	// an observation at level k.
+	cmat := make([][]Dtype, nlev)
+	for k := range cmat {
+		cmat[k] = make([]Dtype, nlev-1)
+	}
+
+	var names []string
+	lname := func(tag string, k int) string {
+		lev := strconv.FormatFloat(levels[k], 'g', -1, 64)
+		return fmt.Sprintf("%s[%s.%s]", name, tag, lev)
+	}
+
+	switch contrast {
+	case TreatmentContrast:
+		for j := 0; j < nlev-1; j++ {
+			cmat[j+1][j] = 1
+			names = append(names, lname("T", j+1))
+		}
+	case SumContrast:
+		for j := 0; j < nlev-1; j++ {
+			cmat[j][j] = 1
+			cmat[nlev-1][j] = -1
+			names = append(names, lname("S", j))
+		}
+	case HelmertContrast:
+		for j := 0; j < nlev-1; j++ {
+			for k := 0; k <= j; k++ {
+				cmat[k][j] = -1
+			}
+			cmat[j+1][j] = Dtype(j + 1)
+			names = append(names, lname("H", j+1))
+		}
+	default:
+		msg := fmt.Sprintf("Unknown contrast type: %v\n", contrast)
+		return nil, nil, fmt.Errorf(msg)
+	}
+
+	cols := make([][]Dtype, nlev-1)
+	for j := range cols {
+		cols[j] = make([]Dtype, len(x))
+	}
+
+	for i, v := range x {
+		row := cmat[lm[v]]
+		for j := range cols {
+			cols[j][i] = row[j]
+		}
+	}
+
+	return cols, names, nil
+}
@@ -0,0 +1,58 @@
+package statmodel
+
+import (
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+)
+
+func TestExpandFactor(t *testing.T) {
+
+	x := []Dtype{2, 1, 3, 3, 1}
+
+	for _, tc := range []struct {
+		contrast ContrastType
+		names    []string
+		cols     [][]Dtype
+	}{
+		{
+			contrast: TreatmentContrast,
+			names:    []string{"g[T.2]", "g[T.3]"},
+			cols:     [][]Dtype{{1, 0, 0, 0, 0}, {0, 0, 1, 1, 0}},
+		},
+		{
+			contrast: SumContrast,
+			names:    []string{"g[S.1]", "g[S.2]"},
+			cols:     [][]Dtype{{0, 1, -1, -1, 1}, {1, 0, -1, -1, 0}},
+		},
+		{
+			contrast: HelmertContrast,
+			names:    []string{"g[H.2]", "g[H.3]"},
+			cols:     [][]Dtype{{1, -1, 0, 0, -1}, {-1, -1, 2, 2, -1}},
+		},
+	} {
+		cols, names, err := ExpandFactor(x, "g", tc.contrast)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(names) != len(tc.names) {
+			t.Fail()
+			continue
+		}
+		for j := range names {
+			if names[j] != tc.names[j] {
+				t.Logf("%v: %v != %v\n", tc.contrast, names, tc.names)
+				t.Fail()
+			}
+			if !floats.Equal(cols[j], tc.cols[j]) {
+				t.Logf("%v: %v != %v\n", tc.contrast, cols, tc.cols)
+				t.Fail()
+			}
+		}
+	}
+
+	if _, _, err := ExpandFactor([]Dtype{1, 1}, "g", TreatmentContrast); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,147 @@
+package statmodel
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// sparkChars are the characters used to draw sparklines, in order of
+// increasing height.  Only ASCII characters are used so that they do not
+// interfere with the width calculations in SummaryTable.
+const sparkChars = " .:-=+*#%@"
+
+// SparklineFmter returns a Fmter that renders each element of a column of
+// type [][]float64 as a compact histogram (a sparkline) with nbins bins.
+// For example, this can be used to display the distribution of predicted
+// probabilities within groups.  The bins span the range of all values in
+// the column, so the histograms in different rows are comparable, and the
+// height of each bar is relative to the largest bin in its row.
+func SparklineFmter(nbins int) Fmter {
+
+	if nbins < 1 {
+		panic("SparklineFmter: nbins must be positive")
+	}
+
+	return func(x interface{}, h string) []string {
+
+		y := x.([][]float64)
+
+		// The range of the data in all rows
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, v := range y {
+			for _, u := range v {
+				lo = math.Min(lo, u)
+				hi = math.Max(hi, u)
+			}
+		}
+		width := (hi - lo) / float64(nbins)
+
+		m := len(h)
+		if nbins > m {
+			m = nbins
+		}
+		c := fmt.Sprintf("%%-%ds", m)
+
+		var z []string
+		counts := make([]int, nbins)
+		for _, v := range y {
+			for k := range counts {
+				counts[k] = 0
+			}
+			var mx int
+			for _, u := range v {
+				k := 0
+				if width > 0 {
+					k = int((u - lo) / width)
+				}
+				if k >= nbins {
+					k = nbins - 1
+				}
+				counts[k]++
+				if counts[k] > mx {
+					mx = counts[k]
+				}
+			}
+
+			var b strings.Builder
+			for _, n := range counts {
+				q := 0
+				if mx > 0 {
+					q = int(math.Ceil(float64(n*(len(sparkChars)-1)) / float64(mx)))
+				}
+				b.WriteByte(sparkChars[q])
+			}
+			z = append(z, fmt.Sprintf(c, b.String()))
+		}
+
+		return z
+	}
+}
+
+// QuantileFmter returns a Fmter that renders each element of a column of
+// type [][]float64 as a list of quantiles, at the given probability
+// points, separated by spaces.  Each quantile is formatted using the
+// given fixed-width format, e.g. "%.3f".  The quantiles are obtained by
+// linear interpolation between order statistics.
+func QuantileFmter(probs []float64, format string) Fmter {
+
+	for _, p := range probs {
+		if p < 0 || p > 1 {
+			msg := fmt.Sprintf("QuantileFmter: invalid probability %f\n", p)
+			panic(msg)
+		}
+	}
+
+	return func(x interface{}, h string) []string {
+
+		y := x.([][]float64)
+
+		var z []string
+		var w int
+		for _, v := range y {
+			s := make([]float64, len(v))
+			copy(s, v)
+			sort.Float64s(s)
+
+			var q []string
+			for _, p := range probs {
+				q = append(q, fmt.Sprintf(format, quantile(s, p)))
+			}
+			u := strings.Join(q, " ")
+			if len(u) > w {
+				w = len(u)
+			}
+			z = append(z, u)
+		}
+
+		// Ensure that all cells have the same width
+		if len(h) > w {
+			w = len(h)
+		}
+		c := fmt.Sprintf("%%%ds", w)
+		for i := range z {
+			z[i] = fmt.Sprintf(c, z[i])
+		}
+
+		return z
+	}
+}
+
+// quantile returns the p^th quantile of the sorted values in s, using
+// linear interpolation between order statistics.
+func quantile(s []float64, p float64) float64 {
+
+	if len(s) == 0 {
+		return math.NaN()
+	}
+
+	f := p * float64(len(s)-1)
+	i := int(math.Floor(f))
+	if i >= len(s)-1 {
+		return s[len(s)-1]
+	}
+
+	return s[i] + (f-float64(i))*(s[i+1]-s[i])
+}
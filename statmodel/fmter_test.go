@@ -0,0 +1,43 @@
+package statmodel
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDistributionFmters(t *testing.T) {
+
+	cols := [][]float64{
+		{0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{9, 9, 9, 9, 8},
+		{},
+	}
+
+	sp := SparklineFmter(5)(cols, "Hist")
+	if len(sp) != 3 || sp[0] != "@++++" || sp[1] != "    @" || sp[2] != "     " {
+		t.Logf("%q\n", sp)
+		t.Fail()
+	}
+
+	qs := QuantileFmter([]float64{0, 0.5, 1}, "%.1f")(cols, "Quantiles")
+	if len(qs) != 3 || qs[0] != "0.0 3.5 9.0" || qs[1] != "8.0 9.0 9.0" || qs[2] != "NaN NaN NaN" {
+		t.Logf("%q\n", qs)
+		t.Fail()
+	}
+
+	// The formatters compose with SummaryTable
+	fs := func(x interface{}, h string) []string {
+		return x.([]string)
+	}
+	sum := &SummaryTable{
+		Title:    "Distributions",
+		ColNames: []string{"Group", "Hist", "Quantiles"},
+		ColFmt:   []Fmter{fs, SparklineFmter(5), QuantileFmter([]float64{0, 0.5, 1}, "%.1f")},
+		Cols:     []interface{}{[]string{"a    ", "b    ", "c    "}, cols, cols},
+	}
+	for _, line := range strings.Split(sum.String(), "\n") {
+		if strings.HasPrefix(line, "a") && !strings.Contains(line, "@++++") {
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package statmodel
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Resampler is a seedable source of random row indices.  It is used by
+// all resampling-based procedures (data splitting, cross validation,
+// bootstrap, permutation tests) so that their results are reproducible.
+// A Resampler is not safe for concurrent use.
+type Resampler struct {
+	rng *rand.Rand
+}
+
+// NewResampler returns a Resampler that produces a deterministic sequence
+// of results for the given seed.
+func NewResampler(seed int64) *Resampler {
+	return &Resampler{
+		rng: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Permutation returns a random permutation of the observation indices
+// 0, ..., n-1.
+func (r *Resampler) Permutation(n int) []int {
+	return r.rng.Perm(n)
+}
+
+// Bootstrap returns n observation indices sampled with replacement from
+// 0, ..., n-1.
+func (r *Resampler) Bootstrap(n int) []int {
+
+	ix := make([]int, n)
+	for i := range ix {
+		ix[i] = r.rng.Intn(n)
+	}
+
+	return ix
+}
+
+// DirichletWeights returns n observation weights for the Bayesian
+// bootstrap, which are n times a draw from the Dirichlet(1, ..., 1)
+// distribution, so that the weights are positive and sum to n.  Fitting
+// a model with these weights, rather than to a resampled dataset,
+// produces a bootstrap replicate that has no tied observations.
+func (r *Resampler) DirichletWeights(n int) []float64 {
+
+	w := make([]float64, n)
+	var tot float64
+	for i := range w {
+		w[i] = r.rng.ExpFloat64()
+		tot += w[i]
+	}
+	for i := range w {
+		w[i] *= float64(n) / tot
+	}
+
+	return w
+}
+
+// Float64 returns a uniform random value in [0, 1).
+func (r *Resampler) Float64() float64 {
+	return r.rng.Float64()
+}
+
+// Resample returns a copy of the data containing the given rows, in the
+// given order.  Rows may be repeated.
+func Resample(data [][]Dtype, rows []int) [][]Dtype {
+
+	rd := make([][]Dtype, len(data))
+	for j, x := range data {
+		y := make([]Dtype, len(rows))
+		for i, k := range rows {
+			y[i] = x[k]
+		}
+		rd[j] = y
+	}
+
+	return rd
+}
+
+// SplitDataset randomly partitions the rows of a dataset into a training
+// set and a test set.  The training set contains the given fraction of the
+// rows (rounded to the nearest integer), and the test set contains the
+// remaining rows.  Within each subset, the rows are in their original
+// order.  Both datasets have the same columns and names as d.  The split
+// is reproducible for a given seed.
+func SplitDataset(d Dataset, fraction float64, seed int64) (Dataset, Dataset) {
+
+	if fraction < 0 || fraction > 1 {
+		msg := fmt.Sprintf("SplitDataset: fraction must be between 0 and 1, got %f\n", fraction)
+		panic(msg)
+	}
+
+	data := d.Data()
+	var n int
+	if len(data) > 0 {
+		n = len(data[0])
+	}
+	ntrain := int(math.Round(fraction * float64(n)))
+
+	perm := NewResampler(seed).Permutation(n)
+	train := perm[0:ntrain]
+	test := perm[ntrain:]
+	sort.Ints(train)
+	sort.Ints(test)
+
+	return NewDataset(Resample(data, train), d.Names()), NewDataset(Resample(data, test), d.Names())
+}
+
+// Subset returns a dataset containing the given rows of d, in the given
+// order.  If the rows form a contiguous increasing range, the returned
+// dataset is a view that shares storage with d, otherwise the selected
+// rows are copied.  The row indices must be valid row positions of d.
+func Subset(d Dataset, keep []int) Dataset {
+
+	data := d.Data()
+	var n int
+	if len(data) > 0 {
+		n = len(data[0])
+	}
+
+	contig := true
+	for i, k := range keep {
+		if k < 0 || k >= n {
+			msg := fmt.Sprintf("Subset: row index %d is out of range\n", k)
+			panic(msg)
+		}
+		if i > 0 && k != keep[i-1]+1 {
+			contig = false
+		}
+	}
+
+	if !contig || len(keep) == 0 {
+		return NewDataset(Resample(data, keep), d.Names())
+	}
+
+	i0, i1 := keep[0], keep[len(keep)-1]+1
+	sd := make([][]Dtype, len(data))
+	for j, x := range data {
+		sd[j] = x[i0:i1:i1]
+	}
+
+	return NewDataset(sd, d.Names())
+}
+
+// SubsetMask returns a dataset containing the rows of d for which mask
+// is true.  The length of mask must equal the number of rows of d.
+func SubsetMask(d Dataset, mask []bool) Dataset {
+
+	data := d.Data()
+	if len(data) > 0 && len(mask) != len(data[0]) {
+		msg := fmt.Sprintf("SubsetMask: mask has length %d, but there are %d rows\n", len(mask), len(data[0]))
+		panic(msg)
+	}
+
+	var keep []int
+	for i, m := range mask {
+		if m {
+			keep = append(keep, i)
+		}
+	}
+
+	return Subset(d, keep)
+}
+
+// ConcatDatasets returns a dataset containing the rows of all of the
+// given datasets, in order.  The datasets must have the same variable
+// names in the same order, and the columns within each dataset must
+// have equal lengths.  The data are copied, so the result does not
+// share memory with the arguments.
+func ConcatDatasets(ds ...Dataset) (Dataset, error) {
+
+	if len(ds) == 0 {
+		return nil, fmt.Errorf("ConcatDatasets: no datasets provided\n")
+	}
+
+	names := ds[0].Names()
+	var n int
+	for k, d := range ds {
+		dn := d.Names()
+		if len(dn) != len(names) {
+			msg := fmt.Sprintf("ConcatDatasets: dataset %d has %d variables, dataset 0 has %d\n", k, len(dn), len(names))
+			return nil, fmt.Errorf(msg)
+		}
+		for j := range dn {
+			if dn[j] != names[j] {
+				msg := fmt.Sprintf("ConcatDatasets: variable %d of dataset %d is '%s', expected '%s'\n", j, k, dn[j], names[j])
+				return nil, fmt.Errorf(msg)
+			}
+		}
+		data := d.Data()
+		for j := range data {
+			if len(data[j]) != len(data[0]) {
+				msg := fmt.Sprintf("ConcatDatasets: the columns of dataset %d have different lengths\n", k)
+				return nil, fmt.Errorf(msg)
+			}
+		}
+		if len(data) > 0 {
+			n += len(data[0])
+		}
+	}
+
+	cd := make([][]Dtype, len(names))
+	for j := range cd {
+		cd[j] = make([]Dtype, 0, n)
+		for _, d := range ds {
+			cd[j] = append(cd[j], d.Data()[j]...)
+		}
+	}
+
+	nm := make([]string, len(names))
+	copy(nm, names)
+
+	return NewDataset(cd, nm), nil
+}
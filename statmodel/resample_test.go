@@ -0,0 +1,163 @@
+package statmodel
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestResampler(t *testing.T) {
+
+	p1 := NewResampler(42).Permutation(20)
+	p2 := NewResampler(42).Permutation(20)
+	for i := range p1 {
+		if p1[i] != p2[i] {
+			t.Fail()
+		}
+	}
+
+	// Check that it is a permutation
+	sort.Ints(p2)
+	for i := range p2 {
+		if p2[i] != i {
+			t.Fail()
+		}
+	}
+
+	b1 := NewResampler(3).Bootstrap(50)
+	b2 := NewResampler(3).Bootstrap(50)
+	for i := range b1 {
+		if b1[i] != b2[i] || b1[i] < 0 || b1[i] >= 50 {
+			t.Fail()
+		}
+	}
+
+	w1 := NewResampler(3).DirichletWeights(50)
+	w2 := NewResampler(3).DirichletWeights(50)
+	var tot float64
+	for i := range w1 {
+		if w1[i] != w2[i] || !(w1[i] > 0) {
+			t.Fail()
+		}
+		tot += w1[i]
+	}
+	if math.Abs(tot-50) > 1e-10 {
+		t.Fail()
+	}
+
+	_, da := data1()
+	rd := Resample(da, []int{2, 2, 0})
+	if len(rd) != 3 || len(rd[0]) != 3 || rd[0][0] != 3 || rd[0][1] != 3 || rd[2][2] != 4 {
+		t.Fail()
+	}
+}
+
+func TestSplitDataset(t *testing.T) {
+
+	n := 20
+	x := make([]Dtype, n)
+	y := make([]Dtype, n)
+	for i := range x {
+		x[i] = Dtype(i)
+		y[i] = Dtype(2 * i)
+	}
+	d := NewDataset([][]Dtype{x, y}, []string{"x", "y"})
+
+	train, test := SplitDataset(d, 0.7, 5)
+	if len(train.Data()[0]) != 14 || len(test.Data()[0]) != 6 {
+		t.Fail()
+	}
+	if train.Names()[1] != "y" || test.Names()[0] != "x" {
+		t.Fail()
+	}
+
+	// Each row appears in exactly one subset, with its columns intact
+	seen := make(map[Dtype]bool)
+	for _, ds := range []Dataset{train, test} {
+		da := ds.Data()
+		for i := range da[0] {
+			if da[1][i] != 2*da[0][i] || seen[da[0][i]] {
+				t.Fail()
+			}
+			seen[da[0][i]] = true
+		}
+	}
+	if len(seen) != n {
+		t.Fail()
+	}
+
+	// The split is reproducible
+	train2, _ := SplitDataset(d, 0.7, 5)
+	for i, v := range train2.Data()[0] {
+		if v != train.Data()[0][i] {
+			t.Fail()
+		}
+	}
+}
+
+func TestSubset(t *testing.T) {
+
+	x := []Dtype{0, 1, 2, 3, 4, 5}
+	y := []Dtype{0, 2, 4, 6, 8, 10}
+	d := NewDataset([][]Dtype{x, y}, []string{"x", "y"})
+
+	// A contiguous range is a view
+	s := Subset(d, []int{2, 3, 4})
+	if len(s.Data()[0]) != 3 || s.Data()[1][0] != 4 || &s.Data()[0][0] != &x[2] {
+		t.Fail()
+	}
+
+	// Other selections are copies
+	s = Subset(d, []int{5, 1})
+	if len(s.Data()[0]) != 2 || s.Data()[0][0] != 5 || s.Data()[1][1] != 2 || &s.Data()[0][1] == &x[1] {
+		t.Fail()
+	}
+
+	s = SubsetMask(d, []bool{true, false, false, true, true, false})
+	if len(s.Data()[0]) != 3 || s.Data()[0][1] != 3 || s.Data()[1][2] != 8 {
+		t.Fail()
+	}
+
+	if len(Subset(d, nil).Data()[0]) != 0 || s.Names()[1] != "y" {
+		t.Fail()
+	}
+}
+
+func TestConcatDatasets(t *testing.T) {
+
+	d1 := NewDataset([][]Dtype{{1, 2}, {3, 4}}, []string{"y", "x"})
+	d2 := NewDataset([][]Dtype{{5}, {6}}, []string{"y", "x"})
+	d3 := NewDataset([][]Dtype{{7, 8, 9}, {10, 11, 12}}, []string{"y", "x"})
+
+	d, err := ConcatDatasets(d1, d2, d3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d.Data(), [][]Dtype{{1, 2, 5, 7, 8, 9}, {3, 4, 6, 10, 11, 12}}) {
+		t.Fail()
+	}
+	if !reflect.DeepEqual(d.Names(), []string{"y", "x"}) {
+		t.Fail()
+	}
+
+	// The result does not share memory with the inputs
+	d.Data()[0][0] = 100
+	if d1.Data()[0][0] != 1 {
+		t.Fail()
+	}
+
+	// Schema mismatches
+	for _, bad := range []Dataset{
+		NewDataset([][]Dtype{{1}, {2}}, []string{"x", "y"}),
+		NewDataset([][]Dtype{{1}}, []string{"y"}),
+		NewDataset([][]Dtype{{1, 2}, {2}}, []string{"y", "x"}),
+	} {
+		if _, err := ConcatDatasets(d1, bad); err == nil {
+			t.Fail()
+		}
+	}
+	if _, err := ConcatDatasets(); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,159 @@
+package statmodel
+
+import (
+	"fmt"
+)
+
+// checkKnots panics if there are fewer than minKnots knots, or if the
+// knots are not strictly increasing.
+func checkKnots(fname string, knots []float64, minKnots int) {
+
+	if len(knots) < minKnots {
+		msg := fmt.Sprintf("%s: at least %d knots are required, got %d\n", fname, minKnots, len(knots))
+		panic(msg)
+	}
+	for j := 1; j < len(knots); j++ {
+		if !(knots[j] > knots[j-1]) {
+			msg := fmt.Sprintf("%s: the knots must be strictly increasing\n", fname)
+			panic(msg)
+		}
+	}
+}
+
+// BSpline expands x into a B-spline basis of the given degree, e.g. 3 for
+// cubic splines, with columns that can be used as covariates to fit a
+// smooth nonlinear effect of x.  The first and last knots are the
+// boundary knots, which must include all values of x, and any other
+// knots are interior knots.  The knots must be strictly increasing.  The
+// B-spline basis functions sum to one, so that their span includes the
+// intercept.  As with the factor codings in ExpandFactor, the first basis
+// function is omitted so that the columns can be used in a model with an
+// intercept.  There are len(knots)+degree-2 returned columns, and their
+// names are bs1, bs2, etc.  Up to the omitted column, the basis is the
+// same as produced by bs in the R splines package.  BSpline panics if
+// the knots or degree are invalid.
+func BSpline(x []Dtype, knots []float64, degree int) ([][]Dtype, []string) {
+
+	if degree < 0 {
+		msg := fmt.Sprintf("BSpline: the degree must be non-negative, got %d\n", degree)
+		panic(msg)
+	}
+	checkKnots("BSpline", knots, 2)
+
+	lo, hi := knots[0], knots[len(knots)-1]
+	for i, v := range x {
+		if !(float64(v) >= lo && float64(v) <= hi) {
+			msg := fmt.Sprintf("BSpline: x[%d] = %v is outside the boundary knots [%v, %v]\n", i, v, lo, hi)
+			panic(msg)
+		}
+	}
+
+	// The full knot sequence, with the boundary knots repeated so that
+	// they have multiplicity degree+1.
+	t := make([]float64, 0, len(knots)+2*degree)
+	for j := 0; j < degree; j++ {
+		t = append(t, knots[0])
+	}
+	t = append(t, knots...)
+	for j := 0; j < degree; j++ {
+		t = append(t, knots[len(knots)-1])
+	}
+
+	// The number of basis functions
+	nb := len(t) - degree - 1
+
+	cols := make([][]Dtype, nb-1)
+	names := make([]string, nb-1)
+	for j := range cols {
+		cols[j] = make([]Dtype, len(x))
+		names[j] = fmt.Sprintf("bs%d", j+1)
+	}
+
+	b := make([]float64, degree+1)
+	left := make([]float64, degree+1)
+	right := make([]float64, degree+1)
+	for i, v := range x {
+		u := float64(v)
+
+		// The knot span containing u, the last span includes the
+		// right boundary knot.
+		s := degree
+		for s < nb-1 && u >= t[s+1] {
+			s++
+		}
+
+		// The basis functions s-degree, ..., s are non-zero on the
+		// span, evaluate them with the Cox-de Boor recursion.
+		b[0] = 1
+		for j := 1; j <= degree; j++ {
+			left[j] = u - t[s+1-j]
+			right[j] = t[s+j] - u
+			var saved float64
+			for r := 0; r < j; r++ {
+				f := b[r] / (right[r+1] + left[j-r])
+				b[r] = saved + right[r+1]*f
+				saved = left[j-r] * f
+			}
+			b[j] = saved
+		}
+
+		for r := 0; r <= degree; r++ {
+			if k := s - degree + r; k > 0 {
+				cols[k-1][i] = Dtype(b[r])
+			}
+		}
+	}
+
+	return cols, names
+}
+
+// NaturalSpline expands x into a natural cubic spline basis with the
+// given knots, using the restricted cubic spline parameterization of
+// Harrell (Regression Modeling Strategies, section 2.4.5).  The spline is
+// cubic between the knots and linear below the first knot and above the
+// last knot, which makes it more stable than a cubic B-spline in the
+// tails.  Values of x may lie outside the knots.  With k knots, which
+// must be strictly increasing, there are k-1 columns, named ns1, ns2,
+// etc.  The first column is x, and for j = 1, ..., k-2 column j+1 is
+//
+//	(x-t_j)+^3 - (x-t_{k-1})+^3 (t_k-t_j)/(t_k-t_{k-1}) + (x-t_k)+^3 (t_{k-1}-t_j)/(t_k-t_{k-1})
+//
+// divided by (t_k-t_1)^2, where t_1, ..., t_k are the knots and u+ is
+// max(u, 0).  The normalization puts the columns on the same scale as x,
+// as in rcspline.eval in the R Hmisc package.  The columns do not include
+// an intercept.  NaturalSpline panics if there are fewer than three
+// knots, or if the knots are not strictly increasing.
+func NaturalSpline(x []Dtype, knots []float64) ([][]Dtype, []string) {
+
+	checkKnots("NaturalSpline", knots, 3)
+
+	k := len(knots)
+	tk, tk1 := knots[k-1], knots[k-2]
+	norm := (tk - knots[0]) * (tk - knots[0])
+
+	cube := func(u float64) float64 {
+		if u <= 0 {
+			return 0
+		}
+		return u * u * u
+	}
+
+	cols := make([][]Dtype, k-1)
+	names := make([]string, k-1)
+	for j := range cols {
+		cols[j] = make([]Dtype, len(x))
+		names[j] = fmt.Sprintf("ns%d", j+1)
+	}
+
+	for i, v := range x {
+		u := float64(v)
+		cols[0][i] = v
+		for j := 0; j < k-2; j++ {
+			tj := knots[j]
+			c := cube(u-tj) - cube(u-tk1)*(tk-tj)/(tk-tk1) + cube(u-tk)*(tk1-tj)/(tk-tk1)
+			cols[j+1][i] = Dtype(c / norm)
+		}
+	}
+
+	return cols, names
+}
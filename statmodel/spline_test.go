@@ -0,0 +1,148 @@
+package statmodel
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBSpline(t *testing.T) {
+
+	x := []Dtype{0, 0.25, 0.5, 1, 1.5, 2}
+
+	// Linear splines are hat functions centered at the knots
+	cols, names := BSpline(x, []float64{0, 1, 2}, 1)
+	if len(cols) != 2 || names[0] != "bs1" || names[1] != "bs2" {
+		t.Fatalf("unexpected names: %v", names)
+	}
+	want := [][]Dtype{
+		{0, 0.25, 0.5, 1, 0.5, 0},
+		{0, 0, 0, 0, 0.5, 1},
+	}
+	for j := range want {
+		for i := range x {
+			if math.Abs(float64(cols[j][i]-want[j][i])) > 1e-12 {
+				t.Errorf("linear basis column %d, row %d: got %v, expected %v", j, i, cols[j][i], want[j][i])
+			}
+		}
+	}
+
+	// Cubic splines without interior knots are the Bernstein
+	// polynomials, as from bs(x, df=3, Boundary.knots=c(0, 2)) in R
+	cols, _ = BSpline(x, []float64{0, 2}, 3)
+	if len(cols) != 3 {
+		t.Fatalf("expected 3 columns, got %d", len(cols))
+	}
+	for i, v := range x {
+		u := float64(v) / 2
+		bern := []float64{3 * u * (1 - u) * (1 - u), 3 * u * u * (1 - u), u * u * u}
+		for j := range bern {
+			if math.Abs(float64(cols[j][i])-bern[j]) > 1e-12 {
+				t.Errorf("cubic basis column %d, row %d: got %v, expected %v", j, i, cols[j][i], bern[j])
+			}
+		}
+	}
+
+	// With interior knots, the basis functions are non-negative and,
+	// with the omitted first function, sum to one.  The first function
+	// is (1-x)^3 on the first interval.
+	knots := []float64{0, 0.5, 1.2, 2}
+	cols, _ = BSpline(x, knots, 3)
+	if len(cols) != 5 {
+		t.Fatalf("expected 5 columns, got %d", len(cols))
+	}
+	for i, v := range x {
+		var tot float64
+		for j := range cols {
+			if cols[j][i] < 0 {
+				t.Fail()
+			}
+			tot += float64(cols[j][i])
+		}
+		var first float64
+		if u := float64(v); u < 0.5 {
+			first = math.Pow(1-u/0.5, 3)
+		}
+		if math.Abs(1-tot-first) > 1e-12 {
+			t.Errorf("row %d: the basis functions sum to %v", i, tot+first)
+		}
+	}
+
+	// Reference values from the Cox-de Boor recursion
+	cols, _ = BSpline([]Dtype{0.7, 1.5}, knots, 3)
+	ref := [][]float64{
+		{0.1240079365, 0.6053968254, 0.2655158730, 0.0050793651, 0},
+		{0, 0.0520833333, 0.3732638889, 0.5219184028, 0.0527343750},
+	}
+	for i := range ref {
+		for j, v := range ref[i] {
+			if math.Abs(float64(cols[j][i])-v) > 1e-9 {
+				t.Errorf("row %d, column %d: got %v, expected %v", i, j, cols[j][i], v)
+			}
+		}
+	}
+
+	for _, f := range []func(){
+		func() { BSpline(x, []float64{0, 1}, 3) },
+		func() { BSpline(x, []float64{0, 2, 1, 3}, 3) },
+		func() { BSpline(x, []float64{0, 2}, -1) },
+		func() { BSpline(x, []float64{0}, 3) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fail()
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestNaturalSpline(t *testing.T) {
+
+	x := []Dtype{-1, 0, 0.5, 1.5, 2.5, 3, 4, 5, 6}
+	knots := []float64{0, 1, 2, 3}
+	cols, names := NaturalSpline(x, knots)
+	if len(cols) != 3 || names[0] != "ns1" || names[2] != "ns3" {
+		t.Fatalf("unexpected names: %v", names)
+	}
+
+	// Reference values, calculated by hand from the truncated power
+	// representation and normalized by (3-0)^2.
+	want := [][]float64{
+		{-1, 0, 0.5, 1.5, 2.5, 3, 4, 5, 6},
+		{0, 0, 0.125 / 9, 3.375 / 9, 15.25 / 9, 24.0 / 9, 42.0 / 9, 60.0 / 9, 78.0 / 9},
+		{0, 0, 0, 0.125 / 9, 3.125 / 9, 6.0 / 9, 12.0 / 9, 18.0 / 9, 24.0 / 9},
+	}
+	for j := range want {
+		for i, v := range want[j] {
+			if math.Abs(float64(cols[j][i])-v) > 1e-12 {
+				t.Errorf("column %d, row %d: got %v, expected %v", j, i, cols[j][i], v)
+			}
+		}
+	}
+
+	// The basis is linear beyond the boundary knots
+	cols, _ = NaturalSpline([]Dtype{4, 5, 7, 8}, []float64{0.3, 1, 1.2, 2.5, 3.1})
+	for j := range cols {
+		d1 := cols[j][1] - cols[j][0]
+		d2 := cols[j][3] - cols[j][2]
+		if math.Abs(float64(d1-d2)) > 1e-10 {
+			t.Errorf("column %d is not linear above the last knot", j)
+		}
+	}
+
+	for _, f := range []func(){
+		func() { NaturalSpline(x, []float64{0, 1}) },
+		func() { NaturalSpline(x, []float64{0, 1, 1}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fail()
+				}
+			}()
+			f()
+		}()
+	}
+}